@@ -0,0 +1,413 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/txrelayer"
+)
+
+// names of the actions in the audit log
+const (
+	actionRetry  = "retry"
+	actionMark   = "mark"
+	actionPause  = "pause"
+	actionResume = "resume"
+	actionRescan = "rescan"
+)
+
+const (
+	maxActionBodySize = 4096
+	// maxReasonLength is the size of the reason columns
+	maxReasonLength = 255
+	// maxEventsPerTx bounds the events of a BNB tx updated by one action
+	maxEventsPerTx = 1000
+)
+
+// RelayerControl pauses the loops of a relayer and rescans its chain, e.g. txrelayer.TxRelayer
+type RelayerControl interface {
+	Pause(loop string) error
+	Resume(loop string) error
+	// LoopStates returns whether each loop is paused
+	LoopStates() map[string]bool
+	RequestRescan(height uint64) error
+}
+
+// WithActions enables the actions, every action is recorded in the audit repository.
+// The controls are the relayers by chain, the BNB relayer is absent if it is disabled.
+func (s *Server) WithActions(audit db.IAuditRepository, controls map[string]RelayerControl) *Server {
+	s.audit = audit
+	s.controls = controls
+	return s
+}
+
+// actionRequest is the JSON body of the actions, each action reads its own fields
+type actionRequest struct {
+	Txid string `json:"txid"`
+	// LogIndex selects one event of a BNB tx, all events of the tx are selected if it is omitted
+	LogIndex *uint  `json:"logIndex"`
+	Status   string `json:"status"`
+	Loop     string `json:"loop"`
+	Height   uint64 `json:"height"`
+	Reason   string `json:"reason"`
+}
+
+// target is the object of the action in the audit log
+func (r actionRequest) target() string {
+	switch {
+	case r.Txid != "" && r.LogIndex != nil:
+		return fmt.Sprintf("%s/%d", r.Txid, *r.LogIndex)
+	case r.Txid != "":
+		return r.Txid
+	case r.Loop != "":
+		return r.Loop
+	case r.Height > 0:
+		return strconv.FormatUint(r.Height, 10)
+	default:
+		return ""
+	}
+}
+
+func decodeActionRequest(body []byte) (actionRequest, error) {
+	var req actionRequest
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return actionRequest{}, badRequestError{fmt.Errorf("invalid request body: %w", err)}
+	}
+	if len(req.Reason) > maxReasonLength {
+		return actionRequest{}, badRequestError{fmt.Errorf("reason is longer than %d characters", maxReasonLength)}
+	}
+	if len(req.Txid) > 256 {
+		return actionRequest{}, badRequestError{errors.New("txid is too long")}
+	}
+	return req, nil
+}
+
+// accepted is the result of an action applied asynchronously by a relayer
+type accepted struct {
+	value any
+}
+
+type actionFunc func(chain string, req actionRequest) (any, error)
+
+// action authenticates the request, applies the action and records it in the audit log, failed actions included.
+// The audit log is written before the action is applied and its outcome is recorded afterwards,
+// an action is never applied without its audit log.
+func (s *Server) action(chain string, name string, handler actionFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+		if s.audit == nil {
+			writeError(w, errActionsDisabled)
+			return
+		}
+		identity, ok := s.auth.Authenticate(r)
+		if !ok {
+			writeUnauthorized(w)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxActionBodySize))
+		var req actionRequest
+		if err != nil {
+			err = badRequestError{fmt.Errorf("read request body: %w", err)}
+			body = nil
+		} else if req, err = decodeActionRequest(body); err != nil {
+			body = nil
+		}
+
+		log := &db.AdminAuditLog{
+			Actor:    db.ActorAPI,
+			Identity: identity,
+			Action:   name,
+			Chain:    chain,
+			Target:   req.target(),
+			Params:   string(body),
+			Reason:   req.Reason,
+			Error:    db.AuditOutcomeUnknown,
+		}
+		if err != nil {
+			log.Error = err.Error()
+		}
+		if auditErr := s.audit.InsertAuditLog(log); auditErr != nil {
+			writeError(w, fmt.Errorf("the action is not recorded in the audit log: %w", auditErr))
+			return
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		result, err := handler(chain, req)
+		var outcome string
+		if err != nil {
+			outcome = err.Error()
+		}
+		if auditErr := s.audit.CompleteAuditLog(log.Id, outcome); auditErr != nil {
+			writeError(w, fmt.Errorf("the outcome of the action is not recorded in the audit log: %w", auditErr))
+			return
+		}
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		switch result := result.(type) {
+		case accepted:
+			writeJSON(w, http.StatusAccepted, result.value)
+		default:
+			writeJSON(w, http.StatusOK, result)
+		}
+	}
+}
+
+// retry sets the deposit back to pending so the relayer submits it again
+func (s *Server) retry(chain string, req actionRequest) (any, error) {
+	return s.updateStatus(chain, req, db.StatusPending, func(status int) error {
+		switch status {
+		case db.StatusPending:
+			return conflictError{errors.New("the deposit is already pending")}
+		case db.StatusSuccess:
+			return conflictError{errors.New("a successful deposit cannot be retried, mark it instead")}
+		}
+		return nil
+	})
+}
+
+// mark forces the status of the deposit, e.g. to skip a poison transaction
+func (s *Server) mark(chain string, req actionRequest) (any, error) {
+	if req.Reason == "" {
+		return nil, badRequestError{errors.New("reason is required")}
+	}
+	status, ok := parseStatus(req.Status)
+	if !ok {
+		return nil, badRequestError{fmt.Errorf("invalid status: %s", req.Status)}
+	}
+	return s.updateStatus(chain, req, status, func(int) error { return nil })
+}
+
+// updateStatus sets the status of the deposit of the request if check accepts its current status,
+// the status is checked and updated in one transaction and the transition is recorded in the status history.
+// The update of a deposit moved into the archive by the retention job fails with db.ErrDepositArchived,
+// the update of an unknown deposit changes nothing and it is not found afterwards.
+func (s *Server) updateStatus(chain string, req actionRequest, status int, check func(status int) error) (any, error) {
+	if req.Txid == "" {
+		return nil, badRequestError{errors.New("txid is required")}
+	}
+	change := db.StatusChange{Actor: db.ActorAPI, Reason: req.Reason, Check: check}
+
+	if chain == chainBTC {
+		if req.LogIndex != nil {
+			return nil, badRequestError{errors.New("logIndex selects bnb deposits only")}
+		}
+		if err := s.btcRepository.UpdateTxStatus(req.Txid, status, change); err != nil {
+			return nil, err
+		}
+		tx, err := s.findBTCDeposit(req.Txid)
+		if err != nil {
+			return nil, err
+		}
+		return newBTCDeposit(tx), nil
+	}
+
+	if s.bnbRepository == nil {
+		return nil, errBNBDisabled
+	}
+	if err := s.bnbRepository.UpdateStatus(req.Txid, req.LogIndex, status, change); err != nil {
+		return nil, err
+	}
+	txs, err := s.findBNBDeposits(req.Txid, req.LogIndex)
+	if err != nil {
+		return nil, err
+	}
+	deposits := make([]bnbDeposit, 0, len(txs))
+	for _, tx := range txs {
+		deposits = append(deposits, newBNBDeposit(tx))
+	}
+	return deposits, nil
+}
+
+func (s *Server) findBTCDeposit(txid string) (*db.BtcDepositTx, error) {
+	txs, err := s.btcRepository.QueryBtcDepositTxs(db.DepositFilter{Txid: txid, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(txs) == 0 {
+		return nil, errDepositNotFound
+	}
+	return txs[0], nil
+}
+
+func (s *Server) findBNBDeposits(txid string, logIndex *uint) ([]*db.WrappedBTCDepositTx, error) {
+	txs, err := s.bnbRepository.QueryWrappedBTCDepositTxs(db.DepositFilter{Txid: txid, Limit: maxEventsPerTx})
+	if err != nil {
+		return nil, err
+	}
+	var found []*db.WrappedBTCDepositTx
+	for _, tx := range txs {
+		if logIndex == nil || tx.LogIndex == *logIndex {
+			found = append(found, tx)
+		}
+	}
+	if len(found) == 0 {
+		return nil, errDepositNotFound
+	}
+	return found, nil
+}
+
+func (s *Server) pause(chain string, req actionRequest) (any, error) {
+	return s.controlLoop(chain, req, RelayerControl.Pause)
+}
+
+func (s *Server) resume(chain string, req actionRequest) (any, error) {
+	return s.controlLoop(chain, req, RelayerControl.Resume)
+}
+
+func (s *Server) controlLoop(chain string, req actionRequest, apply func(RelayerControl, string) error) (any, error) {
+	control, err := s.control(chain)
+	if err != nil {
+		return nil, err
+	}
+	if req.Loop == "" {
+		return nil, badRequestError{errors.New("loop is required")}
+	}
+	if err := apply(control, req.Loop); err != nil {
+		if errors.Is(err, txrelayer.ErrUnknownLoop) {
+			return nil, badRequestError{err}
+		}
+		return nil, err
+	}
+	return relayerState{Chain: chain, Paused: control.LoopStates()}, nil
+}
+
+// rescan is applied by the scan loop of the relayer, the sync point is moved before the height
+func (s *Server) rescan(chain string, req actionRequest) (any, error) {
+	control, err := s.control(chain)
+	if err != nil {
+		return nil, err
+	}
+	if err := control.RequestRescan(req.Height); err != nil {
+		if errors.Is(err, txrelayer.ErrInvalidRescanHeight) {
+			return nil, badRequestError{err}
+		}
+		return nil, err
+	}
+	return accepted{rescanRequest{Chain: chain, Height: req.Height}}, nil
+}
+
+func (s *Server) control(chain string) (RelayerControl, error) {
+	control, ok := s.controls[chain]
+	if !ok {
+		if chain == chainBNB {
+			return nil, errBNBDisabled
+		}
+		return nil, errActionsDisabled
+	}
+	return control, nil
+}
+
+func (s *Server) relayers(_ url.Values) (any, error) {
+	states := make([]relayerState, 0, len(s.controls))
+	for _, chain := range []string{chainBTC, chainBNB} {
+		if control, ok := s.controls[chain]; ok {
+			states = append(states, relayerState{Chain: chain, Paused: control.LoopStates()})
+		}
+	}
+	return states, nil
+}
+
+func (s *Server) auditLogs(query url.Values) (any, error) {
+	if s.audit == nil {
+		return nil, errActionsDisabled
+	}
+	afterId, limit := 0, s.maxPageSize
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := strconv.Atoi(value)
+		if err != nil || cursor < 0 {
+			return nil, badRequestError{fmt.Errorf("invalid cursor: %s", value)}
+		}
+		afterId = cursor
+	}
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, badRequestError{fmt.Errorf("invalid limit: %s", value)}
+		}
+		if parsed > 0 && parsed < limit {
+			limit = parsed
+		}
+	}
+
+	logs, err := s.audit.GetAuditLogs(afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := auditPage{Logs: make([]auditLog, 0, len(logs))}
+	for _, log := range logs {
+		page.Logs = append(page.Logs, auditLog{
+			Id:          log.Id,
+			Actor:       log.Actor,
+			Identity:    log.Identity,
+			Action:      log.Action,
+			Chain:       log.Chain,
+			Target:      log.Target,
+			Params:      json.RawMessage(nonEmptyJSON(log.Params)),
+			Reason:      log.Reason,
+			Error:       log.Error,
+			CreatedTime: log.CreatedTime,
+		})
+	}
+	if len(logs) == limit {
+		page.NextCursor = logs[len(logs)-1].Id
+	}
+	return page, nil
+}
+
+// nonEmptyJSON returns the JSON params of an audit log, null if the request body was rejected
+func nonEmptyJSON(params string) string {
+	if params == "" || !json.Valid([]byte(params)) {
+		return "null"
+	}
+	return params
+}
+
+type relayerState struct {
+	Chain string `json:"chain"`
+	// Paused tells whether each loop is paused
+	Paused map[string]bool `json:"paused"`
+}
+
+type rescanRequest struct {
+	Chain  string `json:"chain"`
+	Height uint64 `json:"height"`
+}
+
+type auditPage struct {
+	Logs []auditLog `json:"logs"`
+	// NextCursor is the cursor parameter of the next page, omitted on the last page
+	NextCursor int `json:"nextCursor,omitempty"`
+}
+
+type auditLog struct {
+	Id          int             `json:"id"`
+	Actor       string          `json:"actor"`
+	Identity    string          `json:"identity"`
+	Action      string          `json:"action"`
+	Chain       string          `json:"chain"`
+	Target      string          `json:"target,omitempty"`
+	Params      json.RawMessage `json:"params"`
+	Reason      string          `json:"reason,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedTime time.Time       `json:"createdTime"`
+}
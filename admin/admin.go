@@ -0,0 +1,434 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	agenttypes "github.com/Lorenzo-Protocol/lorenzo/v3/x/agent/types"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+// AgentSource lists the agents refreshed from Lorenzo, e.g. the BTC relayer
+type AgentSource interface {
+	Agents() []agenttypes.Agent
+}
+
+// chains of the deposits and the relayers
+const (
+	chainBTC = "btc"
+	chainBNB = "bnb"
+)
+
+// Server is the admin HTTP JSON API, it answers deposit queries from the repositories
+// and applies the authenticated actions of operators
+type Server struct {
+	btcRepository db.IBTCRepository
+	// nil if the BNB relayer is disabled
+	bnbRepository db.IBNBRepository
+	agents        AgentSource
+	maxPageSize   int
+
+	auth *Authenticator
+	// audit and controls are nil until the actions are enabled
+	audit    db.IAuditRepository
+	controls map[string]RelayerControl
+}
+
+func NewServer(cfg config.Admin, btcRepository db.IBTCRepository, bnbRepository db.IBNBRepository, agents AgentSource) *Server {
+	return &Server{
+		btcRepository: btcRepository,
+		bnbRepository: bnbRepository,
+		agents:        agents,
+		maxPageSize:   cfg.MaxPageSize,
+		auth:          NewAuthenticator(cfg.Tokens),
+	}
+}
+
+// Register adds the API endpoints to the mux
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/deposits/btc", get(s.btcDeposits))
+	mux.HandleFunc("/api/v1/deposits/bnb", get(s.bnbDeposits))
+	mux.HandleFunc("/api/v1/deposits/btc/history", get(s.btcHistory))
+	mux.HandleFunc("/api/v1/deposits/bnb/history", get(s.bnbHistory))
+	mux.HandleFunc("/api/v1/sync-points", get(s.syncPoints))
+	mux.HandleFunc("/api/v1/agents", get(s.agentList))
+	mux.HandleFunc("/api/v1/relayers", s.authenticated(get(s.relayers)))
+	mux.HandleFunc("/api/v1/audit", s.authenticated(get(s.auditLogs)))
+
+	for _, chain := range []string{chainBTC, chainBNB} {
+		prefix := "/api/v1/actions/" + chain
+		mux.HandleFunc(prefix+"/retry", s.action(chain, actionRetry, s.retry))
+		mux.HandleFunc(prefix+"/mark", s.action(chain, actionMark, s.mark))
+		mux.HandleFunc(prefix+"/pause", s.action(chain, actionPause, s.pause))
+		mux.HandleFunc(prefix+"/resume", s.action(chain, actionResume, s.resume))
+		mux.HandleFunc(prefix+"/rescan", s.action(chain, actionRescan, s.rescan))
+	}
+}
+
+var (
+	// errBNBDisabled is returned by the BNB endpoints when the BNB relayer is disabled
+	errBNBDisabled     = errors.New("bnb relayer is disabled")
+	errActionsDisabled = errors.New("admin actions are disabled")
+	errDepositNotFound = errors.New("deposit not found")
+)
+
+// badRequestError is a query parameter or request body error
+type badRequestError struct {
+	err error
+}
+
+func (e badRequestError) Error() string {
+	return e.err.Error()
+}
+
+// conflictError rejects an action in the current state of the deposit
+type conflictError struct {
+	err error
+}
+
+func (e conflictError) Error() string {
+	return e.err.Error()
+}
+
+type handlerFunc func(query url.Values) (any, error)
+
+func get(handler handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+			return
+		}
+
+		result, err := handler(r.URL.Query())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var badRequest badRequestError
+	var conflict conflictError
+	switch {
+	case errors.As(err, &badRequest):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.As(err, &conflict), errors.Is(err, db.ErrStatusChanged), errors.Is(err, db.ErrDepositStripped),
+		errors.Is(err, db.ErrDepositArchived):
+		writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+	case errors.Is(err, errBNBDisabled), errors.Is(err, errActionsDisabled), errors.Is(err, errDepositNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type depositPage[T any] struct {
+	Deposits []T `json:"deposits"`
+	// NextCursor is the cursor parameter of the next page, omitted on the last page
+	NextCursor int `json:"nextCursor,omitempty"`
+}
+
+func newDepositPage[D any, T any](deposits []D, limit int, id func(D) int, convert func(D) T) depositPage[T] {
+	page := depositPage[T]{Deposits: make([]T, 0, len(deposits))}
+	for _, deposit := range deposits {
+		page.Deposits = append(page.Deposits, convert(deposit))
+	}
+	if len(deposits) == limit {
+		page.NextCursor = id(deposits[len(deposits)-1])
+	}
+	return page
+}
+
+func (s *Server) btcDeposits(query url.Values) (any, error) {
+	if query.Has("plan") {
+		return nil, badRequestError{errors.New("plan filters bnb deposits only")}
+	}
+	filter, err := s.depositFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := s.btcRepository.QueryBtcDepositTxs(filter)
+	if err != nil {
+		return nil, err
+	}
+	return newDepositPage(txs, filter.Limit, func(tx *db.BtcDepositTx) int { return tx.Id }, newBTCDeposit), nil
+}
+
+func (s *Server) bnbDeposits(query url.Values) (any, error) {
+	if s.bnbRepository == nil {
+		return nil, errBNBDisabled
+	}
+	if query.Has("agent") {
+		return nil, badRequestError{errors.New("agent filters btc deposits only")}
+	}
+	filter, err := s.depositFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := s.bnbRepository.QueryWrappedBTCDepositTxs(filter)
+	if err != nil {
+		return nil, err
+	}
+	return newDepositPage(txs, filter.Limit, func(tx *db.WrappedBTCDepositTx) int { return tx.Id }, newBNBDeposit), nil
+}
+
+func (s *Server) btcHistory(query url.Values) (any, error) {
+	return history(s.btcRepository, query)
+}
+
+func (s *Server) bnbHistory(query url.Values) (any, error) {
+	if s.bnbRepository == nil {
+		return nil, errBNBDisabled
+	}
+	return history(s.bnbRepository, query)
+}
+
+func history(repository db.IStatusHistoryRepository, query url.Values) (any, error) {
+	txid := query.Get("txid")
+	if txid == "" {
+		return nil, badRequestError{errors.New("txid is required")}
+	}
+
+	history, err := repository.GetStatusHistory(txid)
+	if err != nil {
+		return nil, err
+	}
+	transitions := make([]statusTransition, 0, len(history))
+	for _, h := range history {
+		transitions = append(transitions, newStatusTransition(h))
+	}
+	return transitions, nil
+}
+
+func (s *Server) syncPoints(_ url.Values) (any, error) {
+	repositories := []db.ISyncPointRepository{s.btcRepository}
+	if s.bnbRepository != nil {
+		repositories = append(repositories, s.bnbRepository)
+	}
+
+	points := make([]syncPoint, 0, len(repositories))
+	for _, repository := range repositories {
+		checkpoint, err := repository.GetCheckpoint()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, syncPoint{
+			Chain:       checkpoint.Chain,
+			Height:      checkpoint.Height,
+			BlockHash:   checkpoint.BlockHash,
+			UpdatedTime: checkpoint.UpdatedTime,
+		})
+	}
+	return points, nil
+}
+
+func (s *Server) agentList(_ url.Values) (any, error) {
+	agents := s.agents.Agents()
+	list := make([]agent, 0, len(agents))
+	for _, a := range agents {
+		list = append(list, agent{
+			Id:                  a.Id,
+			Name:                a.Name,
+			BtcReceivingAddress: a.BtcReceivingAddress,
+			EthAddr:             a.EthAddr,
+			Description:         a.Description,
+			Url:                 a.Url,
+		})
+	}
+	return list, nil
+}
+
+// depositFilter parses the filter parameters shared by the deposit endpoints
+func (s *Server) depositFilter(query url.Values) (db.DepositFilter, error) {
+	filter := db.DepositFilter{
+		Txid:    query.Get("txid"),
+		Address: query.Get("address"),
+		Limit:   s.maxPageSize,
+	}
+
+	var err error
+	parseUint := func(name string) uint64 {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return 0
+		}
+		var parsed uint64
+		if parsed, err = strconv.ParseUint(value, 10, 64); err != nil {
+			err = badRequestError{fmt.Errorf("invalid %s: %s", name, value)}
+		}
+		return parsed
+	}
+	filter.AgentId = parseUint("agent")
+	filter.PlanId = parseUint("plan")
+	filter.FromHeight = parseUint("fromHeight")
+	filter.ToHeight = parseUint("toHeight")
+	filter.AfterId = int(parseUint("cursor"))
+	if limit := parseUint("limit"); limit > 0 && limit < uint64(s.maxPageSize) {
+		filter.Limit = int(limit)
+	}
+	if err != nil {
+		return db.DepositFilter{}, err
+	}
+
+	if name := query.Get("status"); name != "" {
+		status, ok := parseStatus(name)
+		if !ok {
+			return db.DepositFilter{}, badRequestError{fmt.Errorf("invalid status: %s", name)}
+		}
+		filter.Status = &status
+	}
+	return filter, nil
+}
+
+// parseStatus accepts the name or the number of a status
+func parseStatus(name string) (int, bool) {
+	for status, statusName := range db.StatusNames {
+		if statusName == name || strconv.Itoa(status) == name {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+func statusName(status int) string {
+	if name, ok := db.StatusNames[status]; ok {
+		return name
+	}
+	return strconv.Itoa(status)
+}
+
+type btcDeposit struct {
+	Id              int       `json:"id"`
+	Txid            string    `json:"txid"`
+	Status          string    `json:"status"`
+	AgentId         uint64    `json:"agentId"`
+	ReceiverName    string    `json:"receiverName"`
+	ReceiverAddress string    `json:"receiverAddress"`
+	Amount          uint64    `json:"amount"`
+	Height          uint64    `json:"height"`
+	BlockHash       string    `json:"blockHash"`
+	BlockTime       time.Time `json:"blockTime"`
+	CreatedTime     time.Time `json:"createdTime"`
+	UpdatedTime     time.Time `json:"updatedTime"`
+}
+
+func newBTCDeposit(tx *db.BtcDepositTx) btcDeposit {
+	return btcDeposit{
+		Id:              tx.Id,
+		Txid:            tx.Txid,
+		Status:          statusName(tx.Status),
+		AgentId:         tx.AgentId,
+		ReceiverName:    tx.ReceiverName,
+		ReceiverAddress: tx.ReceiverAddress,
+		Amount:          tx.Amount,
+		Height:          tx.Height,
+		BlockHash:       tx.BlockHash,
+		BlockTime:       tx.BlockTime,
+		CreatedTime:     tx.CreatedTime,
+		UpdatedTime:     tx.UpdatedTime,
+	}
+}
+
+// bnbDeposit is a BNB deposit event without its receipt and proof
+type bnbDeposit struct {
+	Id                 int       `json:"id"`
+	Chain              string    `json:"chain"`
+	Txid               string    `json:"txid"`
+	LogIndex           uint      `json:"logIndex"`
+	EventName          string    `json:"eventName"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason,omitempty"`
+	Height             uint64    `json:"height"`
+	BlockHash          string    `json:"blockHash"`
+	BlockTime          time.Time `json:"blockTime"`
+	StakeIndex         uint64    `json:"stakeIndex"`
+	PlanId             uint64    `json:"planId"`
+	UserAddress        string    `json:"userAddress"`
+	BtcContractAddress string    `json:"btcContractAddress"`
+	StakeAmount        string    `json:"stakeAmount"`
+	StBTCAmount        string    `json:"stBTCAmount"`
+	CreatedTime        time.Time `json:"createdTime"`
+	UpdatedTime        time.Time `json:"updatedTime"`
+}
+
+func newBNBDeposit(tx *db.WrappedBTCDepositTx) bnbDeposit {
+	return bnbDeposit{
+		Id:                 tx.Id,
+		Chain:              tx.Chain,
+		Txid:               tx.Txid,
+		LogIndex:           tx.LogIndex,
+		EventName:          tx.EventName,
+		Status:             statusName(tx.Status),
+		Reason:             tx.Reason,
+		Height:             tx.Height,
+		BlockHash:          tx.BlockHash,
+		BlockTime:          tx.BlockTime,
+		StakeIndex:         tx.StakeIndex,
+		PlanId:             tx.PlanId,
+		UserAddress:        tx.UserAddress,
+		BtcContractAddress: tx.BtcContractAddress,
+		StakeAmount:        tx.StakeAmount,
+		StBTCAmount:        tx.StBTCAmount,
+		CreatedTime:        tx.CreatedTime,
+		UpdatedTime:        tx.UpdatedTime,
+	}
+}
+
+type statusTransition struct {
+	LogIndex    uint      `json:"logIndex"`
+	OldStatus   string    `json:"oldStatus"`
+	NewStatus   string    `json:"newStatus"`
+	Actor       string    `json:"actor"`
+	Reason      string    `json:"reason,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	CreatedTime time.Time `json:"createdTime"`
+}
+
+func newStatusTransition(h *db.DepositStatusHistory) statusTransition {
+	return statusTransition{
+		LogIndex:    h.LogIndex,
+		OldStatus:   statusName(h.OldStatus),
+		NewStatus:   statusName(h.NewStatus),
+		Actor:       h.Actor,
+		Reason:      h.Reason,
+		Error:       h.Error,
+		CreatedTime: h.CreatedTime,
+	}
+}
+
+type syncPoint struct {
+	Chain       string    `json:"chain"`
+	Height      uint64    `json:"height"`
+	BlockHash   string    `json:"blockHash"`
+	UpdatedTime time.Time `json:"updatedTime"`
+}
+
+type agent struct {
+	Id                  uint64 `json:"id"`
+	Name                string `json:"name"`
+	BtcReceivingAddress string `json:"btcReceivingAddress"`
+	EthAddr             string `json:"ethAddr"`
+	Description         string `json:"description"`
+	Url                 string `json:"url"`
+}
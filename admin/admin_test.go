@@ -0,0 +1,419 @@
+package admin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	agenttypes "github.com/Lorenzo-Protocol/lorenzo/v3/x/agent/types"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/txrelayer"
+)
+
+type staticAgents []agenttypes.Agent
+
+func (a staticAgents) Agents() []agenttypes.Agent {
+	return a
+}
+
+func newTestServer(t *testing.T, bnbEnabled bool) *httptest.Server {
+	btcRepository := db.NewMemoryBTCRepository()
+	txs := []*db.BtcDepositTx{
+		{Txid: "a", AgentId: 1, ReceiverAddress: "bc1a", Height: 10},
+		{Txid: "b", AgentId: 2, ReceiverAddress: "bc1b", Height: 11},
+		{Txid: "c", AgentId: 1, ReceiverAddress: "bc1a", Height: 12},
+	}
+	if err := btcRepository.InsertBtcDepositTxs(txs, 12, "0x12"); err != nil {
+		t.Fatal(err)
+	}
+	if err := btcRepository.UpdateTxStatus("b", db.StatusInvalid, db.StatusChange{Actor: db.ActorRelayer, Error: "bad proof"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var bnbRepository db.IBNBRepository
+	if bnbEnabled {
+		bnbRepository = db.NewMemoryBNBRepository("bnb")
+		events := []*db.WrappedBTCDepositTx{{Chain: "bnb", Txid: "0xa", PlanId: 3, Receipt: "0x01", Proof: "0x02", Height: 100}}
+		if err := bnbRepository.InsertWrappedBTCDepositTxs(events, 120, "0x120"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	agents := staticAgents{{Id: 1, Name: "agent", BtcReceivingAddress: "bc1a"}}
+	NewServer(config.Admin{MaxPageSize: 2}, btcRepository, bnbRepository, agents).Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func getJSON(t *testing.T, url string, expectStatus int, body any) {
+	getAuthJSON(t, url, "", expectStatus, body)
+}
+
+func getAuthJSON(t *testing.T, url string, token string, expectStatus int, body any) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expectStatus {
+		t.Fatalf("GET %s: expect status %d, got %d", url, expectStatus, resp.StatusCode)
+	}
+	if body != nil {
+		if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBTCDeposits(t *testing.T) {
+	server := newTestServer(t, true)
+
+	// the page size is capped by maxPageSize
+	var page depositPage[btcDeposit]
+	getJSON(t, server.URL+"/api/v1/deposits/btc?limit=10", http.StatusOK, &page)
+	if len(page.Deposits) != 2 || page.Deposits[0].Txid != "a" || page.NextCursor != page.Deposits[1].Id {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	var next depositPage[btcDeposit]
+	getJSON(t, server.URL+"/api/v1/deposits/btc?cursor="+strconv.Itoa(page.NextCursor), http.StatusOK, &next)
+	if len(next.Deposits) != 1 || next.Deposits[0].Txid != "c" || next.NextCursor != 0 {
+		t.Fatalf("unexpected next page: %+v", next)
+	}
+
+	var filtered depositPage[btcDeposit]
+	getJSON(t, server.URL+"/api/v1/deposits/btc?agent=1&fromHeight=11", http.StatusOK, &filtered)
+	if len(filtered.Deposits) != 1 || filtered.Deposits[0].Txid != "c" {
+		t.Fatalf("unexpected deposits of agent 1: %+v", filtered)
+	}
+	getJSON(t, server.URL+"/api/v1/deposits/btc?status=invalid&address=bc1b", http.StatusOK, &filtered)
+	if len(filtered.Deposits) != 1 || filtered.Deposits[0].Txid != "b" || filtered.Deposits[0].Status != "invalid" {
+		t.Fatalf("unexpected invalid deposits: %+v", filtered)
+	}
+
+	var history []statusTransition
+	getJSON(t, server.URL+"/api/v1/deposits/btc/history?txid=b", http.StatusOK, &history)
+	if len(history) != 1 || history[0].OldStatus != "pending" || history[0].NewStatus != "invalid" || history[0].Error != "bad proof" {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+
+	getJSON(t, server.URL+"/api/v1/deposits/btc?status=unknown", http.StatusBadRequest, nil)
+	getJSON(t, server.URL+"/api/v1/deposits/btc?fromHeight=-1", http.StatusBadRequest, nil)
+	getJSON(t, server.URL+"/api/v1/deposits/btc?plan=1", http.StatusBadRequest, nil)
+	getJSON(t, server.URL+"/api/v1/deposits/btc/history", http.StatusBadRequest, nil)
+
+	resp, err := http.Post(server.URL+"/api/v1/deposits/btc", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expect the API to be read-only, got status %d", resp.StatusCode)
+	}
+}
+
+func TestBNBDepositsAndSyncPoints(t *testing.T) {
+	server := newTestServer(t, true)
+
+	var page depositPage[map[string]any]
+	getJSON(t, server.URL+"/api/v1/deposits/bnb?plan=3", http.StatusOK, &page)
+	if len(page.Deposits) != 1 || page.Deposits[0]["txid"] != "0xa" {
+		t.Fatalf("unexpected bnb deposits: %+v", page)
+	}
+	if _, ok := page.Deposits[0]["proof"]; ok {
+		t.Error("proofs should not be returned")
+	}
+
+	var points []syncPoint
+	getJSON(t, server.URL+"/api/v1/sync-points", http.StatusOK, &points)
+	if len(points) != 2 || points[0].Chain != "btc" || points[0].Height != 12 || points[1].Chain != "bnb" || points[1].Height != 120 {
+		t.Fatalf("unexpected sync points: %+v", points)
+	}
+
+	var agents []agent
+	getJSON(t, server.URL+"/api/v1/agents", http.StatusOK, &agents)
+	if len(agents) != 1 || agents[0].BtcReceivingAddress != "bc1a" {
+		t.Fatalf("unexpected agents: %+v", agents)
+	}
+
+	disabled := newTestServer(t, false)
+	getJSON(t, disabled.URL+"/api/v1/deposits/bnb", http.StatusNotFound, nil)
+	getJSON(t, disabled.URL+"/api/v1/sync-points", http.StatusOK, &points)
+	if len(points) != 1 {
+		t.Fatalf("unexpected sync points without bnb: %+v", points)
+	}
+}
+
+const testToken = "0123456789abcdef"
+
+type fakeControl struct {
+	paused       map[string]bool
+	rescanHeight uint64
+	// audit is the audit log of the last rescan when it was requested
+	audit          db.IAuditRepository
+	rescanAuditLog *db.AdminAuditLog
+}
+
+func (c *fakeControl) Pause(loop string) error {
+	return c.set(loop, true)
+}
+
+func (c *fakeControl) Resume(loop string) error {
+	return c.set(loop, false)
+}
+
+func (c *fakeControl) set(loop string, paused bool) error {
+	if _, ok := c.paused[loop]; !ok {
+		return txrelayer.ErrUnknownLoop
+	}
+	c.paused[loop] = paused
+	return nil
+}
+
+func (c *fakeControl) LoopStates() map[string]bool {
+	return c.paused
+}
+
+func (c *fakeControl) RequestRescan(height uint64) error {
+	if height == 0 {
+		return txrelayer.ErrInvalidRescanHeight
+	}
+	c.rescanHeight = height
+	if logs, err := c.audit.GetAuditLogs(0, 100); err == nil && len(logs) > 0 {
+		c.rescanAuditLog = logs[len(logs)-1]
+	}
+	return nil
+}
+
+func newActionServer(t *testing.T) (*httptest.Server, db.IBTCRepository, *fakeControl) {
+	btcRepository := db.NewMemoryBTCRepository()
+	if err := btcRepository.InsertBtcDepositTxs([]*db.BtcDepositTx{{Txid: "a"}, {Txid: "b"}}, 12, "0x12"); err != nil {
+		t.Fatal(err)
+	}
+	if err := btcRepository.UpdateTxStatus("b", db.StatusInvalid, db.StatusChange{Actor: db.ActorRelayer, Error: "bad proof"}); err != nil {
+		t.Fatal(err)
+	}
+	bnbRepository := db.NewMemoryBNBRepository("bnb")
+	events := []*db.WrappedBTCDepositTx{{Chain: "bnb", Txid: "0xa", LogIndex: 0}, {Chain: "bnb", Txid: "0xa", LogIndex: 1}}
+	if err := bnbRepository.InsertWrappedBTCDepositTxs(events, 120, "0x120"); err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range events {
+		if err := bnbRepository.MarkInvalid("0xa", event.LogIndex, db.StatusChange{Actor: db.ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	audit := db.NewMemoryAuditRepository()
+	control := &fakeControl{paused: map[string]bool{"scan": false, "submit": false}, audit: audit}
+	cfg := config.Admin{MaxPageSize: 100, Tokens: []config.AdminToken{{Name: "ops", Token: testToken}}}
+	mux := http.NewServeMux()
+	NewServer(cfg, btcRepository, bnbRepository, staticAgents{}).
+		WithActions(audit, map[string]RelayerControl{"btc": control}).
+		Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, btcRepository, control
+}
+
+func postJSON(t *testing.T, url string, token string, body string, expectStatus int, result any) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expectStatus {
+		t.Fatalf("POST %s %s: expect status %d, got %d", url, body, expectStatus, resp.StatusCode)
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDepositActions(t *testing.T) {
+	server, btcRepository, _ := newActionServer(t)
+	actions := server.URL + "/api/v1/actions"
+
+	postJSON(t, actions+"/btc/retry", "", `{"txid":"b"}`, http.StatusUnauthorized, nil)
+	postJSON(t, actions+"/btc/retry", "wrong-token-0123", `{"txid":"b"}`, http.StatusUnauthorized, nil)
+
+	var deposit btcDeposit
+	postJSON(t, actions+"/btc/retry", testToken, `{"txid":"b","reason":"proof fixed"}`, http.StatusOK, &deposit)
+	if deposit.Status != "pending" {
+		t.Fatalf("unexpected deposit after retry: %+v", deposit)
+	}
+	history, err := btcRepository.GetStatusHistory("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := history[len(history)-1]; last.Actor != db.ActorAPI || last.Reason != "proof fixed" || last.NewStatus != db.StatusPending {
+		t.Errorf("unexpected last transition: %+v", last)
+	}
+	postJSON(t, actions+"/btc/retry", testToken, `{"txid":"b"}`, http.StatusConflict, nil)
+	postJSON(t, actions+"/btc/retry", testToken, `{"txid":"missing"}`, http.StatusNotFound, nil)
+	postJSON(t, actions+"/btc/retry", testToken, `{"txid":"b","unknown":1}`, http.StatusBadRequest, nil)
+
+	postJSON(t, actions+"/btc/mark", testToken, `{"txid":"a","status":"invalid"}`, http.StatusBadRequest, nil)
+	postJSON(t, actions+"/btc/mark", testToken, `{"txid":"a","status":"done","reason":"poison"}`, http.StatusBadRequest, nil)
+	postJSON(t, actions+"/btc/mark", testToken, `{"txid":"a","status":"invalid","reason":"poison"}`, http.StatusOK, &deposit)
+	if deposit.Status != "invalid" {
+		t.Fatalf("unexpected deposit after mark: %+v", deposit)
+	}
+
+	// one event of the BNB tx is retried
+	var events []bnbDeposit
+	postJSON(t, actions+"/bnb/retry", testToken, `{"txid":"0xa","logIndex":1}`, http.StatusOK, &events)
+	if len(events) != 1 || events[0].LogIndex != 1 || events[0].Status != "pending" {
+		t.Fatalf("unexpected events after retry: %+v", events)
+	}
+	postJSON(t, actions+"/bnb/mark", testToken, `{"txid":"0xa","status":"success","reason":"submitted manually"}`, http.StatusOK, &events)
+	if len(events) != 2 || events[0].Status != "success" || events[1].Status != "success" {
+		t.Fatalf("unexpected events after mark: %+v", events)
+	}
+
+	resp, err := http.Get(actions + "/btc/retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expect actions to require POST, got status %d", resp.StatusCode)
+	}
+}
+
+// the retention job may archive invalid deposits, they are read only and cannot be retried
+func TestRetryArchivedDeposit(t *testing.T) {
+	dbCfg := config.Database{Driver: config.DatabaseDriverSQLite, DBName: ":memory:"}
+	handle, err := db.Open(dbCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(handle, dbCfg.Driver); err != nil {
+		t.Fatal(err)
+	}
+	btcRepository, err := db.NewBTCRepository(handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retention, err := db.NewRetentionRepository(handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := btcRepository.InsertBtcDepositTxs([]*db.BtcDepositTx{{Txid: "b", Height: 12}}, 12, "0x12"); err != nil {
+		t.Fatal(err)
+	}
+	if err := btcRepository.UpdateTxStatus("b", db.StatusInvalid, db.StatusChange{Actor: db.ActorRelayer, Error: "bad proof"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := retention.ArchiveBtcDepositTxs(time.Now().Add(time.Hour), 10); err != nil || n != 1 {
+		t.Fatalf("unexpected archived deposits: %d, error: %v", n, err)
+	}
+
+	cfg := config.Admin{MaxPageSize: 100, Tokens: []config.AdminToken{{Name: "ops", Token: testToken}}}
+	mux := http.NewServeMux()
+	NewServer(cfg, btcRepository, nil, staticAgents{}).WithActions(db.NewMemoryAuditRepository(), nil).Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	var resp errorResponse
+	postJSON(t, server.URL+"/api/v1/actions/btc/retry", testToken, `{"txid":"b"}`, http.StatusConflict, &resp)
+	if !strings.Contains(resp.Error, "archived") {
+		t.Fatalf("unexpected error: %s", resp.Error)
+	}
+	postJSON(t, server.URL+"/api/v1/actions/btc/retry", testToken, `{"txid":"missing"}`, http.StatusNotFound, nil)
+}
+
+func TestRelayerActionsAndAudit(t *testing.T) {
+	server, _, control := newActionServer(t)
+	actions := server.URL + "/api/v1/actions"
+
+	var state relayerState
+	postJSON(t, actions+"/btc/pause", testToken, `{"loop":"submit","reason":"incident"}`, http.StatusOK, &state)
+	if !state.Paused["submit"] || state.Paused["scan"] {
+		t.Fatalf("unexpected relayer state: %+v", state)
+	}
+	postJSON(t, actions+"/btc/pause", testToken, `{"loop":"subscribe"}`, http.StatusBadRequest, nil)
+	postJSON(t, actions+"/btc/resume", testToken, `{"loop":"submit"}`, http.StatusOK, &state)
+	if state.Paused["submit"] {
+		t.Fatalf("unexpected relayer state: %+v", state)
+	}
+	postJSON(t, actions+"/bnb/pause", testToken, `{"loop":"scan"}`, http.StatusNotFound, nil)
+
+	postJSON(t, actions+"/btc/rescan", testToken, `{"height":0}`, http.StatusBadRequest, nil)
+	postJSON(t, actions+"/btc/rescan", testToken, `{"height":100,"reason":"missed deposit"}`, http.StatusAccepted, nil)
+	if control.rescanHeight != 100 {
+		t.Errorf("rescan is not requested: %d", control.rescanHeight)
+	}
+	// the action is audited before it is applied
+	if log := control.rescanAuditLog; log == nil || log.Action != "rescan" || log.Error != db.AuditOutcomeUnknown {
+		t.Errorf("unexpected audit log when the rescan was requested: %+v", log)
+	}
+
+	getJSON(t, server.URL+"/api/v1/relayers", http.StatusUnauthorized, nil)
+	getJSON(t, server.URL+"/api/v1/audit", http.StatusUnauthorized, nil)
+	var relayers []relayerState
+	getAuthJSON(t, server.URL+"/api/v1/relayers", testToken, http.StatusOK, &relayers)
+	if len(relayers) != 1 || relayers[0].Chain != "btc" {
+		t.Fatalf("unexpected relayers: %+v", relayers)
+	}
+
+	// failed actions are audited, unauthenticated requests are not
+	postJSON(t, actions+"/btc/pause", "", `{"loop":"scan"}`, http.StatusUnauthorized, nil)
+	var page auditPage
+	getAuthJSON(t, server.URL+"/api/v1/audit?limit=4", testToken, http.StatusOK, &page)
+	if len(page.Logs) != 4 || page.NextCursor != page.Logs[3].Id {
+		t.Fatalf("unexpected audit page: %+v", page)
+	}
+	first := page.Logs[0]
+	if first.Action != "pause" || first.Identity != "token:ops" || first.Target != "submit" || first.Reason != "incident" ||
+		first.Error != "" || string(first.Params) != `{"loop":"submit","reason":"incident"}` {
+		t.Errorf("unexpected audit log: %+v", first)
+	}
+	if page.Logs[1].Error == "" {
+		t.Errorf("the failed action is not audited with its error: %+v", page.Logs[1])
+	}
+	var next auditPage
+	getAuthJSON(t, server.URL+"/api/v1/audit?cursor="+strconv.Itoa(page.NextCursor), testToken, http.StatusOK, &next)
+	if len(next.Logs) != 2 || next.Logs[1].Action != "rescan" || next.Logs[1].Target != "100" || next.Logs[1].Error != "" {
+		t.Fatalf("unexpected next audit page: %+v", next)
+	}
+}
+
+func TestAuthenticateClientCertificate(t *testing.T) {
+	auth := NewAuthenticator(nil)
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "ops.example.com"}}
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/actions/btc/retry", nil)
+	if _, ok := auth.Authenticate(r); ok {
+		t.Fatal("expect the request without credentials unauthenticated")
+	}
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	if identity, ok := auth.Authenticate(r); !ok || identity != "cert:ops.example.com" {
+		t.Errorf("unexpected identity: %s, authenticated: %v", identity, ok)
+	}
+}
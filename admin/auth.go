@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+// Authenticator authenticates the clients of the admin API and the event streams by the API tokens,
+// or by the client certificate verified by the client CA of the admin TLS
+type Authenticator struct {
+	tokens []config.AdminToken
+}
+
+func NewAuthenticator(tokens []config.AdminToken) *Authenticator {
+	return &Authenticator{tokens: tokens}
+}
+
+// Authenticate returns the identity of the client certificate verified by the client CA, or of the bearer token
+func (a *Authenticator) Authenticate(r *http.Request) (string, bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return "cert:" + r.TLS.VerifiedChains[0][0].Subject.CommonName, true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+			return "token:" + t.Name, true
+		}
+	}
+	return "", false
+}
+
+// authenticated serves the requests authenticated like the actions, e.g. the relayer states and the audit log
+func (s *Server) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := s.auth.Authenticate(r); !ok {
+			writeUnauthorized(w)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+	writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+}
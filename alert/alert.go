@@ -0,0 +1,217 @@
+package alert
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+// statuses of the notifications
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Alert is a condition reported by a rule, alerts with the same key are deduplicated
+type Alert struct {
+	Key     string
+	Rule    string
+	Chain   string
+	Summary string
+	// Event alerts are notified once and never resolved, e.g. a deposit marked invalid
+	Event bool
+	// position of the event in the source of the rule, see Acknowledger
+	position int
+}
+
+// Notification is sent to the notifiers when an alert fires or is resolved
+type Notification struct {
+	Status   string
+	Alert    Alert
+	StartsAt time.Time
+	// EndsAt is set when the alert is resolved
+	EndsAt time.Time
+}
+
+// Rule evaluates a condition, it returns the alerts firing at now
+type Rule interface {
+	Name() string
+	Evaluate(ctx context.Context, now time.Time) ([]Alert, error)
+}
+
+// Acknowledger is implemented by the rules of event alerts. The events are reported again until they are
+// acknowledged, an event is acknowledged once it is delivered to all notifiers.
+type Acknowledger interface {
+	Acknowledge(alert Alert) error
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+type activeAlert struct {
+	// rule is the index of the rule reporting the alert, rules of the same name report the alerts of their chain
+	rule       int
+	alert      Alert
+	startsAt   time.Time
+	notifiedAt time.Time
+	// accepted are the indexes of the notifiers the last firing notification is delivered to
+	accepted map[int]bool
+}
+
+// Manager evaluates the rules periodically. A firing alert is notified when it starts, again after the
+// repeat interval if set, and once more when its rule no longer reports it.
+type Manager struct {
+	logger         *zap.SugaredLogger
+	interval       time.Duration
+	repeatInterval time.Duration
+	rules          []Rule
+	notifiers      []Notifier
+
+	// active alerts by key
+	active map[string]*activeAlert
+	// accepted are the indexes of the notifiers an undelivered event alert is delivered to by its key,
+	// the event is sent again to the other notifiers only
+	accepted map[string]map[int]bool
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+func NewManager(logger *zap.SugaredLogger, cfg config.Alerting, rules []Rule, notifiers []Notifier) *Manager {
+	return &Manager{
+		logger:         logger.Named("alert"),
+		interval:       cfg.Interval,
+		repeatInterval: cfg.RepeatInterval,
+		rules:          rules,
+		notifiers:      notifiers,
+		active:         make(map[string]*activeAlert),
+		accepted:       make(map[string]map[int]bool),
+		quit:           make(chan struct{}),
+	}
+}
+
+func (m *Manager) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		m.loop()
+	}()
+}
+
+func (m *Manager) Stop() {
+	close(m.quit)
+}
+
+func (m *Manager) WaitForShutdown() {
+	m.wg.Wait()
+}
+
+func (m *Manager) loop() {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+		m.EvaluateOnce(ctx, time.Now())
+		cancel()
+
+		select {
+		case <-m.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateOnce evaluates all rules at now and sends the notifications.
+// The alerts of a rule that fails to evaluate are kept as they are.
+func (m *Manager) EvaluateOnce(ctx context.Context, now time.Time) {
+	for i, rule := range m.rules {
+		alerts, err := rule.Evaluate(ctx, now)
+		if err != nil {
+			m.logger.Warnf("Failed to evaluate alert rule %s, error: %v", rule.Name(), err)
+			continue
+		}
+
+		firing := make(map[string]bool)
+		for _, alert := range alerts {
+			firing[alert.Key] = true
+			if !alert.Event {
+				m.fire(ctx, i, alert, now)
+				continue
+			}
+			accepted, ok := m.accepted[alert.Key]
+			if !ok {
+				accepted = make(map[int]bool)
+				m.accepted[alert.Key] = accepted
+			}
+			if !m.notify(ctx, Notification{Status: StatusFiring, Alert: alert, StartsAt: now}, accepted) {
+				// the undelivered event and the ones after it are reported again in order
+				break
+			}
+			delete(m.accepted, alert.Key)
+			if acknowledger, ok := rule.(Acknowledger); ok {
+				if err := acknowledger.Acknowledge(alert); err != nil {
+					m.logger.Warnf("Failed to acknowledge alert %s, error: %v", alert.Key, err)
+				}
+			}
+		}
+
+		for key, active := range m.active {
+			if active.rule != i || firing[key] {
+				continue
+			}
+			delete(m.active, key)
+			if active.notifiedAt.IsZero() {
+				// the firing notification was never delivered
+				continue
+			}
+			m.notify(ctx, Notification{Status: StatusResolved, Alert: active.alert, StartsAt: active.startsAt, EndsAt: now}, nil)
+		}
+	}
+}
+
+func (m *Manager) fire(ctx context.Context, rule int, alert Alert, now time.Time) {
+	active, ok := m.active[alert.Key]
+	if !ok {
+		active = &activeAlert{rule: rule, alert: alert, startsAt: now}
+		m.active[alert.Key] = active
+	}
+	// the summary is refreshed, e.g. the number of pending deposits
+	active.alert = alert
+	if !active.notifiedAt.IsZero() && (m.repeatInterval == 0 || now.Sub(active.notifiedAt) < m.repeatInterval) {
+		return
+	}
+	// a repeated notification goes to all notifiers again
+	if active.accepted == nil || !active.notifiedAt.IsZero() {
+		active.accepted = make(map[int]bool)
+	}
+	if m.notify(ctx, Notification{Status: StatusFiring, Alert: alert, StartsAt: active.startsAt}, active.accepted) {
+		active.notifiedAt = now
+	}
+}
+
+// notify sends the notification to the notifiers not in accepted and adds the ones it is delivered to,
+// accepted is nil if the notification is not retried. It returns false if any of them fails.
+func (m *Manager) notify(ctx context.Context, notification Notification, accepted map[int]bool) bool {
+	m.logger.Infof("Alert %s %s: %s", notification.Alert.Key, notification.Status, notification.Alert.Summary)
+	delivered := true
+	for i, notifier := range m.notifiers {
+		if accepted[i] {
+			continue
+		}
+		if err := notifier.Notify(ctx, notification); err != nil {
+			m.logger.Warnf("Failed to notify alert %s, error: %v", notification.Alert.Key, err)
+			delivered = false
+			continue
+		}
+		if accepted != nil {
+			accepted[i] = true
+		}
+	}
+	return delivered
+}
@@ -0,0 +1,353 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+type recordingNotifier struct {
+	notifications []Notification
+	err           error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+func (n *recordingNotifier) statuses() []string {
+	var statuses []string
+	for _, notification := range n.notifications {
+		statuses = append(statuses, notification.Alert.Key+":"+notification.Status)
+	}
+	return statuses
+}
+
+type heights struct {
+	chainTip   uint64
+	lorenzoTip uint64
+	err        error
+}
+
+func newTestManager(repeatInterval time.Duration, notifier Notifier, rules ...Rule) *Manager {
+	cfg := config.Alerting{Interval: time.Minute, RepeatInterval: repeatInterval}
+	return NewManager(zap.NewNop().Sugar(), cfg, rules, []Notifier{notifier})
+}
+
+func TestManagerDedupAndResolve(t *testing.T) {
+	h := &heights{chainTip: 100, lorenzoTip: 99}
+	rule := NewLorenzoTipLagRule("btc", func() (uint64, error) { return h.chainTip, h.err },
+		func() (uint64, error) { return h.lorenzoTip, nil }, 5)
+	notifier := &recordingNotifier{}
+	m := newTestManager(time.Hour, notifier, rule)
+	ctx := context.Background()
+	now := time.Now()
+
+	m.EvaluateOnce(ctx, now)
+	if len(notifier.notifications) != 0 {
+		t.Fatalf("unexpected notifications: %v", notifier.statuses())
+	}
+
+	// the alert is notified once while it keeps firing
+	h.chainTip = 110
+	m.EvaluateOnce(ctx, now.Add(time.Minute))
+	h.chainTip = 111
+	m.EvaluateOnce(ctx, now.Add(2*time.Minute))
+	if got := strings.Join(notifier.statuses(), ","); got != "lorenzo_tip_lag/btc:firing" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+
+	// failed evaluations keep the alert
+	h.err = errors.New("rpc down")
+	m.EvaluateOnce(ctx, now.Add(3*time.Minute))
+	h.err = nil
+
+	// repeated after the repeat interval
+	m.EvaluateOnce(ctx, now.Add(time.Hour+time.Minute))
+	if len(notifier.notifications) != 2 {
+		t.Fatalf("expect the alert repeated, got: %v", notifier.statuses())
+	}
+
+	h.lorenzoTip = 111
+	m.EvaluateOnce(ctx, now.Add(2*time.Hour))
+	if got := strings.Join(notifier.statuses(), ","); got !=
+		"lorenzo_tip_lag/btc:firing,lorenzo_tip_lag/btc:firing,lorenzo_tip_lag/btc:resolved" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+	resolved := notifier.notifications[2]
+	if !resolved.StartsAt.Equal(now.Add(time.Minute)) || !resolved.EndsAt.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("unexpected resolved notification: %+v", resolved)
+	}
+}
+
+func TestManagerRetriesUndeliveredAlert(t *testing.T) {
+	repository := db.NewMemoryBTCRepository()
+	if err := repository.InsertBtcDepositTxs([]*db.BtcDepositTx{{Txid: "a"}}, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+	notifier := &recordingNotifier{err: errors.New("webhook down")}
+	m := newTestManager(0, notifier, NewPendingDepositRule("btc", repository, time.Hour))
+	ctx := context.Background()
+	now := time.Now()
+
+	m.EvaluateOnce(ctx, now.Add(2*time.Hour))
+	notifier.err = nil
+	m.EvaluateOnce(ctx, now.Add(2*time.Hour+time.Minute))
+	m.EvaluateOnce(ctx, now.Add(2*time.Hour+2*time.Minute))
+	if got := strings.Join(notifier.statuses(), ","); got != "pending_deposit/btc:firing" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+	if summary := notifier.notifications[0].Alert.Summary; !strings.Contains(summary, "1 btc deposits") ||
+		!strings.Contains(summary, "oldest txid: a") {
+		t.Errorf("unexpected summary: %s", summary)
+	}
+
+	if err := repository.UpdateTxStatus("a", db.StatusSuccess, db.StatusChange{Actor: db.ActorRelayer}); err != nil {
+		t.Fatal(err)
+	}
+	m.EvaluateOnce(ctx, now.Add(3*time.Hour))
+	if got := strings.Join(notifier.statuses(), ","); got != "pending_deposit/btc:firing,pending_deposit/btc:resolved" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+}
+
+func TestManagerRulesOfTwoChains(t *testing.T) {
+	btc := &heights{chainTip: 110, lorenzoTip: 100}
+	bnb := &heights{chainTip: 100, lorenzoTip: 100}
+	newRule := func(chain string, h *heights) Rule {
+		return NewLorenzoTipLagRule(chain, func() (uint64, error) { return h.chainTip, nil },
+			func() (uint64, error) { return h.lorenzoTip, nil }, 5)
+	}
+	notifier := &recordingNotifier{}
+	m := newTestManager(0, notifier, newRule("btc", btc), newRule("bnb", bnb))
+	ctx := context.Background()
+	now := time.Now()
+
+	// the bnb rule of the same name does not resolve the firing btc alert
+	for i := 0; i < 3; i++ {
+		m.EvaluateOnce(ctx, now.Add(time.Duration(i)*time.Minute))
+	}
+	if got := strings.Join(notifier.statuses(), ","); got != "lorenzo_tip_lag/btc:firing" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+
+	bnb.chainTip = 110
+	btc.lorenzoTip = 110
+	m.EvaluateOnce(ctx, now.Add(3*time.Minute))
+	m.EvaluateOnce(ctx, now.Add(4*time.Minute))
+	if got := strings.Join(notifier.statuses(), ","); got !=
+		"lorenzo_tip_lag/btc:firing,lorenzo_tip_lag/btc:resolved,lorenzo_tip_lag/bnb:firing" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+}
+
+func TestSyncStalledRule(t *testing.T) {
+	repository := db.NewMemoryBNBRepository("bnb")
+	rule := NewSyncStalledRule("bnb", repository, time.Hour)
+	now := time.Now()
+
+	// nothing has been scanned yet
+	if alerts, err := rule.Evaluate(context.Background(), now); err != nil || len(alerts) != 0 {
+		t.Fatalf("unexpected alerts: %v, error: %v", alerts, err)
+	}
+	if err := repository.UpdateSyncPoint(100, ""); err != nil {
+		t.Fatal(err)
+	}
+	if alerts, err := rule.Evaluate(context.Background(), now.Add(30*time.Minute)); err != nil || len(alerts) != 0 {
+		t.Fatalf("unexpected alerts: %v, error: %v", alerts, err)
+	}
+	alerts, err := rule.Evaluate(context.Background(), now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || alerts[0].Key != "sync_stalled/bnb" {
+		t.Fatalf("unexpected alerts: %v", alerts)
+	}
+}
+
+func TestInvalidDepositRule(t *testing.T) {
+	repository := db.NewMemoryBNBRepository("bnb")
+	txs := []*db.WrappedBTCDepositTx{{Chain: "bnb", Txid: "0xa"}, {Chain: "bnb", Txid: "0xb"}, {Chain: "bnb", Txid: "0xc"}}
+	if err := repository.InsertWrappedBTCDepositTxs(txs, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+	// transitions before the rule is created are not notified
+	if err := repository.MarkInvalid("0xa", 0, db.StatusChange{Actor: db.ActorRelayer, Error: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	rule := NewInvalidDepositRule("bnb", repository, time.Now())
+	notifier := &recordingNotifier{}
+	m := newTestManager(0, notifier, rule)
+
+	if err := repository.MarkSuccess("0xb", 0, db.StatusChange{Actor: db.ActorRelayer}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.MarkInvalidPlan("0xc", 0, db.StatusChange{Actor: db.ActorRelayer, Reason: "plan not found"}); err != nil {
+		t.Fatal(err)
+	}
+	m.EvaluateOnce(context.Background(), time.Now())
+	m.EvaluateOnce(context.Background(), time.Now())
+
+	// event alerts are notified once and never resolved
+	if got := strings.Join(notifier.statuses(), ","); got != "invalid_deposit/bnb/0xc/0:firing" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+	if summary := notifier.notifications[0].Alert.Summary; !strings.Contains(summary, "plan not found") {
+		t.Errorf("unexpected summary: %s", summary)
+	}
+}
+
+func TestInvalidDepositRuleRetriesUndelivered(t *testing.T) {
+	repository := db.NewMemoryBTCRepository()
+	if err := repository.InsertBtcDepositTxs([]*db.BtcDepositTx{{Txid: "a"}, {Txid: "b"}}, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+	rule := NewInvalidDepositRule("btc", repository, time.Now().Add(-time.Second))
+	notifier := &recordingNotifier{err: errors.New("webhook down")}
+	m := newTestManager(0, notifier, rule)
+
+	for _, txid := range []string{"a", "b"} {
+		if err := repository.UpdateTxStatus(txid, db.StatusInvalid, db.StatusChange{Actor: db.ActorRelayer, Error: "bad proof"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.EvaluateOnce(context.Background(), time.Now())
+	notifier.err = nil
+	m.EvaluateOnce(context.Background(), time.Now())
+	m.EvaluateOnce(context.Background(), time.Now())
+
+	// the events of the failed notification are notified once delivered, in order
+	if got := strings.Join(notifier.statuses(), ","); got != "invalid_deposit/btc/a/0:firing,invalid_deposit/btc/b/0:firing" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+}
+
+func TestLowBalanceRule(t *testing.T) {
+	balance := sdk.NewInt64Coin("alrz", 5)
+	rule := NewLowBalanceRule("lrz1submitter", func() (sdk.Coin, error) { return balance, nil }, sdk.NewInt64Coin("alrz", 10))
+
+	alerts, err := rule.Evaluate(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 1 || !strings.Contains(alerts[0].Summary, "5alrz") {
+		t.Fatalf("unexpected alerts: %v", alerts)
+	}
+	balance = sdk.NewInt64Coin("alrz", 10)
+	if alerts, err := rule.Evaluate(context.Background(), time.Now()); err != nil || len(alerts) != 0 {
+		t.Fatalf("unexpected alerts: %v, error: %v", alerts, err)
+	}
+}
+
+func TestWebhookNotifierPayloads(t *testing.T) {
+	var lock sync.Mutex
+	bodies := make(map[string]map[string]any)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		bodies[r.URL.Path] = body
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	notification := Notification{
+		Status:   StatusResolved,
+		Alert:    Alert{Key: "sync_stalled/btc", Rule: RuleSyncStalled, Chain: "btc", Summary: "btc sync point 10 has not advanced"},
+		StartsAt: time.Unix(1700000000, 0),
+		EndsAt:   time.Unix(1700003600, 0),
+	}
+	generic := NewWebhookNotifier(config.AlertWebhook{URL: server.URL + "/generic", Format: config.AlertWebhookFormatGeneric})
+	if err := generic.Notify(context.Background(), notification); err != nil {
+		t.Fatal(err)
+	}
+	slack := NewWebhookNotifier(config.AlertWebhook{URL: server.URL + "/slack", Format: config.AlertWebhookFormatSlack})
+	if err := slack.Notify(context.Background(), notification); err != nil {
+		t.Fatal(err)
+	}
+
+	if body := bodies["/generic"]; body["status"] != StatusResolved || body["key"] != "sync_stalled/btc" || body["endsAt"] == nil {
+		t.Errorf("unexpected generic payload: %v", body)
+	}
+	if text, _ := bodies["/slack"]["text"].(string); !strings.Contains(text, "[RESOLVED] sync_stalled") {
+		t.Errorf("unexpected slack payload: %v", bodies["/slack"])
+	}
+
+	failing := NewWebhookNotifier(config.AlertWebhook{URL: server.URL + "/missing", Format: config.AlertWebhookFormatGeneric})
+	server.Config.Handler = http.NotFoundHandler()
+	if err := failing.Notify(context.Background(), notification); err == nil {
+		t.Error("expect an error on a non 2xx response")
+	}
+}
+
+func TestInvalidDepositRuleResumesFromCursor(t *testing.T) {
+	repository := db.NewMemoryBTCRepository()
+	if err := repository.InsertBtcDepositTxs([]*db.BtcDepositTx{{Txid: "a"}, {Txid: "b"}}, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now().Add(-time.Second)
+	notifier := &recordingNotifier{}
+	m := newTestManager(0, notifier, NewInvalidDepositRule("btc", repository, since))
+	if err := repository.UpdateTxStatus("a", db.StatusInvalid, db.StatusChange{Actor: db.ActorRelayer, Error: "bad proof"}); err != nil {
+		t.Fatal(err)
+	}
+	m.EvaluateOnce(context.Background(), time.Now())
+
+	// a restarted rule neither sends the acknowledged alert again nor skips the transitions while it was down
+	if err := repository.UpdateTxStatus("b", db.StatusInvalid, db.StatusChange{Actor: db.ActorRelayer, Error: "bad proof"}); err != nil {
+		t.Fatal(err)
+	}
+	m = newTestManager(0, notifier, NewInvalidDepositRule("btc", repository, time.Now().Add(time.Hour)))
+	m.EvaluateOnce(context.Background(), time.Now())
+
+	if got := strings.Join(notifier.statuses(), ","); got != "invalid_deposit/btc/a/0:firing,invalid_deposit/btc/b/0:firing" {
+		t.Fatalf("unexpected notifications: %s", got)
+	}
+}
+
+func TestManagerRetriesFailedNotifiersOnly(t *testing.T) {
+	repository := db.NewMemoryBTCRepository()
+	if err := repository.InsertBtcDepositTxs([]*db.BtcDepositTx{{Txid: "a"}}, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+	rule := NewInvalidDepositRule("btc", repository, time.Now().Add(-time.Second))
+	accepting := &recordingNotifier{}
+	failing := &recordingNotifier{err: errors.New("webhook down")}
+	m := NewManager(zap.NewNop().Sugar(), config.Alerting{Interval: time.Minute}, []Rule{rule}, []Notifier{accepting, failing})
+	if err := repository.UpdateTxStatus("a", db.StatusInvalid, db.StatusChange{Actor: db.ActorRelayer, Error: "bad proof"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.EvaluateOnce(context.Background(), time.Now())
+	m.EvaluateOnce(context.Background(), time.Now())
+	failing.err = nil
+	m.EvaluateOnce(context.Background(), time.Now())
+	m.EvaluateOnce(context.Background(), time.Now())
+
+	if got := strings.Join(accepting.statuses(), ","); got != "invalid_deposit/btc/a/0:firing" {
+		t.Fatalf("unexpected notifications of the accepting notifier: %s", got)
+	}
+	if got := strings.Join(failing.statuses(), ","); got != "invalid_deposit/btc/a/0:firing" {
+		t.Fatalf("unexpected notifications of the failing notifier: %s", got)
+	}
+}
@@ -0,0 +1,267 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+// names of the rules
+const (
+	RulePendingDeposit = "pending_deposit"
+	RuleSyncStalled    = "sync_stalled"
+	RuleLorenzoTipLag  = "lorenzo_tip_lag"
+	RuleLowBalance     = "low_submitter_balance"
+	RuleInvalidDeposit = "invalid_deposit"
+)
+
+const (
+	// invalidDepositBatchSize is the number of status transitions read per evaluation of InvalidDepositRule
+	invalidDepositBatchSize = 500
+	// invalidDepositCursor is the name of the cursor of InvalidDepositRule, the id of its last acknowledged transition
+	invalidDepositCursor = "alert-" + RuleInvalidDeposit
+)
+
+// HeightFunc returns the tip of a chain
+type HeightFunc func() (uint64, error)
+
+// BalanceFunc returns the balance of the submitter
+type BalanceFunc func() (sdk.Coin, error)
+
+// PendingDepositRule fires while deposits of the chain are pending longer than the duration
+type PendingDepositRule struct {
+	chain      string
+	repository db.IPendingDepositRepository
+	after      time.Duration
+}
+
+func NewPendingDepositRule(chain string, repository db.IPendingDepositRepository, after time.Duration) *PendingDepositRule {
+	return &PendingDepositRule{chain: chain, repository: repository, after: after}
+}
+
+func (r *PendingDepositRule) Name() string {
+	return RulePendingDeposit
+}
+
+func (r *PendingDepositRule) Evaluate(_ context.Context, now time.Time) ([]Alert, error) {
+	pending, err := r.repository.GetPendingDeposits(now.Add(-r.after))
+	if err != nil {
+		return nil, err
+	}
+	if pending.Count == 0 {
+		return nil, nil
+	}
+
+	return []Alert{{
+		Key:   fmt.Sprintf("%s/%s", RulePendingDeposit, r.chain),
+		Rule:  RulePendingDeposit,
+		Chain: r.chain,
+		Summary: fmt.Sprintf("%d %s deposits pending for more than %s, oldest txid: %s since %s", pending.Count, r.chain,
+			r.after, pending.OldestTxid, pending.OldestCreatedTime.UTC().Format(time.RFC3339)),
+	}}, nil
+}
+
+// SyncStalledRule fires while the sync point of the chain has not advanced for the duration
+type SyncStalledRule struct {
+	chain      string
+	repository db.ISyncPointRepository
+	after      time.Duration
+}
+
+func NewSyncStalledRule(chain string, repository db.ISyncPointRepository, after time.Duration) *SyncStalledRule {
+	return &SyncStalledRule{chain: chain, repository: repository, after: after}
+}
+
+func (r *SyncStalledRule) Name() string {
+	return RuleSyncStalled
+}
+
+func (r *SyncStalledRule) Evaluate(_ context.Context, now time.Time) ([]Alert, error) {
+	checkpoint, err := r.repository.GetCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	// nothing has been scanned yet
+	if checkpoint.UpdatedTime.IsZero() || now.Sub(checkpoint.UpdatedTime) <= r.after {
+		return nil, nil
+	}
+
+	return []Alert{{
+		Key:   fmt.Sprintf("%s/%s", RuleSyncStalled, r.chain),
+		Rule:  RuleSyncStalled,
+		Chain: r.chain,
+		Summary: fmt.Sprintf("%s sync point %d has not advanced since %s", r.chain, checkpoint.Height,
+			checkpoint.UpdatedTime.UTC().Format(time.RFC3339)),
+	}}, nil
+}
+
+// LorenzoTipLagRule fires while the Lorenzo light client of the chain lags behind the chain tip by more than maxLag blocks
+type LorenzoTipLagRule struct {
+	chain      string
+	chainTip   HeightFunc
+	lorenzoTip HeightFunc
+	maxLag     uint64
+}
+
+func NewLorenzoTipLagRule(chain string, chainTip HeightFunc, lorenzoTip HeightFunc, maxLag uint64) *LorenzoTipLagRule {
+	return &LorenzoTipLagRule{chain: chain, chainTip: chainTip, lorenzoTip: lorenzoTip, maxLag: maxLag}
+}
+
+func (r *LorenzoTipLagRule) Name() string {
+	return RuleLorenzoTipLag
+}
+
+func (r *LorenzoTipLagRule) Evaluate(_ context.Context, _ time.Time) ([]Alert, error) {
+	chainTip, err := r.chainTip()
+	if err != nil {
+		return nil, fmt.Errorf("get %s tip: %w", r.chain, err)
+	}
+	lorenzoTip, err := r.lorenzoTip()
+	if err != nil {
+		return nil, fmt.Errorf("get Lorenzo %s light client tip: %w", r.chain, err)
+	}
+	if chainTip <= lorenzoTip+r.maxLag {
+		return nil, nil
+	}
+
+	return []Alert{{
+		Key:   fmt.Sprintf("%s/%s", RuleLorenzoTipLag, r.chain),
+		Rule:  RuleLorenzoTipLag,
+		Chain: r.chain,
+		Summary: fmt.Sprintf("Lorenzo %s light client tip %d lags behind the chain tip %d by %d blocks",
+			r.chain, lorenzoTip, chainTip, chainTip-lorenzoTip),
+	}}, nil
+}
+
+// LowBalanceRule fires while the submitter balance is lower than the minimum
+type LowBalanceRule struct {
+	submitter string
+	balance   BalanceFunc
+	min       sdk.Coin
+}
+
+func NewLowBalanceRule(submitter string, balance BalanceFunc, min sdk.Coin) *LowBalanceRule {
+	return &LowBalanceRule{submitter: submitter, balance: balance, min: min}
+}
+
+func (r *LowBalanceRule) Name() string {
+	return RuleLowBalance
+}
+
+func (r *LowBalanceRule) Evaluate(_ context.Context, _ time.Time) ([]Alert, error) {
+	balance, err := r.balance()
+	if err != nil {
+		return nil, err
+	}
+	if !balance.Amount.LT(r.min.Amount) {
+		return nil, nil
+	}
+
+	return []Alert{{
+		Key:     fmt.Sprintf("%s/%s", RuleLowBalance, r.submitter),
+		Rule:    RuleLowBalance,
+		Summary: fmt.Sprintf("submitter %s balance %s is lower than %s", r.submitter, balance, r.min),
+	}}, nil
+}
+
+// InvalidDepositRepository is the status history of a chain and the cursors kept with its events
+type InvalidDepositRepository interface {
+	db.IStatusHistoryRepository
+	GetEventCursor(publisher string) (int, error)
+	SetEventCursor(publisher string, id int) error
+}
+
+// InvalidDepositRule notifies the deposits of the chain marked invalid. The id of the last acknowledged transition
+// is kept in the event cursors of the chain, so the alerts are neither lost nor sent again across restarts;
+// the transitions before since are skipped only while no cursor is stored yet.
+type InvalidDepositRule struct {
+	chain      string
+	repository InvalidDepositRepository
+	since      time.Time
+	// lastId is the id of the last transition acknowledged, the ones after it are read again
+	lastId int
+	loaded bool
+}
+
+func NewInvalidDepositRule(chain string, repository InvalidDepositRepository, since time.Time) *InvalidDepositRule {
+	return &InvalidDepositRule{chain: chain, repository: repository, since: since}
+}
+
+func (r *InvalidDepositRule) Name() string {
+	return RuleInvalidDeposit
+}
+
+func (r *InvalidDepositRule) Evaluate(_ context.Context, _ time.Time) ([]Alert, error) {
+	if !r.loaded {
+		lastId, err := r.repository.GetEventCursor(invalidDepositCursor)
+		if err != nil {
+			return nil, err
+		}
+		r.lastId = lastId
+		if lastId > 0 {
+			r.since = time.Time{}
+		}
+		r.loaded = true
+	}
+
+	var alerts []Alert
+	lastId := r.lastId
+	for {
+		history, err := r.repository.GetStatusHistoryAfter(lastId, r.since, invalidDepositBatchSize)
+		if err != nil {
+			// the alerts of the read transitions are not lost, they are notified with the next ones
+			if len(alerts) > 0 {
+				return alerts, nil
+			}
+			return nil, err
+		}
+		for _, h := range history {
+			lastId = h.Id
+			if h.NewStatus != db.StatusInvalid && h.NewStatus != db.StatusInvalidPlan {
+				continue
+			}
+			reason := h.Error
+			if reason == "" {
+				reason = h.Reason
+			}
+			alerts = append(alerts, Alert{
+				Key:      fmt.Sprintf("%s/%s/%s/%d", RuleInvalidDeposit, r.chain, h.Txid, h.LogIndex),
+				Rule:     RuleInvalidDeposit,
+				Chain:    r.chain,
+				Summary:  fmt.Sprintf("%s deposit %s (log index %d) marked invalid by %s: %s", r.chain, h.Txid, h.LogIndex, h.Actor, reason),
+				Event:    true,
+				position: h.Id,
+			})
+		}
+		if len(history) < invalidDepositBatchSize {
+			break
+		}
+	}
+
+	// the transitions without an alert need no acknowledgement
+	if len(alerts) == 0 && lastId > r.lastId {
+		if err := r.acknowledge(lastId); err != nil {
+			return nil, err
+		}
+	}
+	return alerts, nil
+}
+
+// Acknowledge skips the transitions up to the delivered alert
+func (r *InvalidDepositRule) Acknowledge(alert Alert) error {
+	if alert.position <= r.lastId {
+		return nil
+	}
+	return r.acknowledge(alert.position)
+}
+
+// acknowledge moves the cursor to the transition, it's moved in memory even if it is not stored:
+// the alerts after the stored cursor are sent again after a restart only
+func (r *InvalidDepositRule) acknowledge(id int) error {
+	r.lastId = id
+	return r.repository.SetEventCursor(invalidDepositCursor, id)
+}
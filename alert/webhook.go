@@ -0,0 +1,95 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// WebhookNotifier posts the notifications as JSON to a webhook url
+type WebhookNotifier struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+func NewWebhookNotifier(cfg config.AlertWebhook) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    cfg.URL,
+		format: cfg.Format,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// webhookPayload is the generic payload of a notification
+type webhookPayload struct {
+	Status   string     `json:"status"`
+	Key      string     `json:"key"`
+	Rule     string     `json:"rule"`
+	Chain    string     `json:"chain,omitempty"`
+	Summary  string     `json:"summary"`
+	StartsAt time.Time  `json:"startsAt"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+}
+
+// slackPayload is accepted by Slack incoming webhooks and compatible services
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(n.payload(notification))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook responded %d: %s", resp.StatusCode, message)
+	}
+
+	return nil
+}
+
+func (n *WebhookNotifier) payload(notification Notification) any {
+	alert := notification.Alert
+	if n.format == config.AlertWebhookFormatSlack {
+		text := fmt.Sprintf(":rotating_light: [FIRING] %s: %s", alert.Rule, alert.Summary)
+		if notification.Status == StatusResolved {
+			text = fmt.Sprintf(":white_check_mark: [RESOLVED] %s: %s (since %s)", alert.Rule, alert.Summary,
+				notification.StartsAt.UTC().Format(time.RFC3339))
+		}
+		return slackPayload{Text: text}
+	}
+
+	payload := webhookPayload{
+		Status:   notification.Status,
+		Key:      alert.Key,
+		Rule:     alert.Rule,
+		Chain:    alert.Chain,
+		Summary:  alert.Summary,
+		StartsAt: notification.StartsAt,
+	}
+	if !notification.EndsAt.IsZero() {
+		payload.EndsAt = &notification.EndsAt
+	}
+	return payload
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
 )
 
 type Refresher struct {
@@ -21,18 +22,13 @@ type Refresher struct {
 	logger *zap.Logger
 }
 
-func NewRefresher(startHeight uint64, blockscoutApi string, lorenzoAppApi string) (*Refresher, error) {
-	logger, err := config.NewRootLogger("json", true)
-	if err != nil {
-		return nil, err
-	}
-
+func NewRefresher(startHeight uint64, blockscoutApi string, lorenzoAppApi string, logger *zap.Logger) *Refresher {
 	return &Refresher{
 		blockscoutApi:     blockscoutApi,
 		lorenzoAppApi:     lorenzoAppApi,
 		nextRefreshHeight: startHeight,
-		logger:            logger,
-	}, nil
+		logger:            logger.Named(config.LogComponentRefresher),
+	}
 }
 
 func (r *Refresher) Start() error {
@@ -40,11 +36,14 @@ func (r *Refresher) Start() error {
 	lorenzoBlockHeightSleepTime := time.Second * 5
 	batchNum := uint64(1000)
 	for {
+		metrics.BlockscoutCursor.Set(float64(r.nextRefreshHeight))
 		blockscoutHeight, err := r.getBockscoutCurrentHeight()
 		if err != nil {
+			metrics.Error(metrics.ComponentBlockscout, metrics.ErrorClassBlockscoutApi)
 			time.Sleep(networkErrorSleepTime)
 			continue
 		}
+		metrics.BlockscoutHeight.Set(float64(blockscoutHeight))
 		startHeight := r.nextRefreshHeight
 		if blockscoutHeight < startHeight {
 			r.logger.Warn("blockscout height is less than start height",
@@ -60,10 +59,12 @@ func (r *Refresher) Start() error {
 
 		eventScanCursor, err := r.getLorenzoEventScanCursor()
 		if err != nil {
+			metrics.Error(metrics.ComponentBlockscout, metrics.ErrorClassBlockscoutApi)
 			r.logger.Warn("get EventScanCursor failed", zap.Error(err))
 			time.Sleep(networkErrorSleepTime)
 			continue
 		}
+		metrics.BlockscoutEventScanCursor.Set(float64(eventScanCursor))
 		if eventScanCursor < startHeight {
 			r.logger.Warn("event scan cursor is less than start height",
 				zap.Uint64("eventScanCursor", eventScanCursor), zap.Uint64("startHeight", startHeight))
@@ -75,6 +76,7 @@ func (r *Refresher) Start() error {
 		}
 		events, err := r.getLorenzoBurnOrEventListByHeightRange(r.lorenzoAppApi, startHeight, endHeight)
 		if err != nil {
+			metrics.Error(metrics.ComponentBlockscout, metrics.ErrorClassBlockscoutApi)
 			r.logger.Warn("get events failed", zap.Error(err))
 			time.Sleep(networkErrorSleepTime)
 			continue
@@ -90,10 +92,12 @@ func (r *Refresher) Start() error {
 		for i < len(events) {
 			event := events[i]
 			if err := r.refreshBlockscoutBalance(event.LorenzoAddr, event.LorenzoBlockHeight); err != nil {
+				metrics.Error(metrics.ComponentBlockscout, metrics.ErrorClassBlockscoutApi)
 				r.logger.Warn("refresh failed", zap.Error(err))
 				time.Sleep(networkErrorSleepTime)
 				continue
 			}
+			metrics.BlockscoutRefreshedAccountsTotal.Inc()
 
 			r.logger.Info("account balance refreshed", zap.String("address", event.LorenzoAddr),
 				zap.Uint64("height", event.LorenzoBlockHeight))
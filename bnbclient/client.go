@@ -2,6 +2,10 @@ package bnbclient
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -9,6 +13,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
 	lru "github.com/hashicorp/golang-lru/v2"
 
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/bnbclient/bnbtypes"
@@ -17,6 +22,8 @@ import (
 const (
 	BlockReceiptsCacheSize = 100
 	BlockHeaderCacheSize   = 100
+	// ReceiptsBatchSize is the max number of eth_getTransactionReceipt requests in one batch call
+	ReceiptsBatchSize = 100
 )
 
 type Client struct {
@@ -26,6 +33,17 @@ type Client struct {
 
 	blockReceiptsCache *lru.Cache[common.Hash, []*types.Receipt]
 	blockHeaderCache   *lru.Cache[common.Hash, *bnbtypes.Header]
+
+	// hub events decoded from logs
+	events *EventRegistry
+
+	// set once the rpc provider reports eth_getBlockReceipts is unavailable
+	blockReceiptsUnsupported atomic.Bool
+
+	// optional websocket endpoint for subscriptions, dialed on demand
+	wsUrl    string
+	wsClient *ethclient.Client
+	wsLock   sync.Mutex
 }
 
 func New(rpcUrl string) (*Client, error) {
@@ -53,6 +71,7 @@ func New(rpcUrl string) (*Client, error) {
 		rpcClient:          rpcClient,
 		blockReceiptsCache: blockReceiptsCache,
 		blockHeaderCache:   blockHeaderCache,
+		events:             DefaultEventRegistry,
 	}, nil
 }
 
@@ -61,16 +80,118 @@ func (c *Client) ReceiptsByBlockHash(hash common.Hash) ([]*types.Receipt, error)
 		return receipts, nil
 	}
 
+	var receipts []*types.Receipt
+	var err error
+	if !c.blockReceiptsUnsupported.Load() {
+		receipts, err = c.blockReceiptsByHash(hash)
+		if IsMethodNotSupportedError(err) {
+			c.blockReceiptsUnsupported.Store(true)
+		}
+	}
+	if c.blockReceiptsUnsupported.Load() {
+		receipts, err = c.transactionReceiptsByBlockHash(hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.blockReceiptsCache.Add(hash, receipts)
+	return receipts, nil
+}
+
+func (c *Client) blockReceiptsByHash(hash common.Hash) ([]*types.Receipt, error) {
 	var receipts []*types.Receipt
 	err := c.rpcClient.CallContext(context.Background(), &receipts, "eth_getBlockReceipts", hash.Hex())
 	if err == nil && receipts == nil {
 		return nil, ethereum.NotFound
 	}
 
-	c.blockReceiptsCache.Add(hash, receipts)
 	return receipts, err
 }
 
+// transactionReceiptsByBlockHash fetches the receipts of a block by batched eth_getTransactionReceipt calls,
+// it's the fallback for rpc providers without eth_getBlockReceipts.
+// The receipts trie rebuilt from the result must match the ReceiptHash of the block header.
+func (c *Client) transactionReceiptsByBlockHash(hash common.Hash) ([]*types.Receipt, error) {
+	header, txHashes, err := c.headerWithTxHashesByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := make([]*types.Receipt, len(txHashes))
+	for start := 0; start < len(txHashes); start += ReceiptsBatchSize {
+		end := min(start+ReceiptsBatchSize, len(txHashes))
+		batch := make([]rpc.BatchElem, 0, end-start)
+		for i := start; i < end; i++ {
+			batch = append(batch, rpc.BatchElem{
+				Method: "eth_getTransactionReceipt",
+				Args:   []interface{}{txHashes[i]},
+				Result: &receipts[i],
+			})
+		}
+		if err := c.rpcClient.BatchCallContext(context.Background(), batch); err != nil {
+			return nil, err
+		}
+
+		for i, elem := range batch {
+			if elem.Error != nil {
+				return nil, elem.Error
+			}
+			if receipts[start+i] == nil {
+				return nil, fmt.Errorf("receipt of tx %s not found: %w", txHashes[start+i].Hex(), ethereum.NotFound)
+			}
+		}
+	}
+
+	receiptHash := types.DeriveSha(types.Receipts(receipts), trie.NewStackTrie(nil))
+	if receiptHash != header.ReceiptHash {
+		return nil, fmt.Errorf("receipts root mismatch, block: %s, expect: %s, got: %s",
+			hash.Hex(), header.ReceiptHash.Hex(), receiptHash.Hex())
+	}
+
+	return receipts, nil
+}
+
+// headerWithTxHashesByHash returns the block header and the transaction hashes of the block
+func (c *Client) headerWithTxHashesByHash(hash common.Hash) (*bnbtypes.Header, []common.Hash, error) {
+	var raw json.RawMessage
+	err := c.rpcClient.CallContext(context.Background(), &raw, "eth_getBlockByHash", hash, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := decodeHeader(raw, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	var body struct {
+		Transactions []common.Hash `json:"transactions"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, nil, err
+	}
+
+	return header, body.Transactions, nil
+}
+
+// decodeHeader decodes a block returned by the rpc and checks that the header hashes to the requested hash,
+// the receipts root of the header can then be trusted for the block. Header fields added by a fork must be
+// added to bnbtypes.Header, otherwise the blocks after the fork are rejected.
+func decodeHeader(raw json.RawMessage, hash common.Hash) (*bnbtypes.Header, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, ethereum.NotFound
+	}
+
+	var header bnbtypes.Header
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, err
+	}
+	if headerHash := header.Hash(); headerHash != hash {
+		return nil, fmt.Errorf("%w: header hash mismatch, expect: %s, got: %s", ErrIntegrity, hash.Hex(), headerHash.Hex())
+	}
+
+	return &header, nil
+}
+
 func (c *Client) ReceiptsByBlockNumber(number uint64) ([]*types.Receipt, error) {
 	var r []*types.Receipt
 	err := c.rpcClient.CallContext(context.Background(), &r, "eth_getBlockReceipts", hexutil.Uint64(number).String())
@@ -97,14 +218,28 @@ func (c *Client) HeaderByHash(hash common.Hash) (*bnbtypes.Header, error) {
 		return header, nil
 	}
 
-	var header *bnbtypes.Header
-	err := c.rpcClient.CallContext(context.Background(), &header, "eth_getBlockByHash", hash, false)
-	if err == nil && header == nil {
-		err = ethereum.NotFound
+	var raw json.RawMessage
+	err := c.rpcClient.CallContext(context.Background(), &raw, "eth_getBlockByHash", hash, false)
+	if err != nil {
+		return nil, err
+	}
+	header, err := decodeHeader(raw, hash)
+	if err != nil {
+		return nil, err
 	}
 
-	c.blockHeaderCache.Add(header.Hash(), header)
-	return header, err
+	c.blockHeaderCache.Add(hash, header)
+	return header, nil
+}
+
+// BlockHashByNumber returns the hash of the canonical block at the height, computed from its header
+func (c *Client) BlockHashByNumber(number uint64) (common.Hash, error) {
+	header, err := c.HeaderByNumber(number)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return header.Hash(), nil
 }
 
 func (c *Client) BlockNumber() (uint64, error) {
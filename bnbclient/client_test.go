@@ -0,0 +1,177 @@
+package bnbclient
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/bnbclient/bnbtypes"
+)
+
+// testEthService serves blocks and receipts without eth_getBlockReceipts
+type testEthService struct {
+	hash     common.Hash
+	block    json.RawMessage
+	receipts map[common.Hash]json.RawMessage
+}
+
+func (s *testEthService) GetBlockByHash(_ common.Hash, _ bool) (json.RawMessage, error) {
+	return s.block, nil
+}
+
+func (s *testEthService) GetBlockByNumber(_ string, _ bool) (json.RawMessage, error) {
+	return s.block, nil
+}
+
+func (s *testEthService) GetTransactionReceipt(hash common.Hash) (json.RawMessage, error) {
+	return s.receipts[hash], nil
+}
+
+func newTestEthService(t *testing.T, receiptsRoot common.Hash) *testEthService {
+	return newTestEthServiceWithHeader(t, &bnbtypes.Header{
+		ReceiptHash: receiptsRoot,
+		Difficulty:  big.NewInt(2),
+		Number:      big.NewInt(0x2922a8e),
+		Extra:       []byte{},
+	})
+}
+
+// newTestEthServiceWithHeader serves the block of the header, the block hash is the hash of the header
+func newTestEthServiceWithHeader(t *testing.T, header *bnbtypes.Header) *testEthService {
+	var rawReceipts []json.RawMessage
+	if err := json.Unmarshal([]byte(testBlockReceiptsJson), &rawReceipts); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := &testEthService{receipts: map[common.Hash]json.RawMessage{}}
+	var txHashes []common.Hash
+	for _, rawReceipt := range rawReceipts {
+		var receipt struct {
+			TxHash common.Hash `json:"transactionHash"`
+		}
+		if err := json.Unmarshal(rawReceipt, &receipt); err != nil {
+			t.Fatal(err)
+		}
+		txHashes = append(txHashes, receipt.TxHash)
+		svc.receipts[receipt.TxHash] = rawReceipt
+	}
+
+	svc.hash = header.Hash()
+	headerRaw, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block map[string]interface{}
+	if err := json.Unmarshal(headerRaw, &block); err != nil {
+		t.Fatal(err)
+	}
+	block["transactions"] = txHashes
+	if svc.block, err = json.Marshal(block); err != nil {
+		t.Fatal(err)
+	}
+
+	return svc
+}
+
+func newTestClient(t *testing.T, svc *testEthService) *Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", svc); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	client, err := New(httpServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestReceiptsByBlockHashFallback(t *testing.T) {
+	svc := newTestEthService(t, common.HexToHash(testBlockReceiptsRoot))
+	client := newTestClient(t, svc)
+
+	blockHash := svc.hash
+	receipts, err := client.ReceiptsByBlockHash(blockHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != len(svc.receipts) {
+		t.Errorf("receipts count mismatch, expect: %d, got: %d", len(svc.receipts), len(receipts))
+	}
+	if !client.blockReceiptsUnsupported.Load() {
+		t.Error("eth_getBlockReceipts should be marked unsupported")
+	}
+}
+
+func TestReceiptsByBlockHashFallbackRootMismatch(t *testing.T) {
+	svc := newTestEthService(t, common.HexToHash("0x01"))
+	client := newTestClient(t, svc)
+
+	blockHash := svc.hash
+	if _, err := client.ReceiptsByBlockHash(blockHash); err == nil {
+		t.Fatal("expect receipts root mismatch error")
+	}
+	if _, ok := client.blockReceiptsCache.Get(blockHash); ok {
+		t.Error("mismatched receipts should not be cached")
+	}
+}
+
+// a header of a fork adding header fields hashes to its block hash
+func TestHeaderByHashPostFork(t *testing.T) {
+	requestsHash := common.HexToHash("0xe3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	baseFee := big.NewInt(0)
+	svc := newTestEthServiceWithHeader(t, &bnbtypes.Header{
+		ReceiptHash:  common.HexToHash(testBlockReceiptsRoot),
+		Difficulty:   big.NewInt(2),
+		Number:       big.NewInt(0x2922a8e),
+		Extra:        []byte{},
+		BaseFee:      baseFee,
+		RequestsHash: &requestsHash,
+	})
+	client := newTestClient(t, svc)
+
+	header, err := client.HeaderByHash(svc.hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.RequestsHash == nil || *header.RequestsHash != requestsHash || header.ReceiptHash != common.HexToHash(testBlockReceiptsRoot) {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	if hash, err := client.BlockHashByNumber(0x2922a8e); err != nil || hash != svc.hash {
+		t.Errorf("unexpected block hash: %s, error: %v", hash.Hex(), err)
+	}
+
+	// a node answering with another block is rejected
+	if _, err := client.HeaderByHash(common.HexToHash("0x01")); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("expect integrity error, got: %v", err)
+	}
+}
+
+// a header whose fields do not hash to the block hash is rejected, even if the rpc reports the block hash
+func TestHeaderByHashTampered(t *testing.T) {
+	svc := newTestEthService(t, common.HexToHash(testBlockReceiptsRoot))
+	var block map[string]interface{}
+	if err := json.Unmarshal(svc.block, &block); err != nil {
+		t.Fatal(err)
+	}
+	block["receiptsRoot"] = common.HexToHash("0x01")
+	var err error
+	if svc.block, err = json.Marshal(block); err != nil {
+		t.Fatal(err)
+	}
+	client := newTestClient(t, svc)
+
+	if _, err := client.HeaderByHash(svc.hash); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("expect integrity error, got: %v", err)
+	}
+	if _, err := client.ReceiptsByBlockHash(svc.hash); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("expect integrity error, got: %v", err)
+	}
+}
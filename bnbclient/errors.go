@@ -0,0 +1,82 @@
+package bnbclient
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// LimitExceededErrorCode is the JSON-RPC error code returned by most providers when a request exceeds
+	// the node's resource limits. Some providers return it for rate limits too, so it only marks a block range
+	// error together with one of blockRangeTooLargeErrorMessages.
+	LimitExceededErrorCode = -32005
+	// MethodNotFoundErrorCode is the JSON-RPC error code of an unknown method.
+	MethodNotFoundErrorCode = -32601
+)
+
+// blockRangeTooLargeErrorMessages are the error messages returned by common BSC RPC
+// providers when an eth_getLogs block range is too large or returns too many results.
+// Generic messages like "limit exceeded" or "block range" are left out, they are returned for rate limits
+// and for invalid or not yet available ranges as well, which a smaller range does not fix.
+var blockRangeTooLargeErrorMessages = []string{
+	"exceed maximum block range",
+	"block range limit exceeded",
+	"range too large",
+	"range is too large",
+	"too many results",
+	"query returned more than",
+	"response size exceeded",
+	"response size should not greater than",
+}
+
+// methodNotSupportedErrorMessages are the error messages returned by RPC providers
+// which don't expose a (non-standard) method.
+var methodNotSupportedErrorMessages = []string{
+	"method not found",
+	"does not exist/is not available",
+	"not supported",
+	"unsupported method",
+	"method not allowed",
+}
+
+// IsBlockRangeTooLargeError reports whether err is a provider rejection caused by
+// the requested eth_getLogs range, in which case the caller should retry with a smaller range.
+// The error code is not enough: rate limited requests are rejected with LimitExceededErrorCode by some providers
+// and retrying them with a smaller range would only shrink the range for nothing.
+func IsBlockRangeTooLargeError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	for _, msg := range blockRangeTooLargeErrorMessages {
+		if strings.Contains(errMsg, msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsMethodNotSupportedError reports whether err means the RPC provider doesn't expose the called method.
+func IsMethodNotSupportedError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == MethodNotFoundErrorCode {
+		return true
+	}
+
+	errMsg := strings.ToLower(err.Error())
+	for _, msg := range methodNotSupportedErrorMessages {
+		if strings.Contains(errMsg, msg) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package bnbclient
+
+import (
+	"errors"
+	"testing"
+)
+
+type testRpcError struct {
+	code int
+	msg  string
+}
+
+func (e testRpcError) Error() string  { return e.msg }
+func (e testRpcError) ErrorCode() int { return e.code }
+
+func TestIsBlockRangeTooLargeError(t *testing.T) {
+	testCases := []struct {
+		err    error
+		expect bool
+	}{
+		{nil, false},
+		{errors.New("exceed maximum block range: 5000"), true},
+		{errors.New("query returned more than 10000 results"), true},
+		{errors.New("Block range is too large"), true},
+		{errors.New("block range limit exceeded, max: 1000"), true},
+		{testRpcError{code: LimitExceededErrorCode, msg: "query returned more than 10000 results"}, true},
+		{testRpcError{code: -32000, msg: "header not found"}, false},
+		{errors.New("context deadline exceeded"), false},
+		// other block range errors are not fixed by a smaller range
+		{errors.New("invalid block range"), false},
+		{testRpcError{code: -32000, msg: "invalid block range params: fromBlock 120 > toBlock 100"}, false},
+		{errors.New("block range extends beyond current head block"), false},
+		// rate limits are not range errors, whatever their code
+		{errors.New("rate limit exceeded"), false},
+		{errors.New("limit exceeded"), false},
+		{testRpcError{code: LimitExceededErrorCode, msg: "request rate limit exceeded"}, false},
+		{testRpcError{code: LimitExceededErrorCode, msg: "request failed"}, false},
+		{testRpcError{code: 429, msg: "Too Many Requests"}, false},
+	}
+
+	for _, tc := range testCases {
+		if got := IsBlockRangeTooLargeError(tc.err); got != tc.expect {
+			t.Errorf("IsBlockRangeTooLargeError(%v) = %v, expect %v", tc.err, got, tc.expect)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package bnbclient
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+//go:embed abi/*.json
+var abiFS embed.FS
+
+// HubEvent is a decoded event of a Lorenzo hub contract
+type HubEvent interface {
+	EventName() string
+	EventLog() HubEventLog
+	SetEventLog(log types.Log)
+}
+
+// HubEventLog is the position of the log an event is decoded from, embed it in the typed event structs
+type HubEventLog struct {
+	Txhash    common.Hash
+	BlockHash common.Hash
+	LogIndex  uint
+}
+
+func (l *HubEventLog) EventLog() HubEventLog {
+	return *l
+}
+
+func (l *HubEventLog) SetEventLog(log types.Log) {
+	l.Txhash = log.TxHash
+	l.BlockHash = log.BlockHash
+	l.LogIndex = log.Index
+}
+
+// NewLorenzoMsgFunc builds the Lorenzo message which relays the receipt and proof of an event
+type NewLorenzoMsgFunc func(signer string, number uint64, receipt []byte, proof []byte) sdk.Msg
+
+// EventDefinition defines a hub contract event relayed to Lorenzo
+type EventDefinition struct {
+	Name  string
+	Topic common.Hash
+	// NewEvent returns a new typed event, the log is decoded into it by the ABI argument names
+	NewEvent func() HubEvent
+	// NewLorenzoMsg builds the Lorenzo message of the event
+	NewLorenzoMsg NewLorenzoMsgFunc
+
+	contractABI abi.ABI
+	event       abi.Event
+}
+
+// NewEventDefinition creates the definition of the named event in the embedded ABI file
+func NewEventDefinition(abiFile string, name string, newEvent func() HubEvent, newLorenzoMsg NewLorenzoMsgFunc) (*EventDefinition, error) {
+	abiData, err := abiFS.ReadFile("abi/" + abiFile)
+	if err != nil {
+		return nil, err
+	}
+	contractABI, err := abi.JSON(bytes.NewReader(abiData))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ABI file %s: %v", abiFile, err)
+	}
+	event, ok := contractABI.Events[name]
+	if !ok {
+		return nil, fmt.Errorf("event %s not found in ABI file %s", name, abiFile)
+	}
+
+	return &EventDefinition{
+		Name:          name,
+		Topic:         event.ID,
+		NewEvent:      newEvent,
+		NewLorenzoMsg: newLorenzoMsg,
+		contractABI:   contractABI,
+		event:         event,
+	}, nil
+}
+
+func mustNewEventDefinition(abiFile string, name string, newEvent func() HubEvent, newLorenzoMsg NewLorenzoMsgFunc) *EventDefinition {
+	def, err := NewEventDefinition(abiFile, name, newEvent, newLorenzoMsg)
+	if err != nil {
+		panic(err)
+	}
+	return def
+}
+
+// Decode decodes a log of the event
+func (def *EventDefinition) Decode(log types.Log) (HubEvent, error) {
+	if len(log.Topics) == 0 || log.Topics[0] != def.Topic {
+		return nil, fmt.Errorf("log is not a %s event", def.Name)
+	}
+
+	event := def.NewEvent()
+	if len(def.event.Inputs.NonIndexed()) > 0 {
+		if err := def.contractABI.UnpackIntoInterface(event, def.Name, log.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	var indexed abi.Arguments
+	for _, arg := range def.event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopics(event, indexed, log.Topics[1:]); err != nil {
+		return nil, err
+	}
+
+	event.SetEventLog(log)
+	return event, nil
+}
+
+// EventRegistry is the set of hub events relayed to Lorenzo
+type EventRegistry struct {
+	definitions []*EventDefinition
+}
+
+// NewEventRegistry creates a registry of the given event definitions
+func NewEventRegistry(definitions ...*EventDefinition) *EventRegistry {
+	return &EventRegistry{definitions: definitions}
+}
+
+// Topics returns the topics of all registered events
+func (r *EventRegistry) Topics() []common.Hash {
+	topics := make([]common.Hash, 0, len(r.definitions))
+	for _, def := range r.definitions {
+		topics = append(topics, def.Topic)
+	}
+	return topics
+}
+
+// ByTopic returns the event definition of the topic, or nil if not registered
+func (r *EventRegistry) ByTopic(topic common.Hash) *EventDefinition {
+	for _, def := range r.definitions {
+		if def.Topic == topic {
+			return def
+		}
+	}
+	return nil
+}
+
+// ByName returns the event definition of the name, or nil if not registered
+func (r *EventRegistry) ByName(name string) *EventDefinition {
+	for _, def := range r.definitions {
+		if def.Name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// DecodeLogs decodes the logs of registered events, logs of unknown events are skipped
+func (r *EventRegistry) DecodeLogs(logs []types.Log) ([]HubEvent, error) {
+	var events []HubEvent
+	for _, log := range logs {
+		if len(log.Topics) == 0 {
+			continue
+		}
+		def := r.ByTopic(log.Topics[0])
+		if def == nil {
+			continue
+		}
+
+		event, err := def.Decode(log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s event, txhash: %s, error: %v", def.Name, log.TxHash.Hex(), err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// DefaultEventRegistry contains the hub events relayed by default
+var DefaultEventRegistry = NewEventRegistry(
+	StakeBTC2JoinStakePlanEventDefinition,
+)
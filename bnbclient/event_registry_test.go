@@ -0,0 +1,65 @@
+package bnbclient
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestDefaultEventRegistry(t *testing.T) {
+	expectTopic := crypto.Keccak256Hash([]byte("StakeBTC2JoinStakePlan(uint256,uint256,address,address,uint256,uint256)"))
+	if StakeBTC2JoinStakePlanEventTopic != expectTopic {
+		t.Fatalf("unexpected topic: %s, expect: %s", StakeBTC2JoinStakePlanEventTopic.Hex(), expectTopic.Hex())
+	}
+	if def := DefaultEventRegistry.ByTopic(expectTopic); def == nil || def.Name != StakeBTC2JoinStakePlanEventName {
+		t.Fatal("StakeBTC2JoinStakePlan is not registered by topic")
+	}
+	if def := DefaultEventRegistry.ByName(StakeBTC2JoinStakePlanEventName); def == nil || def.Topic != expectTopic {
+		t.Fatal("StakeBTC2JoinStakePlan is not registered by name")
+	}
+
+	user := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	btcContract := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	data := append(common.LeftPadBytes(btcContract.Bytes(), 32), common.LeftPadBytes(big.NewInt(1000).Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(990).Bytes(), 32)...)
+	logs := []types.Log{
+		// unknown events are skipped
+		{Topics: []common.Hash{common.HexToHash("0x01")}},
+		{
+			Topics: []common.Hash{
+				expectTopic,
+				common.BigToHash(big.NewInt(7)),
+				common.BigToHash(big.NewInt(3)),
+				common.BytesToHash(user.Bytes()),
+			},
+			Data:      data,
+			TxHash:    common.HexToHash("0xa1"),
+			BlockHash: common.HexToHash("0xb1"),
+			Index:     5,
+		},
+	}
+
+	events, err := DefaultEventRegistry.DecodeLogs(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expect 1 event, got %d", len(events))
+	}
+	event, ok := events[0].(*StakeBTC2JoinStakePlanEvent)
+	if !ok {
+		t.Fatalf("unexpected event type: %T", events[0])
+	}
+	if event.StakeIndex.Uint64() != 7 || event.PlanId.Uint64() != 3 || event.User != user {
+		t.Errorf("unexpected indexed fields: %d, %d, %s", event.StakeIndex, event.PlanId, event.User.Hex())
+	}
+	if event.BtcContractAddress != btcContract || event.StakeAmount.Uint64() != 1000 || event.StBTCAmount.Uint64() != 990 {
+		t.Errorf("unexpected data fields: %s, %d, %d", event.BtcContractAddress.Hex(), event.StakeAmount, event.StBTCAmount)
+	}
+	if l := event.EventLog(); l.Txhash != logs[1].TxHash || l.BlockHash != logs[1].BlockHash || l.LogIndex != 5 {
+		t.Errorf("unexpected event log: %+v", l)
+	}
+}
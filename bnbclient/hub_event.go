@@ -0,0 +1,137 @@
+package bnbclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	bnblightclienttypes "github.com/Lorenzo-Protocol/lorenzo/v3/x/bnblightclient/types"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type ReceiptWithProof struct {
+	BlockTime uint64
+	Receipt   *types.Receipt
+	Proof     *bnblightclienttypes.Proof
+	// registered hub events emitted in the receipt
+	Events []HubEvent
+}
+
+// EventRegistry returns the registry of the hub events the client decodes
+func (c *Client) EventRegistry() *EventRegistry {
+	return c.events
+}
+
+// GetHubEventsByRangeBlock get the registered events emitted by the hub contract in [start, end]
+func (c *Client) GetHubEventsByRangeBlock(hubAddress common.Address, start, end uint64) ([]HubEvent, error) {
+	logs, err := c.getHubEventLogs(hubAddress, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.events.DecodeLogs(logs)
+}
+
+// GetHubEventReceiptsWithProof get all receipts of the registered hub events in [start, end]
+func (c *Client) GetHubEventReceiptsWithProof(hubAddress common.Address, start, end uint64) ([]*ReceiptWithProof, error) {
+	events, err := c.GetHubEventsByRangeBlock(hubAddress, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.getReceiptsWithProofByEvents(events)
+}
+
+// GetHubEventReceiptsWithProofByLogs get receipts of the given hub event logs,
+// e.g. logs received from a websocket subscription
+func (c *Client) GetHubEventReceiptsWithProofByLogs(logs []types.Log) ([]*ReceiptWithProof, error) {
+	events, err := c.events.DecodeLogs(logs)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.getReceiptsWithProofByEvents(events)
+}
+
+func (c *Client) getReceiptsWithProofByEvents(hubEvents []HubEvent) ([]*ReceiptWithProof, error) {
+	var receiptWithProofList []*ReceiptWithProof
+
+	// group events by tx, keep the order of the logs
+	var txhashList []common.Hash
+	txhashEventsSet := map[common.Hash][]HubEvent{}
+	for _, event := range hubEvents {
+		txhash := event.EventLog().Txhash
+		if _, ok := txhashEventsSet[txhash]; !ok {
+			txhashList = append(txhashList, txhash)
+		}
+		txhashEventsSet[txhash] = append(txhashEventsSet[txhash], event)
+	}
+	for _, txhash := range txhashList {
+		events := txhashEventsSet[txhash]
+		blockHash := events[0].EventLog().BlockHash
+		receipts, err := c.ReceiptsByBlockHash(blockHash)
+		if err != nil {
+			return nil, err
+		}
+
+		var receipt *types.Receipt
+		for _, r := range receipts {
+			if r.TxHash == txhash {
+				receipt = r
+				break
+			}
+		}
+		if receipt == nil {
+			return nil, errors.New("receipt not found in his block, it's impossible, maybe something wrong")
+		}
+		if receipt.BlockHash != blockHash {
+			c.blockReceiptsCache.Remove(blockHash)
+			return nil, fmt.Errorf("%w: receipt block hash mismatch, txhash: %s, expect: %s, got: %s",
+				ErrIntegrity, txhash.Hex(), blockHash.Hex(), receipt.BlockHash.Hex())
+		}
+		blockHeader, err := c.HeaderByHash(blockHash)
+		if err != nil {
+			return nil, err
+		}
+
+		//generate receipt proof
+		proof, err := bnblightclienttypes.GenReceiptProof(uint64(receipt.TransactionIndex), blockHeader.ReceiptHash, receipts)
+		if err != nil {
+			c.blockReceiptsCache.Remove(blockHash)
+			return nil, fmt.Errorf("%w: failed to generate receipt proof, txhash: %s, error: %v", ErrIntegrity, txhash.Hex(), err)
+		}
+		if err := VerifyReceiptProof(blockHeader.ReceiptHash, receipt, proof); err != nil {
+			c.blockReceiptsCache.Remove(blockHash)
+			return nil, err
+		}
+
+		receiptWithProof := &ReceiptWithProof{
+			Receipt:   receipt,
+			Proof:     proof,
+			BlockTime: blockHeader.Time,
+			Events:    events,
+		}
+		receiptWithProofList = append(receiptWithProofList, receiptWithProof)
+	}
+
+	return receiptWithProofList, nil
+}
+
+func (c *Client) getHubEventLogs(hubAddress common.Address, start, end uint64) ([]types.Log, error) {
+	query := ethereum.FilterQuery{
+		BlockHash: nil,
+		FromBlock: big.NewInt(0).SetUint64(start),
+		ToBlock:   big.NewInt(0).SetUint64(end),
+		Addresses: []common.Address{hubAddress},
+		Topics:    [][]common.Hash{c.events.Topics()},
+	}
+	logs, err := c.ethClient.FilterLogs(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
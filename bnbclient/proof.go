@@ -0,0 +1,43 @@
+package bnbclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	bnblightclienttypes "github.com/Lorenzo-Protocol/lorenzo/v3/x/bnblightclient/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// ErrIntegrity is returned when data from the rpc provider is inconsistent with itself,
+// e.g. a header doesn't hash to the requested block hash or a receipt proof doesn't verify.
+var ErrIntegrity = errors.New("integrity error")
+
+// VerifyReceiptProof verifies the receipt proof against the receipts root of the block header,
+// the same way as the Lorenzo bnblightclient module does on submission.
+func VerifyReceiptProof(receiptsRoot common.Hash, receipt *types.Receipt, proof *bnblightclienttypes.Proof) error {
+	if proof == nil {
+		return fmt.Errorf("%w: empty receipt proof, txhash: %s", ErrIntegrity, receipt.TxHash.Hex())
+	}
+
+	expectIndex := rlp.AppendUint64(nil, uint64(receipt.TransactionIndex))
+	if !bytes.Equal(proof.Index, expectIndex) {
+		return fmt.Errorf("%w: receipt proof index mismatch, txhash: %s", ErrIntegrity, receipt.TxHash.Hex())
+	}
+
+	val, err := trie.VerifyProof(receiptsRoot, proof.Index, &proof.Path)
+	if err != nil {
+		return fmt.Errorf("%w: invalid receipt proof, txhash: %s, error: %v", ErrIntegrity, receipt.TxHash.Hex(), err)
+	}
+
+	var receiptBuf bytes.Buffer
+	types.Receipts{receipt}.EncodeIndex(0, &receiptBuf)
+	if !bytes.Equal(val, proof.Value) || !bytes.Equal(val, receiptBuf.Bytes()) {
+		return fmt.Errorf("%w: receipt proof value mismatch, txhash: %s", ErrIntegrity, receipt.TxHash.Hex())
+	}
+
+	return nil
+}
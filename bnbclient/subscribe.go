@@ -0,0 +1,84 @@
+package bnbclient
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrWebSocketNotEnabled is returned by subscriptions when the client has no websocket endpoint
+var ErrWebSocketNotEnabled = errors.New("websocket endpoint is not configured")
+
+// NewWithWebSocket creates a client which also subscribes logs and new heads over the websocket endpoint
+func NewWithWebSocket(rpcUrl string, wsUrl string) (*Client, error) {
+	client, err := New(rpcUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	client.wsUrl = wsUrl
+	return client, nil
+}
+
+// WebSocketEnabled returns true if the client is created with a websocket endpoint
+func (c *Client) WebSocketEnabled() bool {
+	return c.wsUrl != ""
+}
+
+// SubscribeNewHead subscribes notifications about new BNB chain heads
+func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	wsClient, err := c.getWsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return wsClient.SubscribeNewHead(ctx, ch)
+}
+
+// SubscribeHubEventLogs subscribes logs of the registered events of the hub contract
+func (c *Client) SubscribeHubEventLogs(ctx context.Context, hubAddress common.Address, ch chan<- types.Log) (ethereum.Subscription, error) {
+	wsClient, err := c.getWsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{hubAddress},
+		Topics:    [][]common.Hash{c.events.Topics()},
+	}
+	return wsClient.SubscribeFilterLogs(ctx, query, ch)
+}
+
+// CloseWebSocket closes the websocket connection, the next subscription dials a new one
+func (c *Client) CloseWebSocket() {
+	c.wsLock.Lock()
+	defer c.wsLock.Unlock()
+
+	if c.wsClient != nil {
+		c.wsClient.Close()
+		c.wsClient = nil
+	}
+}
+
+func (c *Client) getWsClient() (*ethclient.Client, error) {
+	if !c.WebSocketEnabled() {
+		return nil, ErrWebSocketNotEnabled
+	}
+
+	c.wsLock.Lock()
+	defer c.wsLock.Unlock()
+
+	if c.wsClient == nil {
+		wsClient, err := ethclient.Dial(c.wsUrl)
+		if err != nil {
+			return nil, err
+		}
+		c.wsClient = wsClient
+	}
+
+	return c.wsClient, nil
+}
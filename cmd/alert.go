@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"time"
+
+	lrzclient "github.com/Lorenzo-Protocol/lorenzo-sdk/v3/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/alert"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/bnbclient"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/btc"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+)
+
+// startAlerting starts the alert manager with the rules enabled in the config
+func startAlerting(logger *zap.SugaredLogger, cfg config.Config, repositories *repositories, lorenzoClient *lrzclient.Client, bnbEnabled bool) {
+	rules := newAlertRules(cfg, repositories, lorenzoClient, bnbEnabled)
+	if len(rules) == 0 {
+		logger.Warn("Alerting is enabled without any rule")
+		return
+	}
+
+	var notifiers []alert.Notifier
+	for _, webhook := range cfg.Alerting.Webhooks {
+		notifiers = append(notifiers, alert.NewWebhookNotifier(webhook))
+	}
+
+	manager := alert.NewManager(logger, cfg.Alerting, rules, notifiers)
+	manager.Start()
+	addInterruptHandler(func() {
+		logger.Info("Stopping alert manager...")
+		manager.Stop()
+		manager.WaitForShutdown()
+	})
+}
+
+func newAlertRules(cfg config.Config, repositories *repositories, lorenzoClient *lrzclient.Client, bnbEnabled bool) []alert.Rule {
+	ruleCfg := cfg.Alerting.Rules
+	var rules []alert.Rule
+	if ruleCfg.PendingDepositAfter > 0 {
+		rules = append(rules, alert.NewPendingDepositRule(metrics.ChainBTC, repositories.btc, ruleCfg.PendingDepositAfter))
+		if bnbEnabled {
+			rules = append(rules, alert.NewPendingDepositRule(metrics.ChainBNB, repositories.bnb, ruleCfg.PendingDepositAfter))
+		}
+	}
+	if ruleCfg.SyncStalledAfter > 0 {
+		rules = append(rules, alert.NewSyncStalledRule(metrics.ChainBTC, repositories.btc, ruleCfg.SyncStalledAfter))
+		if bnbEnabled {
+			rules = append(rules, alert.NewSyncStalledRule(metrics.ChainBNB, repositories.bnb, ruleCfg.SyncStalledAfter))
+		}
+	}
+	if ruleCfg.MaxBTCLorenzoTipLag > 0 {
+		btcQuery := btc.NewBTCQuery(cfg.TxRelayer.BtcApiEndpoint)
+		rules = append(rules, alert.NewLorenzoTipLagRule(metrics.ChainBTC, btcQuery.GetBTCCurrentHeight, func() (uint64, error) {
+			tip, err := lorenzoClient.BTCHeaderChainTip()
+			if err != nil {
+				return 0, err
+			}
+			return tip.Header.Height, nil
+		}, ruleCfg.MaxBTCLorenzoTipLag))
+	}
+	if ruleCfg.MaxBNBLorenzoTipLag > 0 && bnbEnabled {
+		bnbClient, err := bnbclient.New(cfg.BNBTxRelayer.RpcUrl)
+		if err != nil {
+			panic(err)
+		}
+		rules = append(rules, alert.NewLorenzoTipLagRule(metrics.ChainBNB, bnbClient.BlockNumber, func() (uint64, error) {
+			header, err := lorenzoClient.BNBLatestHeader()
+			if err != nil {
+				return 0, err
+			}
+			return header.Number, nil
+		}, ruleCfg.MaxBNBLorenzoTipLag))
+	}
+	if ruleCfg.MinSubmitterBalance != "" {
+		// validated in the config
+		minBalance, _ := sdk.ParseCoinNormalized(ruleCfg.MinSubmitterBalance)
+		submitter := lorenzoClient.MustGetAddr()
+		rules = append(rules, alert.NewLowBalanceRule(submitter, func() (sdk.Coin, error) {
+			resp, err := lorenzoClient.Balance(submitter, minBalance.Denom)
+			if err != nil {
+				return sdk.Coin{}, err
+			}
+			return resp.Coin, nil
+		}, minBalance))
+	}
+	if ruleCfg.InvalidDeposit {
+		// the deposits marked invalid before the first start are not notified, later ones resume from the stored cursor
+		now := time.Now()
+		rules = append(rules, alert.NewInvalidDepositRule(metrics.ChainBTC, repositories.btc, now))
+		if bnbEnabled {
+			rules = append(rules, alert.NewInvalidDepositRule(metrics.ChainBNB, repositories.bnb, now))
+		}
+	}
+
+	return rules
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/admin"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+)
+
+// startEvents starts publishing the deposit events of the outbox to the streams of the returned hub,
+// the streams authenticate the clients like the admin API
+func startEvents(logger *zap.SugaredLogger, cfg config.Events, adminCfg config.Admin, repositories *repositories, bnbEnabled bool) *events.Hub {
+	eventRepositories := map[string]db.IDepositEventRepository{metrics.ChainBTC: repositories.btc}
+	if bnbEnabled {
+		eventRepositories[metrics.ChainBNB] = repositories.bnb
+	}
+
+	hub, err := events.NewHub(logger, cfg, eventRepositories, admin.NewAuthenticator(adminCfg.Tokens))
+	if err != nil {
+		panic(err)
+	}
+	for chain, repository := range eventRepositories {
+		chain := chain
+		dispatcher := events.NewDispatcher(logger, cfg, chain, repository, hub)
+		dispatcher.Start()
+		addInterruptHandler(func() {
+			logger.Infof("Stopping %s event dispatcher...", chain)
+			dispatcher.Stop()
+			dispatcher.WaitForShutdown()
+		})
+	}
+	return hub
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+func MigrateCmd() *cobra.Command {
+	var configFile string
+	var targetVersion uint
+
+	openDB := func() (*gorm.DB, config.Database) {
+		cfg, err := config.NewConfig(configFile)
+		if err != nil {
+			panic(err)
+		}
+		handle, err := db.Open(cfg.Database)
+		if err != nil {
+			panic(err)
+		}
+		return handle, cfg.Database
+	}
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply or revert the database schema migrations",
+	}
+	cmd.PersistentFlags().StringVarP(&configFile, "config", "c", "./.testnet/sample-config.yml", "config file")
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Run: func(_ *cobra.Command, _ []string) {
+			handle, dbCfg := openDB()
+			if err := db.Migrate(handle, dbCfg.Driver); err != nil {
+				panic(err)
+			}
+			printSchemaVersion(handle)
+		},
+	}
+
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "Revert migrations down to the target version",
+		Run: func(c *cobra.Command, _ []string) {
+			handle, dbCfg := openDB()
+			target := targetVersion
+			if !c.Flags().Changed("to") {
+				version, err := db.GetSchemaVersion(handle)
+				if err != nil {
+					panic(err)
+				}
+				if version == 0 {
+					fmt.Println("no migration to revert")
+					return
+				}
+				target = version - 1
+			}
+			if err := db.MigrateTo(handle, dbCfg.Driver, target); err != nil {
+				panic(err)
+			}
+			printSchemaVersion(handle)
+		},
+	}
+	downCmd.Flags().UintVar(&targetVersion, "to", 0, "target schema version, reverts the latest migration if not set")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the schema version and the pending migrations",
+		Run: func(_ *cobra.Command, _ []string) {
+			handle, dbCfg := openDB()
+			migrations, err := db.LoadMigrations(dbCfg.Driver)
+			if err != nil {
+				panic(err)
+			}
+			version, err := db.GetSchemaVersion(handle)
+			if err != nil {
+				panic(err)
+			}
+
+			dirty, err := db.GetDirtySchemaVersion(handle)
+			if err != nil {
+				panic(err)
+			}
+
+			fmt.Printf("schema version: %d, latest version: %d\n", version, len(migrations))
+			for _, migration := range migrations {
+				state := "pending"
+				if migration.Version <= version {
+					state = "applied"
+				}
+				if dirty != nil && dirty.Version == migration.Version {
+					state = fmt.Sprintf("failed, %d statements of %s applied, resumed by the next migrate", dirty.Statements, dirty.Dirty)
+				}
+				fmt.Printf("%04d_%s: %s\n", migration.Version, migration.Name, state)
+			}
+		},
+	}
+
+	cmd.AddCommand(upCmd, downCmd, statusCmd)
+	return cmd
+}
+
+func printSchemaVersion(handle *gorm.DB) {
+	version, err := db.GetSchemaVersion(handle)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("schema version: %d\n", version)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/queue"
+)
+
+// startQueue publishes the deposit events of the outbox to the message queue, one dispatcher per chain
+func startQueue(logger *zap.SugaredLogger, cfg config.Config, repositories *repositories, bnbEnabled bool) {
+	publisher, err := queue.NewPublisher(cfg.Queue, cfg.Events.BatchSize)
+	if err != nil {
+		panic(err)
+	}
+
+	eventRepositories := map[string]db.IDepositEventRepository{metrics.ChainBTC: repositories.btc}
+	if bnbEnabled {
+		eventRepositories[metrics.ChainBNB] = repositories.bnb
+	}
+	var dispatchers []*events.Dispatcher
+	for chain, repository := range eventRepositories {
+		dispatcher := events.NewDispatcher(logger, cfg.Events, chain, repository, publisher)
+		dispatcher.Start()
+		dispatchers = append(dispatchers, dispatcher)
+	}
+	addInterruptHandler(func() {
+		logger.Infof("Stopping %s event publisher...", cfg.Queue.Backend)
+		for _, dispatcher := range dispatchers {
+			dispatcher.Stop()
+		}
+		for _, dispatcher := range dispatchers {
+			dispatcher.WaitForShutdown()
+		}
+		if err := publisher.Close(); err != nil {
+			logger.Errorf("Failed to close %s publisher: %v", cfg.Queue.Backend, err)
+		}
+	})
+}
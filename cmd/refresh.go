@@ -2,24 +2,40 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/blockscout"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
 )
 
 func BlockscoutRefreshCmd() *cobra.Command {
 	var blockscoutApiUrl string
 	var lorenzoAppApiUrl string
 	var startHeight uint64
+	var metricsAddr string
+	var configFile string
 
 	cmd := &cobra.Command{
 		Use:   "refresh",
 		Short: "Refresh the blockscout balance when mint/burn or other events happen",
 		Run: func(_ *cobra.Command, _ []string) {
-			refresher, err := blockscout.NewRefresher(startHeight, blockscoutApiUrl, lorenzoAppApiUrl)
+			if metricsAddr != "" {
+				server := metrics.NewServer(metricsAddr)
+				go func() {
+					if err := server.ListenAndServe(); err != nil {
+						panic(err)
+					}
+				}()
+			}
+
+			logger, err := newRefresherLogger(configFile)
 			if err != nil {
 				panic(err)
 			}
 
+			refresher := blockscout.NewRefresher(startHeight, blockscoutApiUrl, lorenzoAppApiUrl, logger)
+
 			if err := refresher.Start(); err != nil {
 				panic(err)
 			}
@@ -31,5 +47,23 @@ func BlockscoutRefreshCmd() *cobra.Command {
 	cmd.Flags().StringVar(&lorenzoAppApiUrl, "lorenzo-app-api",
 		"https://app-testnet.lorenzo-protocol.xyz/api", "Lorenzo App api URL")
 	cmd.Flags().Uint64Var(&startHeight, "start-height", 0, "Start height to refresh")
+	cmd.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Listen address of the prometheus metrics, disabled if empty")
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "config file of the log section, json debug logs to stderr if empty")
 	return cmd
 }
+
+func newRefresherLogger(configFile string) (*zap.Logger, error) {
+	logCfg := config.Log{Format: "json", Level: "debug"}
+	if configFile != "" {
+		var err error
+		if logCfg, err = config.NewLogConfig(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	loggers, err := config.NewLoggers(logCfg, false)
+	if err != nil {
+		return nil, err
+	}
+	return loggers.Component(config.LogComponentRefresher), nil
+}
@@ -1,11 +1,21 @@
 package cmd
 
 import (
+	"context"
+
 	lrzclient "github.com/Lorenzo-Protocol/lorenzo-sdk/v3/client"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/admin"
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/health"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/retention"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/tracing"
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/txrelayer"
 )
 
@@ -19,7 +29,8 @@ func RootAction(c *cobra.Command, _ []string) {
 	if err != nil {
 		panic(err)
 	}
-	if err := db.Init(cfg.Database); err != nil {
+	repositories, err := newRepositories(cfg.Database)
+	if err != nil {
 		panic(err)
 	}
 
@@ -27,32 +38,69 @@ func RootAction(c *cobra.Command, _ []string) {
 	if err != nil {
 		panic(err)
 	}
-	parentLogger, err := cfg.CreateLogger(enableDebug)
+	loggers, err := cfg.CreateLoggers(enableDebug)
 	if err != nil {
 		panic(err)
 	}
-	logger := parentLogger.With().Sugar()
+	parentLogger := loggers.Root()
+	logger := parentLogger.Sugar()
 
-	lorenzoClient, err := lrzclient.New(&cfg.Lorenzo, parentLogger)
+	// the exporter is shut down after the relayers, interrupt handlers run in reverse order
+	shutdownTracing, err := tracing.Init(cfg.Tracing)
+	if err != nil {
+		panic(err)
+	}
+	addInterruptHandler(func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Errorf("Failed to shutdown tracing: %v", err)
+		}
+	})
+
+	lorenzoClient, err := lrzclient.New(&cfg.Lorenzo, loggers.Component(config.LogComponentLorenzoClient).Named(config.LogComponentLorenzoClient))
 	if err != nil {
 		panic(err)
 	}
 	lorenzoClient.SetRetryAttempts(3)
 
 	var txRelayerList []txrelayer.ITxRelayer
-	btcTxRelayer, err := txrelayer.NewTxRelayer(logger, &cfg.TxRelayer, lorenzoClient)
+	btcTxRelayer, err := txrelayer.NewTxRelayer(loggers.Component(config.LogComponentBTC).Sugar(), &cfg.TxRelayer, lorenzoClient, repositories.btc)
 	if err != nil {
 		panic(err)
 	}
 	txRelayerList = append(txRelayerList, btcTxRelayer)
 
-	bnbTxRelayer, err := txrelayer.NewBnbTxRelayer(cfg.BNBTxRelayer, lorenzoClient, logger)
+	bnbTxRelayer, err := txrelayer.NewBnbTxRelayer(cfg.BNBTxRelayer, lorenzoClient, repositories.bnb, loggers.Component(config.LogComponentBNB).Sugar())
 	if err != nil {
 		logger.Errorf("Failed to create BNB Tx-relayer: %s", err)
 	} else {
 		txRelayerList = append(txRelayerList, bnbTxRelayer)
 	}
 
+	if cfg.Health.Enabled {
+		health.Default.Configure(cfg.Health)
+		addHealthChecks(cfg, repositories, lorenzoClient, bnbTxRelayer != nil)
+	}
+	var adminServer *admin.Server
+	if cfg.Admin.Enabled {
+		var bnbRepository db.IBNBRepository
+		if bnbTxRelayer != nil {
+			bnbRepository = repositories.bnb
+		}
+		controls := map[string]admin.RelayerControl{metrics.ChainBTC: btcTxRelayer}
+		if bnbTxRelayer != nil {
+			controls[metrics.ChainBNB] = bnbTxRelayer
+		}
+		adminServer = admin.NewServer(cfg.Admin, repositories.btc, bnbRepository, btcTxRelayer).
+			WithActions(repositories.audit, controls)
+	}
+	var eventHub *events.Hub
+	if cfg.Events.Enabled {
+		eventHub = startEvents(logger, cfg.Events, cfg.Admin, repositories, bnbTxRelayer != nil)
+	}
+	if err := startHTTPServers(logger, cfg, adminServer, eventHub); err != nil {
+		panic(err)
+	}
+
 	for _, txRelayer := range txRelayerList {
 		txRelayer.Start()
 		addInterruptHandler(func() {
@@ -63,6 +111,114 @@ func RootAction(c *cobra.Command, _ []string) {
 		})
 	}
 
+	if cfg.Retention.Enabled {
+		startRetentionJob(logger, cfg.Retention, repositories.retention)
+	}
+
+	if cfg.Webhooks.Enabled {
+		startWebhooks(logger, cfg, repositories)
+	}
+
+	if cfg.Queue.Enabled {
+		startQueue(logger, cfg, repositories, bnbTxRelayer != nil)
+	}
+
+	if cfg.Alerting.Enabled {
+		startAlerting(logger, cfg, repositories, lorenzoClient, bnbTxRelayer != nil)
+	}
+
 	<-interruptHandlersDone
 	parentLogger.Info("Shutdown complete")
 }
+
+func startRetentionJob(logger *zap.SugaredLogger, cfg config.Retention, repository db.IRetentionRepository) {
+	if repository == nil {
+		logger.Warn("Retention is skipped, the database keeps nothing")
+		return
+	}
+
+	job, err := retention.NewJob(logger, cfg, repository)
+	if err != nil {
+		panic(err)
+	}
+	job.Start()
+	addInterruptHandler(func() {
+		logger.Info("Stopping retention job...")
+		job.Stop()
+		job.WaitForShutdown()
+		logger.Info("Retention job shutdown")
+	})
+}
+
+type repositories struct {
+	// handle is nil for the memory driver
+	handle *gorm.DB
+
+	btc db.IBTCRepository
+	bnb db.IBNBRepository
+	// retention is nil for the memory driver
+	retention db.IRetentionRepository
+	audit     db.IAuditRepository
+	webhook   db.IWebhookRepository
+}
+
+// newRepositories opens the database and applies the schema migrations,
+// the memory driver keeps all data in memory for dry runs
+func newRepositories(cfg config.Database) (*repositories, error) {
+	return openRepositories(cfg, !cfg.DisableAutoMigrate)
+}
+
+// openRepositories opens the database, without migrate it fails with db.ErrSchemaOutdated if migrations are pending
+func openRepositories(cfg config.Database, migrate bool) (*repositories, error) {
+	if cfg.Driver == config.DatabaseDriverMemory {
+		return &repositories{
+			btc:     db.NewMemoryBTCRepository(),
+			bnb:     db.NewMemoryBNBRepository(txrelayer.BNBChainName),
+			audit:   db.NewMemoryAuditRepository(),
+			webhook: db.NewMemoryWebhookRepository(),
+		}, nil
+	}
+
+	handle, err := db.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if migrate {
+		err = db.Migrate(handle, cfg.Driver)
+	} else {
+		err = db.CheckSchemaVersion(handle, cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	btcRepository, err := db.NewBTCRepository(handle)
+	if err != nil {
+		return nil, err
+	}
+	bnbRepository, err := db.NewBNBRepository(handle, txrelayer.BNBChainName)
+	if err != nil {
+		return nil, err
+	}
+	retentionRepository, err := db.NewRetentionRepository(handle)
+	if err != nil {
+		return nil, err
+	}
+	auditRepository, err := db.NewAuditRepository(handle)
+	if err != nil {
+		return nil, err
+	}
+	webhookRepository, err := db.NewWebhookRepository(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repositories{
+		handle:    handle,
+		btc:       btcRepository,
+		bnb:       bnbRepository,
+		retention: retentionRepository,
+		audit:     auditRepository,
+		webhook:   webhookRepository,
+	}, nil
+}
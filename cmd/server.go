@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"time"
+
+	lrzclient "github.com/Lorenzo-Protocol/lorenzo-sdk/v3/client"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/admin"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/bnbclient"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/btc"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/health"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+)
+
+// startHTTPServers serves the metrics, the health endpoints, the admin API and the event streams, one server per
+// listen address. The admin API is disabled if adminServer is nil, it is served over HTTPS if its TLS is configured.
+// The event streams are disabled if eventHub is nil.
+func startHTTPServers(logger *zap.SugaredLogger, cfg config.Config, adminServer *admin.Server, eventHub *events.Hub) error {
+	muxes := make(map[string]*http.ServeMux)
+	mux := func(listenAddr string) *http.ServeMux {
+		if _, ok := muxes[listenAddr]; !ok {
+			muxes[listenAddr] = http.NewServeMux()
+		}
+		return muxes[listenAddr]
+	}
+	if cfg.Metrics.Enabled {
+		mux(cfg.Metrics.ListenAddr).Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	}
+	if cfg.Health.Enabled {
+		health.Default.Register(mux(cfg.Health.ListenAddr))
+	}
+	var adminTLSConfig *tls.Config
+	if adminServer != nil {
+		adminServer.Register(mux(cfg.Admin.ListenAddr))
+		if cfg.Admin.TLS.Enabled() {
+			var err error
+			if adminTLSConfig, err = admin.NewTLSConfig(cfg.Admin.TLS); err != nil {
+				return err
+			}
+		}
+	}
+
+	if eventHub != nil {
+		eventHub.Register(mux(cfg.Events.ListenAddr))
+	}
+
+	for listenAddr, handler := range muxes {
+		server := &http.Server{
+			Addr:              listenAddr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		if adminTLSConfig != nil && listenAddr == cfg.Admin.ListenAddr {
+			server.TLSConfig = adminTLSConfig
+		}
+		go func() {
+			var err error
+			if server.TLSConfig != nil {
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				err = server.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorf("HTTP server on %s failed: %v", server.Addr, err)
+			}
+		}()
+		logger.Infof("HTTP server listening on %s", listenAddr)
+		addInterruptHandler(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(ctx)
+		})
+	}
+	return nil
+}
+
+// addHealthChecks adds the dependency checks of /readyz
+func addHealthChecks(cfg config.Config, repositories *repositories, lorenzoClient *lrzclient.Client, bnbEnabled bool) {
+	if repositories.handle != nil {
+		health.Default.AddCheck("database", func(ctx context.Context) error {
+			sqlDB, err := repositories.handle.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		})
+	}
+
+	btcQuery := btc.NewBTCQuery(cfg.TxRelayer.BtcApiEndpoint)
+	health.Default.AddCheck("btc_api", func(_ context.Context) error {
+		_, err := btcQuery.GetBTCCurrentHeight()
+		return err
+	})
+
+	health.Default.AddCheck("lorenzo_rpc", func(_ context.Context) error {
+		_, err := lorenzoClient.BTCHeaderChainTip()
+		return err
+	})
+
+	if bnbEnabled {
+		bnbClient, err := bnbclient.New(cfg.BNBTxRelayer.RpcUrl)
+		if err != nil {
+			panic(err)
+		}
+		health.Default.AddCheck("bnb_rpc", func(_ context.Context) error {
+			_, err := bnbClient.BlockNumber()
+			return err
+		})
+	}
+}
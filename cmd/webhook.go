@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/webhook"
+)
+
+// startWebhooks enqueues the BTC deposit events of the subscribed agents and delivers them in the background
+func startWebhooks(logger *zap.SugaredLogger, cfg config.Config, repositories *repositories) {
+	deliverer, err := webhook.NewDeliverer(logger, cfg.Webhooks, repositories.webhook)
+	if err != nil {
+		panic(err)
+	}
+	deliverer.Start()
+	dispatcher := events.NewDispatcher(logger, cfg.Events, metrics.ChainBTC, repositories.btc, deliverer)
+	dispatcher.Start()
+	addInterruptHandler(func() {
+		logger.Info("Stopping webhook deliverer...")
+		dispatcher.Stop()
+		dispatcher.WaitForShutdown()
+		deliverer.Stop()
+		deliverer.WaitForShutdown()
+	})
+}
+
+func WebhookCmd() *cobra.Command {
+	var configFile string
+	var txid string
+	var subscription string
+
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Manage the webhook deliveries of the deposit events",
+	}
+	cmd.PersistentFlags().StringVarP(&configFile, "config", "c", "./.testnet/sample-config.yml", "config file")
+
+	replayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Deliver the webhooks of a deposit again",
+		Long: "Enqueues the deliveries of the txid again, they are sent and retried by the running submitter.\n" +
+			"The database is not migrated, it fails if the schema is outdated.",
+		Run: func(_ *cobra.Command, _ []string) {
+			cfg, err := config.NewConfig(configFile)
+			if err != nil {
+				panic(err)
+			}
+			if cfg.Database.Driver == config.DatabaseDriverMemory {
+				panic("the memory database keeps no deliveries to replay")
+			}
+			// the schema is migrated by the submitter or the migrate command, never by a maintenance command
+			repositories, err := openRepositories(cfg.Database, false)
+			if err != nil {
+				panic(err)
+			}
+			deliverer, err := webhook.NewDeliverer(zap.NewNop().Sugar(), cfg.Webhooks, repositories.webhook)
+			if err != nil {
+				panic(err)
+			}
+
+			deliveries, err := deliverer.Replay(txid, subscription, time.Now())
+			if err != nil {
+				panic(err)
+			}
+			if len(deliveries) == 0 {
+				fmt.Printf("no webhook delivery of %s to replay\n", txid)
+				return
+			}
+			for _, delivery := range deliveries {
+				fmt.Printf("delivery %d of %s event %d to %s: enqueued\n",
+					delivery.Id, delivery.EventType, delivery.EventId, delivery.Subscription)
+			}
+		},
+	}
+	replayCmd.Flags().StringVar(&txid, "txid", "", "txid of the deposit")
+	replayCmd.Flags().StringVar(&subscription, "subscription", "", "replay the deliveries of this subscription only")
+	_ = replayCmd.MarkFlagRequired("txid")
+
+	cmd.AddCommand(replayCmd)
+	return cmd
+}
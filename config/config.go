@@ -4,15 +4,55 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	lrzcfg "github.com/Lorenzo-Protocol/lorenzo-sdk/v3/config"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/viper"
-	"go.uber.org/zap"
 )
 
 const (
 	MinConfirmationDepth = 1
+
+	DefaultBNBMinBlockRange = uint64(10)
+	DefaultBNBMaxBlockRange = uint64(1000)
+
+	DefaultBNBParamsRefreshInterval = time.Minute
+
+	DefaultRetentionInterval  = time.Hour
+	DefaultRetentionBatchSize = 500
+
+	DefaultMetricsListenAddr = ":2112"
+
+	DefaultHealthLivenessTimeout = 10 * time.Minute
+	DefaultHealthCheckTimeout    = 5 * time.Second
+
+	DefaultTracingServiceName = "lorenzo-btcstaking-submitter"
+	DefaultTracingSampleRatio = 1.0
+)
+
+// tracing exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// database drivers
+const (
+	DatabaseDriverMySQL    = "mysql"
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+	// DatabaseDriverMemory keeps all data in memory, for dry runs
+	DatabaseDriverMemory = "memory"
+)
+
+// archive modes of the retention job
+const (
+	ArchiveModeTable = "table"
+	ArchiveModeJSONL = "jsonl"
 )
 
 type Config struct {
@@ -20,15 +60,417 @@ type Config struct {
 	TxRelayer    TxRelayerConfig      `mapstructure:"tx-relayer"`
 	BNBTxRelayer BNBTxRelayerConfig   `mapstructure:"bnb-tx-relayer"`
 
-	Database Database `mapstructure:"database"`
+	Database  Database  `mapstructure:"database"`
+	Retention Retention `mapstructure:"retention"`
+	Metrics   Metrics   `mapstructure:"metrics"`
+	Health    Health    `mapstructure:"health"`
+	Tracing   Tracing   `mapstructure:"tracing"`
+	Log       Log       `mapstructure:"log"`
+	Alerting  Alerting  `mapstructure:"alerting"`
+	Admin     Admin     `mapstructure:"admin"`
+	Events    Events    `mapstructure:"events"`
+	Webhooks  Webhooks  `mapstructure:"webhooks"`
+	Queue     Queue     `mapstructure:"queue"`
+}
+
+const (
+	DefaultEventsListenAddr        = "127.0.0.1:2115"
+	DefaultEventsPollInterval      = time.Second
+	DefaultEventsBatchSize         = 100
+	DefaultEventsHeartbeatInterval = 15 * time.Second
+	DefaultEventsVisibilityDelay   = time.Minute
+)
+
+// Events publishes the deposit lifecycle events of the outbox table to the SSE and WebSocket endpoints
+type Events struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	ListenAddr string `mapstructure:"listenAddr"`
+	// PollInterval is how often the outbox is polled for new events
+	PollInterval time.Duration `mapstructure:"pollInterval"`
+	// BatchSize is the maximum number of events read from the outbox at once
+	BatchSize int `mapstructure:"batchSize"`
+	// HeartbeatInterval keeps idle streams open through proxies
+	HeartbeatInterval time.Duration `mapstructure:"heartbeatInterval"`
+	// VisibilityDelay is how long a gap in the outbox ids is waited for before it is taken for a rolled back
+	// transaction, it must exceed the longest transaction recording events
+	VisibilityDelay time.Duration `mapstructure:"visibilityDelay"`
+}
+
+func (cfg *Events) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.PollInterval < 0 || cfg.HeartbeatInterval < 0 || cfg.VisibilityDelay < 0 {
+		return fmt.Errorf("events pollInterval, heartbeatInterval and visibilityDelay cannot be negative")
+	}
+	if cfg.BatchSize < 0 {
+		return fmt.Errorf("events batchSize cannot be negative")
+	}
+
+	return nil
+}
+
+// message queue backends of the deposit events
+const (
+	QueueBackendNATS  = "nats"
+	QueueBackendKafka = "kafka"
+)
+
+const (
+	DefaultQueueSubjectPrefix = "lorenzo.deposits"
+	DefaultQueueNATSStream    = "LORENZO_DEPOSITS"
+	DefaultQueueTimeout       = 10 * time.Second
+)
+
+// Queue publishes the deposit events of the outbox to a message queue, one subject or topic per chain
+type Queue struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Backend is nats (JetStream) or kafka
+	Backend string `mapstructure:"backend"`
+	// URLs are the NATS server urls or the Kafka broker addresses
+	URLs []string `mapstructure:"urls"`
+	// SubjectPrefix is followed by the chain in the NATS subjects and the Kafka topics, e.g. lorenzo.deposits.btc
+	SubjectPrefix string `mapstructure:"subjectPrefix"`
+	// NATSStream is the JetStream stream of the subjects, it is created if missing
+	NATSStream string `mapstructure:"natsStream"`
+	// Timeout is the timeout of publishing a batch of events until the broker acknowledges them
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func (cfg *Queue) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	switch cfg.Backend {
+	case QueueBackendNATS, QueueBackendKafka:
+	default:
+		return fmt.Errorf("unsupported queue backend: %s", cfg.Backend)
+	}
+	if len(cfg.URLs) == 0 {
+		return fmt.Errorf("queue urls cannot be empty")
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("queue timeout cannot be negative")
+	}
+
+	return nil
+}
+
+const (
+	DefaultWebhooksTimeout        = 10 * time.Second
+	DefaultWebhooksMaxAttempts    = 10
+	DefaultWebhooksInitialBackoff = 10 * time.Second
+	DefaultWebhooksMaxBackoff     = time.Hour
+	DefaultWebhooksWorkers        = 4
+)
+
+// minWebhookSecretLength rejects guessable webhook signing secrets
+const minWebhookSecretLength = 16
+
+// Webhooks pushes the deposit events of the agents to their subscribers, signed with the subscription secrets.
+// Failed deliveries are retried with exponential backoff until MaxAttempts.
+type Webhooks struct {
+	Enabled       bool                  `mapstructure:"enabled"`
+	Subscriptions []WebhookSubscription `mapstructure:"subscriptions"`
+	// Timeout is the timeout of a delivery attempt
+	Timeout        time.Duration `mapstructure:"timeout"`
+	MaxAttempts    int           `mapstructure:"maxAttempts"`
+	InitialBackoff time.Duration `mapstructure:"initialBackoff"`
+	MaxBackoff     time.Duration `mapstructure:"maxBackoff"`
+	// Workers is the number of subscriptions delivered to in parallel, the deliveries of a subscription are sequential
+	Workers int `mapstructure:"workers"`
+}
+
+// WebhookSubscription delivers the events of the BTC deposits to the receiving address of an agent
+type WebhookSubscription struct {
+	// Name identifies the subscription in the delivery log, deliveries of a renamed subscription are not retried
+	Name    string `mapstructure:"name"`
+	AgentId uint64 `mapstructure:"agentId"`
+	URL     string `mapstructure:"url"`
+	// Secret signs the payloads with HMAC-SHA256
+	Secret string `mapstructure:"secret"`
+	// Events are the delivered event types, only minted if empty
+	Events []string `mapstructure:"events"`
+}
+
+func (cfg *Webhooks) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(cfg.Subscriptions) == 0 {
+		return fmt.Errorf("webhooks subscriptions cannot be empty")
+	}
+	names := make(map[string]bool)
+	for _, subscription := range cfg.Subscriptions {
+		if subscription.Name == "" || subscription.URL == "" {
+			return fmt.Errorf("webhook subscription name and url cannot be empty")
+		}
+		if names[subscription.Name] {
+			return fmt.Errorf("duplicated webhook subscription name: %s", subscription.Name)
+		}
+		names[subscription.Name] = true
+		if subscription.AgentId == 0 {
+			return fmt.Errorf("webhook subscription %s agentId cannot be empty", subscription.Name)
+		}
+		if !strings.HasPrefix(subscription.URL, "https://") && !strings.HasPrefix(subscription.URL, "http://") {
+			return fmt.Errorf("webhook subscription %s url must be http or https", subscription.Name)
+		}
+		if len(subscription.Secret) < minWebhookSecretLength {
+			return fmt.Errorf("webhook subscription %s secret is shorter than %d characters", subscription.Name, minWebhookSecretLength)
+		}
+	}
+	if cfg.MaxAttempts < 0 || cfg.Timeout < 0 || cfg.InitialBackoff < 0 || cfg.MaxBackoff < 0 {
+		return fmt.Errorf("webhooks timeout, maxAttempts and backoffs cannot be negative")
+	}
+
+	return nil
+}
+
+const (
+	DefaultAdminListenAddr  = "127.0.0.1:2114"
+	DefaultAdminMaxPageSize = 100
+)
+
+// minAdminTokenLength rejects guessable admin API tokens
+const minAdminTokenLength = 16
+
+// Admin is the admin HTTP JSON API of the deposits, sync points and agents.
+// Its actions, e.g. retrying a deposit or pausing a relayer loop, require an API token or a client certificate.
+type Admin struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	ListenAddr string `mapstructure:"listenAddr"`
+	// MaxPageSize caps the limit of the deposit queries, it is also the default limit
+	MaxPageSize int `mapstructure:"maxPageSize"`
+	// Tokens authenticate the actions with an "Authorization: Bearer <token>" header
+	Tokens []AdminToken `mapstructure:"tokens"`
+	TLS    AdminTLS     `mapstructure:"tls"`
+}
+
+// AdminToken is an API token of the admin actions, its name is recorded in the audit log
+type AdminToken struct {
+	Name  string `mapstructure:"name"`
+	Token string `mapstructure:"token"`
+}
+
+// AdminTLS serves the admin API over HTTPS, clients presenting a certificate signed by the client CA
+// are authenticated by the common name of the certificate
+type AdminTLS struct {
+	CertFile     string `mapstructure:"certFile"`
+	KeyFile      string `mapstructure:"keyFile"`
+	ClientCAFile string `mapstructure:"clientCAFile"`
+}
+
+func (cfg AdminTLS) Enabled() bool {
+	return cfg.CertFile != ""
+}
+
+func (cfg *Admin) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, token := range cfg.Tokens {
+		if token.Name == "" || token.Token == "" {
+			return fmt.Errorf("admin token name and token cannot be empty")
+		}
+		if names[token.Name] {
+			return fmt.Errorf("duplicated admin token name: %s", token.Name)
+		}
+		names[token.Name] = true
+		if len(token.Token) < minAdminTokenLength {
+			return fmt.Errorf("admin token %s is shorter than %d characters", token.Name, minAdminTokenLength)
+		}
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		return fmt.Errorf("admin tls certFile and keyFile must be set together")
+	}
+	if cfg.TLS.ClientCAFile != "" && !cfg.TLS.Enabled() {
+		return fmt.Errorf("admin tls clientCAFile requires certFile and keyFile")
+	}
+
+	return nil
+}
+
+// webhook payload formats of the alerts
+const (
+	AlertWebhookFormatGeneric = "generic"
+	AlertWebhookFormatSlack   = "slack"
+)
+
+const DefaultAlertingInterval = time.Minute
+
+// Alerting evaluates the alert rules periodically and notifies the webhooks of firing and resolved alerts
+type Alerting struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// RepeatInterval notifies a firing alert again, it is notified only once until resolved if 0
+	RepeatInterval time.Duration  `mapstructure:"repeatInterval"`
+	Webhooks       []AlertWebhook `mapstructure:"webhooks"`
+	Rules          AlertRules     `mapstructure:"rules"`
+}
+
+type AlertWebhook struct {
+	URL string `mapstructure:"url"`
+	// Format is generic (default) or slack
+	Format string `mapstructure:"format"`
+}
+
+// AlertRules are the thresholds of the alert rules, a rule is disabled if its threshold is not set
+type AlertRules struct {
+	// PendingDepositAfter fires when a deposit is still pending this long after it is scanned
+	PendingDepositAfter time.Duration `mapstructure:"pendingDepositAfter"`
+	// SyncStalledAfter fires when the sync point of a chain has not advanced for this long
+	SyncStalledAfter time.Duration `mapstructure:"syncStalledAfter"`
+	// MaxBTCLorenzoTipLag fires when the Lorenzo BTC light client lags behind the BTC tip by more blocks
+	MaxBTCLorenzoTipLag uint64 `mapstructure:"maxBtcLorenzoTipLag"`
+	// MaxBNBLorenzoTipLag fires when the Lorenzo BNB light client lags behind the BNB tip by more blocks
+	MaxBNBLorenzoTipLag uint64 `mapstructure:"maxBnbLorenzoTipLag"`
+	// MinSubmitterBalance fires when the submitter balance is lower, e.g. 1000000000000000000alrz
+	MinSubmitterBalance string `mapstructure:"minSubmitterBalance"`
+	// InvalidDeposit notifies every deposit marked invalid
+	InvalidDeposit bool `mapstructure:"invalidDeposit"`
+}
+
+func (cfg *Alerting) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if len(cfg.Webhooks) == 0 {
+		return fmt.Errorf("alerting webhooks cannot be empty")
+	}
+	for _, webhook := range cfg.Webhooks {
+		if webhook.URL == "" {
+			return fmt.Errorf("alerting webhook url cannot be empty")
+		}
+		switch webhook.Format {
+		case AlertWebhookFormatGeneric, AlertWebhookFormatSlack:
+		default:
+			return fmt.Errorf("unsupported alerting webhook format: %s", webhook.Format)
+		}
+	}
+	if cfg.Rules.MinSubmitterBalance != "" {
+		if _, err := sdk.ParseCoinNormalized(cfg.Rules.MinSubmitterBalance); err != nil {
+			return fmt.Errorf("invalid alerting minSubmitterBalance: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Tracing is the OpenTelemetry tracing of the deposits
+type Tracing struct {
+	// Exporter is none (default), stdout or otlp
+	Exporter string `mapstructure:"exporter"`
+	// Endpoint is the host:port of the OTLP gRPC collector
+	Endpoint string `mapstructure:"endpoint"`
+	// Insecure disables TLS to the OTLP collector
+	Insecure    bool    `mapstructure:"insecure"`
+	SampleRatio float64 `mapstructure:"sampleRatio"`
+	ServiceName string  `mapstructure:"serviceName"`
+}
+
+func (cfg *Tracing) Validate() error {
+	switch cfg.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if cfg.Endpoint == "" {
+			return fmt.Errorf("tracing endpoint cannot be empty for the otlp exporter")
+		}
+	default:
+		return fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return fmt.Errorf("tracing sampleRatio must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// Metrics is the prometheus endpoint at /metrics
+type Metrics struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	ListenAddr string `mapstructure:"listenAddr"`
+}
+
+// Health is the /healthz and /readyz endpoints, served with the metrics if they share the listen address
+type Health struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	ListenAddr string `mapstructure:"listenAddr"`
+	// LivenessTimeout is how long a relayer loop may go without a completed iteration before /healthz fails
+	LivenessTimeout time.Duration `mapstructure:"livenessTimeout"`
+	// CheckTimeout bounds each dependency check of /readyz
+	CheckTimeout time.Duration `mapstructure:"checkTimeout"`
+	// MaxBTCLag and MaxBNBLag are the blocks the sync point may lag behind the chain tip before /readyz fails,
+	// 0 disables the threshold
+	MaxBTCLag uint64 `mapstructure:"maxBtcLag"`
+	MaxBNBLag uint64 `mapstructure:"maxBnbLag"`
 }
 
 type Database struct {
+	// Driver is one of mysql (default), postgres, sqlite and memory
+	Driver   string `mapstructure:"driver"`
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
-	DBName   string `mapstructure:"dbname"`
+	// DBName is the database file path for sqlite, e.g. ./submitter.db or :memory:
+	DBName string `mapstructure:"dbname"`
+	// SSLMode is the postgres sslmode, disable by default
+	SSLMode string `mapstructure:"sslmode"`
+	// DisableAutoMigrate skips applying migrations on startup, run the migrate command instead
+	DisableAutoMigrate bool `mapstructure:"disableAutoMigrate"`
+}
+
+func (cfg *Database) Validate() error {
+	switch cfg.Driver {
+	case DatabaseDriverMySQL, DatabaseDriverPostgres, DatabaseDriverSQLite:
+	case DatabaseDriverMemory:
+		return nil
+	default:
+		return fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
+	if cfg.DBName == "" {
+		return fmt.Errorf("dbname cannot be empty")
+	}
+
+	return nil
+}
+
+// Retention is the data retention job of the deposit tables, only handled deposits are affected
+type Retention struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is how often the job runs
+	Interval time.Duration `mapstructure:"interval"`
+	// StripProofsAfter is the age after which receipts and proofs of successful BNB deposits are emptied, 0 disables it
+	StripProofsAfter time.Duration `mapstructure:"stripProofsAfter"`
+	// ArchiveAfter is the age after which handled deposits are moved out of the deposit tables, 0 disables it
+	ArchiveAfter time.Duration `mapstructure:"archiveAfter"`
+	// ArchiveMode is table (default), moving rows into the archive tables,
+	// or jsonl, also exporting the archived rows into gzip compressed JSONL files in ArchiveDir
+	ArchiveMode string `mapstructure:"archiveMode"`
+	ArchiveDir  string `mapstructure:"archiveDir"`
+	// BatchSize is the number of rows handled in one transaction
+	BatchSize int `mapstructure:"batchSize"`
+}
+
+func (cfg *Retention) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.BatchSize <= 0 {
+		return fmt.Errorf("retention batchSize must be positive")
+	}
+	switch cfg.ArchiveMode {
+	case ArchiveModeTable:
+	case ArchiveModeJSONL:
+		if cfg.ArchiveDir == "" {
+			return fmt.Errorf("retention archiveDir cannot be empty in jsonl archive mode")
+		}
+	default:
+		return fmt.Errorf("unsupported retention archive mode: %s", cfg.ArchiveMode)
+	}
+
+	return nil
 }
 
 type TxRelayerConfig struct {
@@ -42,18 +484,40 @@ type BNBTxRelayerConfig struct {
 	RpcUrl            string `mapstructure:"rpcUrl"`
 	ConfirmationDepth uint64 `mapstructure:"confirmationDepth"`
 	StartBlockHeight  uint64 `mapstructure:"startBlockHeight"`
+	// WsUrl is the optional websocket endpoint, logs and new heads are subscribed in real time if set
+	WsUrl string `mapstructure:"wsUrl"`
+	// MinBlockRange and MaxBlockRange bound the block range of a single eth_getLogs request.
+	// The range shrinks towards MinBlockRange when the provider rejects it and grows back on success.
+	MinBlockRange uint64 `mapstructure:"minBlockRange"`
+	MaxBlockRange uint64 `mapstructure:"maxBlockRange"`
+	// BtcContractAddress is the expected wrapped BTC token of stake plan events, the check is skipped if empty
+	BtcContractAddress string `mapstructure:"btcContractAddress"`
+	// ParamsRefreshInterval is how often the bnblightclient params (StakePlanHubAddress) are reloaded from Lorenzo
+	ParamsRefreshInterval time.Duration `mapstructure:"paramsRefreshInterval"`
 }
 
 func (cfg *BNBTxRelayerConfig) Validate() error {
 	if cfg.RpcUrl == "" {
 		return fmt.Errorf("rpcUrl cannot be empty")
 	}
+	if cfg.WsUrl != "" && !strings.HasPrefix(cfg.WsUrl, "ws://") && !strings.HasPrefix(cfg.WsUrl, "wss://") {
+		return fmt.Errorf("wsUrl must be a ws:// or wss:// url")
+	}
 	if cfg.ConfirmationDepth == 0 {
 		return fmt.Errorf("confirmationDepth cannot be 0")
 	}
 	if cfg.StartBlockHeight == 0 {
 		return fmt.Errorf("startBlockHeight cannot be 0")
 	}
+	if cfg.BtcContractAddress != "" && !common.IsHexAddress(cfg.BtcContractAddress) {
+		return fmt.Errorf("btcContractAddress is not a valid address")
+	}
+	if cfg.MinBlockRange == 0 {
+		return fmt.Errorf("minBlockRange cannot be 0")
+	}
+	if cfg.MinBlockRange > cfg.MaxBlockRange {
+		return fmt.Errorf("minBlockRange must not be larger than maxBlockRange")
+	}
 
 	return nil
 }
@@ -78,10 +542,56 @@ func (cfg *Config) Validate() error {
 		return err
 	}
 
+	if err := cfg.Database.Validate(); err != nil {
+		return err
+	}
+
 	if err := cfg.TxRelayer.Validate(); err != nil {
 		return err
 	}
 
+	if err := cfg.Retention.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Log.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Alerting.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Admin.Validate(); err != nil {
+		return err
+	}
+	if err := cfg.Events.Validate(); err != nil {
+		return err
+	}
+	// the streams are authenticated by the admin tokens, or the client certificates on the admin listenAddr
+	if cfg.Events.Enabled && len(cfg.Admin.Tokens) == 0 &&
+		!(cfg.Admin.Enabled && cfg.Admin.TLS.ClientCAFile != "" && cfg.Events.ListenAddr == cfg.Admin.ListenAddr) {
+		return fmt.Errorf("events require admin tokens to authenticate the streams")
+	}
+
+	if err := cfg.Webhooks.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Queue.Validate(); err != nil {
+		return err
+	}
+	// the metrics and health endpoints are not served over HTTPS
+	if cfg.Admin.Enabled && cfg.Admin.TLS.Enabled() &&
+		((cfg.Metrics.Enabled && cfg.Metrics.ListenAddr == cfg.Admin.ListenAddr) ||
+			(cfg.Health.Enabled && cfg.Health.ListenAddr == cfg.Admin.ListenAddr)) {
+		return fmt.Errorf("admin listenAddr must differ from the metrics and health listenAddr with tls")
+	}
+
 	return nil
 }
 
@@ -104,10 +614,109 @@ func (cfg *Config) fillDefaultValueIfNotSet() {
 	if cfg.Lorenzo.SignModeStr == "" {
 		cfg.Lorenzo.SignModeStr = "direct"
 	}
+	if cfg.Database.Driver == "" {
+		cfg.Database.Driver = DatabaseDriverMySQL
+	}
+	if cfg.Database.Driver == DatabaseDriverPostgres && cfg.Database.SSLMode == "" {
+		cfg.Database.SSLMode = "disable"
+	}
+	if cfg.BNBTxRelayer.MinBlockRange == 0 {
+		cfg.BNBTxRelayer.MinBlockRange = DefaultBNBMinBlockRange
+	}
+	if cfg.BNBTxRelayer.MaxBlockRange == 0 {
+		cfg.BNBTxRelayer.MaxBlockRange = DefaultBNBMaxBlockRange
+	}
+	if cfg.BNBTxRelayer.ParamsRefreshInterval == 0 {
+		cfg.BNBTxRelayer.ParamsRefreshInterval = DefaultBNBParamsRefreshInterval
+	}
+	if cfg.Retention.Interval == 0 {
+		cfg.Retention.Interval = DefaultRetentionInterval
+	}
+	if cfg.Retention.BatchSize == 0 {
+		cfg.Retention.BatchSize = DefaultRetentionBatchSize
+	}
+	if cfg.Retention.ArchiveMode == "" {
+		cfg.Retention.ArchiveMode = ArchiveModeTable
+	}
+	if cfg.Metrics.ListenAddr == "" {
+		cfg.Metrics.ListenAddr = DefaultMetricsListenAddr
+	}
+	if cfg.Health.ListenAddr == "" {
+		cfg.Health.ListenAddr = cfg.Metrics.ListenAddr
+	}
+	if cfg.Health.LivenessTimeout == 0 {
+		cfg.Health.LivenessTimeout = DefaultHealthLivenessTimeout
+	}
+	if cfg.Health.CheckTimeout == 0 {
+		cfg.Health.CheckTimeout = DefaultHealthCheckTimeout
+	}
+	if cfg.Tracing.Exporter == "" {
+		cfg.Tracing.Exporter = TracingExporterNone
+	}
+	if cfg.Tracing.SampleRatio == 0 {
+		cfg.Tracing.SampleRatio = DefaultTracingSampleRatio
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = DefaultTracingServiceName
+	}
+	cfg.Log.fillDefaultValueIfNotSet()
+	if cfg.Admin.ListenAddr == "" {
+		cfg.Admin.ListenAddr = DefaultAdminListenAddr
+	}
+	if cfg.Admin.MaxPageSize == 0 {
+		cfg.Admin.MaxPageSize = DefaultAdminMaxPageSize
+	}
+	if cfg.Alerting.Interval == 0 {
+		cfg.Alerting.Interval = DefaultAlertingInterval
+	}
+	if cfg.Events.ListenAddr == "" {
+		cfg.Events.ListenAddr = DefaultEventsListenAddr
+	}
+	if cfg.Events.PollInterval == 0 {
+		cfg.Events.PollInterval = DefaultEventsPollInterval
+	}
+	if cfg.Events.BatchSize == 0 {
+		cfg.Events.BatchSize = DefaultEventsBatchSize
+	}
+	if cfg.Events.HeartbeatInterval == 0 {
+		cfg.Events.HeartbeatInterval = DefaultEventsHeartbeatInterval
+	}
+	if cfg.Events.VisibilityDelay == 0 {
+		cfg.Events.VisibilityDelay = DefaultEventsVisibilityDelay
+	}
+	if cfg.Webhooks.Timeout == 0 {
+		cfg.Webhooks.Timeout = DefaultWebhooksTimeout
+	}
+	if cfg.Webhooks.MaxAttempts == 0 {
+		cfg.Webhooks.MaxAttempts = DefaultWebhooksMaxAttempts
+	}
+	if cfg.Webhooks.InitialBackoff == 0 {
+		cfg.Webhooks.InitialBackoff = DefaultWebhooksInitialBackoff
+	}
+	if cfg.Webhooks.MaxBackoff == 0 {
+		cfg.Webhooks.MaxBackoff = DefaultWebhooksMaxBackoff
+	}
+	if cfg.Webhooks.Workers == 0 {
+		cfg.Webhooks.Workers = DefaultWebhooksWorkers
+	}
+	if cfg.Queue.SubjectPrefix == "" {
+		cfg.Queue.SubjectPrefix = DefaultQueueSubjectPrefix
+	}
+	if cfg.Queue.NATSStream == "" {
+		cfg.Queue.NATSStream = DefaultQueueNATSStream
+	}
+	if cfg.Queue.Timeout == 0 {
+		cfg.Queue.Timeout = DefaultQueueTimeout
+	}
+	for i := range cfg.Alerting.Webhooks {
+		if cfg.Alerting.Webhooks[i].Format == "" {
+			cfg.Alerting.Webhooks[i].Format = AlertWebhookFormatGeneric
+		}
+	}
 }
 
-func (cfg *Config) CreateLogger(debug bool) (*zap.Logger, error) {
-	return NewRootLogger("auto", debug)
+func (cfg *Config) CreateLoggers(debug bool) (*Loggers, error) {
+	return NewLoggers(cfg.Log, debug)
 }
 
 // NewConfig returns a fully parsed Config object from a given file directory
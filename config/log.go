@@ -2,43 +2,305 @@ package config
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	zaplogfmt "github.com/jsternberg/zap-logfmt"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// NewRootLogger creates a new logger object with the given format and log level
+// components with their own log level
+const (
+	LogComponentBTC           = "btc"
+	LogComponentBNB           = "bnb"
+	LogComponentRefresher     = "refresher"
+	LogComponentLorenzoClient = "lorenzo"
+)
+
+const (
+	DefaultLogFormat         = "auto"
+	DefaultLogLevel          = "info"
+	DefaultLogFileMaxSizeMB  = 100
+	DefaultLogFileMaxBackups = 10
+)
+
+// DefaultLogSampledMessages are the messages logged by the idle relayer loops
+var DefaultLogSampledMessages = []string{
+	"No new block",
+	"No unhandled btc deposit txs",
+	"Sync point is",
+	"no unhandled wrapped btc deposit txs",
+}
+
+type Log struct {
+	// Format is json, console, logfmt or auto (console)
+	Format string `mapstructure:"format"`
+	Level  string `mapstructure:"level"`
+	// Levels overrides the level of the btc, bnb, refresher and lorenzo components
+	Levels   map[string]string `mapstructure:"levels"`
+	File     LogFile           `mapstructure:"file"`
+	Sampling LogSampling       `mapstructure:"sampling"`
+}
+
+// LogFile writes the logs to a file besides stderr, rotated by size
+type LogFile struct {
+	Path       string `mapstructure:"path"`
+	MaxSizeMB  int    `mapstructure:"maxSizeMB"`
+	MaxBackups int    `mapstructure:"maxBackups"`
+	MaxAgeDays int    `mapstructure:"maxAgeDays"`
+	Compress   bool   `mapstructure:"compress"`
+}
+
+// LogSampling logs the messages starting with one of Messages at most once per Interval,
+// the number of dropped messages is logged with the next one. It is disabled if Interval is 0.
+type LogSampling struct {
+	Interval time.Duration `mapstructure:"interval"`
+	Messages []string      `mapstructure:"messages"`
+}
+
+func (cfg *Log) Validate() error {
+	switch cfg.Format {
+	case "json", "auto", "console", "logfmt":
+	default:
+		return fmt.Errorf("unrecognized log format %q", cfg.Format)
+	}
+	if _, err := zapcore.ParseLevel(cfg.Level); err != nil {
+		return err
+	}
+	for component, level := range cfg.Levels {
+		switch component {
+		case LogComponentBTC, LogComponentBNB, LogComponentRefresher, LogComponentLorenzoClient:
+		default:
+			return fmt.Errorf("unknown log component %q", component)
+		}
+		if _, err := zapcore.ParseLevel(level); err != nil {
+			return fmt.Errorf("invalid log level of %s: %w", component, err)
+		}
+	}
+	if cfg.Sampling.Interval < 0 {
+		return fmt.Errorf("log sampling interval cannot be negative")
+	}
+
+	return nil
+}
+
+func (cfg *Log) fillDefaultValueIfNotSet() {
+	if cfg.Format == "" {
+		cfg.Format = DefaultLogFormat
+	}
+	if cfg.Level == "" {
+		cfg.Level = DefaultLogLevel
+	}
+	if cfg.File.MaxSizeMB == 0 {
+		cfg.File.MaxSizeMB = DefaultLogFileMaxSizeMB
+	}
+	if cfg.File.MaxBackups == 0 {
+		cfg.File.MaxBackups = DefaultLogFileMaxBackups
+	}
+	if len(cfg.Sampling.Messages) == 0 {
+		cfg.Sampling.Messages = DefaultLogSampledMessages
+	}
+}
+
+// NewLogConfig parses only the log section of the config file, for commands without the relayer config
+func NewLogConfig(configFile string) (Log, error) {
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return Log{}, err
+	}
+	var cfg Log
+	if err := v.UnmarshalKey("log", &cfg); err != nil {
+		return Log{}, err
+	}
+	cfg.fillDefaultValueIfNotSet()
+	if err := cfg.Validate(); err != nil {
+		return Log{}, err
+	}
+	return cfg, nil
+}
+
+// Loggers creates the root logger and the component loggers, they share the encoder and the outputs
+type Loggers struct {
+	cfg     Log
+	debug   bool
+	encoder zapcore.Encoder
+	output  zapcore.WriteSyncer
+	sampler *logSampler
+}
+
+// NewLoggers creates the loggers of the log config, debug lowers the levels without override to debug
+func NewLoggers(cfg Log, debug bool) (*Loggers, error) {
+	cfg.fillDefaultValueIfNotSet()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	encoder, err := newLogEncoder(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	output := zapcore.Lock(os.Stderr)
+	if cfg.File.Path != "" {
+		output = zapcore.NewMultiWriteSyncer(output, zapcore.AddSync(newRotatingFile(cfg.File)))
+	}
+
+	return &Loggers{
+		cfg:     cfg,
+		debug:   debug,
+		encoder: encoder,
+		output:  output,
+		sampler: newLogSampler(cfg.Sampling),
+	}, nil
+}
+
+// Root is the logger of the components without their own level
+func (l *Loggers) Root() *zap.Logger {
+	return zap.New(l.core(l.level("")))
+}
+
+// Component is the logger with the level override of the component, the component names it
+func (l *Loggers) Component(component string) *zap.Logger {
+	return zap.New(l.core(l.level(component)))
+}
+
+func (l *Loggers) level(component string) zapcore.Level {
+	if level, ok := l.cfg.Levels[component]; ok {
+		// validated in NewLoggers
+		parsed, _ := zapcore.ParseLevel(level)
+		return parsed
+	}
+	if l.debug {
+		return zap.DebugLevel
+	}
+	parsed, _ := zapcore.ParseLevel(l.cfg.Level)
+	return parsed
+}
+
+func (l *Loggers) core(level zapcore.Level) zapcore.Core {
+	core := zapcore.NewCore(l.encoder.Clone(), l.output, level)
+	if l.sampler == nil {
+		return core
+	}
+	return &samplingCore{Core: core, sampler: l.sampler}
+}
+
+func newRotatingFile(cfg LogFile) io.Writer {
+	return &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeDays,
+		Compress:   cfg.Compress,
+	}
+}
+
+// newLogEncoder creates the encoder of the log format
 // (copied from https://github.com/cosmos/relayer/blob/v2.4.2/cmd/root.go#L174-L202)
-func NewRootLogger(format string, debug bool) (*zap.Logger, error) {
+func newLogEncoder(format string) (zapcore.Encoder, error) {
 	config := zap.NewProductionEncoderConfig()
 	config.EncodeTime = func(ts time.Time, encoder zapcore.PrimitiveArrayEncoder) {
 		encoder.AppendString(ts.UTC().Format("2006-01-02T15:04:05.000000Z07:00"))
 	}
 	config.LevelKey = "lvl"
 
-	var enc zapcore.Encoder
 	switch format {
 	case "json":
-		enc = zapcore.NewJSONEncoder(config)
+		return zapcore.NewJSONEncoder(config), nil
 	case "auto", "console":
-		enc = zapcore.NewConsoleEncoder(config)
+		return zapcore.NewConsoleEncoder(config), nil
 	case "logfmt":
-		enc = zaplogfmt.NewEncoder(config)
+		return zaplogfmt.NewEncoder(config), nil
 	default:
 		return nil, fmt.Errorf("unrecognized log format %q", format)
 	}
+}
+
+// logSampler keeps the last time each sampled message was logged, it is shared by all loggers
+type logSampler struct {
+	interval time.Duration
+	messages []string
+
+	mu      sync.Mutex
+	last    map[string]time.Time
+	dropped map[string]int
+	now     func() time.Time
+}
 
-	level := zap.InfoLevel
-	if debug {
-		level = zap.DebugLevel
+func newLogSampler(cfg LogSampling) *logSampler {
+	if cfg.Interval == 0 {
+		return nil
 	}
+	return &logSampler{
+		interval: cfg.Interval,
+		messages: cfg.Messages,
+		last:     make(map[string]time.Time),
+		dropped:  make(map[string]int),
+		now:      time.Now,
+	}
+}
+
+// sample returns whether the message is logged and the number of messages dropped since the last one
+func (s *logSampler) sample(message string) (bool, int) {
+	var key string
+	for _, prefix := range s.messages {
+		if strings.HasPrefix(message, prefix) {
+			key = prefix
+			break
+		}
+	}
+	if key == "" {
+		return true, 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if last, ok := s.last[key]; ok && now.Sub(last) < s.interval {
+		s.dropped[key]++
+		return false, 0
+	}
+	s.last[key] = now
+	dropped := s.dropped[key]
+	delete(s.dropped, key)
+	return true, dropped
+}
+
+type samplingCore struct {
+	zapcore.Core
+	sampler *logSampler
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{Core: c.Core.With(fields), sampler: c.sampler}
+}
+
+func (c *samplingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	ok, dropped := c.sampler.sample(entry.Message)
+	if !ok {
+		return checked
+	}
+	if dropped == 0 {
+		return c.Core.Check(entry, checked)
+	}
+	return checked.AddCore(entry, &droppedCountCore{Core: c.Core, dropped: dropped})
+}
+
+// droppedCountCore adds the number of dropped messages to the logged one
+type droppedCountCore struct {
+	zapcore.Core
+	dropped int
+}
 
-	return zap.New(zapcore.NewCore(
-		enc,
-		os.Stderr,
-		level,
-	)), nil
+func (c *droppedCountCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, append(fields, zap.Int("sampledOut", c.dropped)))
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggersComponentLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "submitter.log")
+	loggers, err := NewLoggers(Log{
+		Format: "json",
+		Level:  "warn",
+		Levels: map[string]string{LogComponentBNB: "debug"},
+		File:   LogFile{Path: path},
+	}, false)
+	require.NoError(t, err)
+
+	loggers.Root().Info("root info")
+	loggers.Root().Warn("root warn")
+	loggers.Component(LogComponentBTC).Info("btc info")
+	loggers.Component(LogComponentBNB).Debug("bnb debug")
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "root info")
+	require.Contains(t, string(content), "root warn")
+	require.NotContains(t, string(content), "btc info")
+	require.Contains(t, string(content), "bnb debug")
+}
+
+func TestLoggersSampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "submitter.log")
+	loggers, err := NewLoggers(Log{
+		Format:   "logfmt",
+		File:     LogFile{Path: path},
+		Sampling: LogSampling{Interval: time.Minute},
+	}, false)
+	require.NoError(t, err)
+	now := time.Now()
+	loggers.sampler.now = func() time.Time { return now }
+
+	// the loggers share the sampler
+	btcLogger := loggers.Component(LogComponentBTC).Sugar()
+	for i := 0; i < 3; i++ {
+		btcLogger.Infof("No new block, current tip: %d, syncPoint:%d", 100, 99)
+		loggers.Root().Info("Handled block")
+	}
+	now = now.Add(time.Minute)
+	loggers.Component(LogComponentBNB).Sugar().Infof("No new block, current tip: %d, syncPoint:%d", 101, 99)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(content), "No new block"))
+	require.Equal(t, 3, strings.Count(string(content), "Handled block"))
+	require.Contains(t, string(content), "sampledOut=2")
+}
+
+func TestLogValidate(t *testing.T) {
+	cfg := Log{}
+	cfg.fillDefaultValueIfNotSet()
+	require.NoError(t, cfg.Validate())
+
+	cfg.Format = "xml"
+	require.Error(t, cfg.Validate())
+
+	cfg.Format = "json"
+	cfg.Levels = map[string]string{"eth": "debug"}
+	require.Error(t, cfg.Validate())
+
+	cfg.Levels = map[string]string{LogComponentRefresher: "verbose"}
+	require.Error(t, cfg.Validate())
+}
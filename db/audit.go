@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AdminAuditLog is an append-only record of an action of the admin API
+type AdminAuditLog struct {
+	Id int
+	// Actor is the source of the action, e.g. ActorAPI
+	Actor string `gorm:"size:31"`
+	// Identity is the name of the API token or the common name of the client certificate
+	Identity string `gorm:"size:255"`
+	Action   string `gorm:"size:31"`
+	Chain    string `gorm:"size:31"`
+	// Target is the deposit txid, the loop or the height of the action
+	Target string `gorm:"size:256"`
+	// Params are the JSON encoded parameters of the action
+	Params string
+	Reason string `gorm:"size:255"`
+	// Error is the error of a failed action, empty if it succeeded.
+	// The log is written before the action is applied with AuditOutcomeUnknown, which is replaced by its outcome.
+	Error       string
+	CreatedTime time.Time `gorm:"autoCreateTime"`
+}
+
+func (AdminAuditLog) TableName() string {
+	return "admin_audit_log"
+}
+
+// AuditOutcomeUnknown is the error of an audit log until the outcome of its action is recorded,
+// it's kept if the process stopped while applying the action
+const AuditOutcomeUnknown = "the outcome of the action is unknown"
+
+type IAuditRepository interface {
+	InsertAuditLog(log *AdminAuditLog) error
+	// CompleteAuditLog records the outcome of the action of the audit log, actionErr is empty if it succeeded
+	CompleteAuditLog(id int, actionErr string) error
+	// GetAuditLogs returns up to limit audit logs with an id greater than afterId in the order they happened
+	GetAuditLogs(afterId int, limit int) ([]*AdminAuditLog, error)
+}
+
+// AuditRepository keeps the audit logs in the admin_audit_log table
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) (IAuditRepository, error) {
+	if db == nil {
+		return nil, errors.New("db handle is nil")
+	}
+
+	return &AuditRepository{
+		db: db,
+	}, nil
+}
+
+func (r *AuditRepository) InsertAuditLog(log *AdminAuditLog) error {
+	return r.db.Create(log).Error
+}
+
+func (r *AuditRepository) CompleteAuditLog(id int, actionErr string) error {
+	return r.db.Model(&AdminAuditLog{}).Where("id = ?", id).Update("error", actionErr).Error
+}
+
+func (r *AuditRepository) GetAuditLogs(afterId int, limit int) ([]*AdminAuditLog, error) {
+	var logs []*AdminAuditLog
+	err := r.db.Model(&AdminAuditLog{}).Where("id > ?", afterId).Order("id").Limit(limit).Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
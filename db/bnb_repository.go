@@ -2,30 +2,142 @@ package db
 
 import (
 	"errors"
+	"fmt"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 // BNBRepository BNB Smart Chain transaction relayer repository
 type BNBRepository struct {
-	db           *gorm.DB
-	chainName    string
-	syncPointKey string
+	db        *gorm.DB
+	chainName string
+}
+
+func (r *BNBRepository) MarkSuccess(txid string, logIndex uint, change StatusChange) error {
+	return r.updateStatus(txid, &logIndex, StatusSuccess, change)
+}
+
+func (r *BNBRepository) MarkInvalid(txid string, logIndex uint, change StatusChange) error {
+	return r.updateStatus(txid, &logIndex, StatusInvalid, change)
+}
+
+func (r *BNBRepository) MarkInvalidPlan(txid string, logIndex uint, change StatusChange) error {
+	return r.updateStatus(txid, &logIndex, StatusInvalidPlan, change)
+}
+
+func (r *BNBRepository) UpdateStatus(txid string, logIndex *uint, status int, change StatusChange) error {
+	return r.updateStatus(txid, logIndex, status, change)
+}
+
+func (r *BNBRepository) GetStatusHistory(txid string) ([]*DepositStatusHistory, error) {
+	return getStatusHistory(r.db, r.chainName, txid)
+}
+
+func (r *BNBRepository) GetStatusHistoryAfter(afterId int, since time.Time, limit int) ([]*DepositStatusHistory, error) {
+	return getStatusHistoryAfter(r.db, r.chainName, afterId, since, limit)
+}
+
+func (r *BNBRepository) RecordDepositEvent(event *DepositEvent) error {
+	return recordDepositEvent(r.db, event)
+}
+
+func (r *BNBRepository) GetDepositEvents(afterId int, limit int) ([]*DepositEvent, error) {
+	return getDepositEvents(r.db, r.chainName, afterId, limit)
+}
+
+func (r *BNBRepository) ReadDepositEvents(position int, limit int, visibleBefore time.Time) ([]*DepositEvent, int, error) {
+	return readDepositEvents(r.db, r.chainName, position, limit, visibleBefore)
+}
+
+func (r *BNBRepository) GetEventCursor(publisher string) (int, error) {
+	return getEventCursor(r.db, publisher, r.chainName)
+}
+
+func (r *BNBRepository) SetEventCursor(publisher string, id int) error {
+	return setEventCursor(r.db, publisher, r.chainName, id)
+}
+
+func (r *BNBRepository) GetHubAddresses() ([]*HubAddress, error) {
+	return getHubAddresses(r.db, r.chainName)
 }
 
-func (r *BNBRepository) MarkSuccess(txid string) error {
-	return r.db.Model(&WrappedBTCDepositTx{}).Where("chain = ? AND txid = ?", r.chainName, txid).
-		Update("status", StatusSuccess).Error
+func (r *BNBRepository) AddHubAddress(address *HubAddress) (bool, error) {
+	return addHubAddress(r.db, r.chainName, address)
 }
 
-func (r *BNBRepository) MarkInvalid(txid string) error {
-	return r.db.Model(&WrappedBTCDepositTx{}).Where("chain = ? AND txid = ?", r.chainName, txid).
-		Update("status", StatusInvalid).Error
+func (r *BNBRepository) GetPendingDeposits(before time.Time) (*PendingDeposits, error) {
+	return getPendingDeposits(r.db.Model(&WrappedBTCDepositTx{}).Where("chain = ?", r.chainName), before)
 }
 
-func (r *BNBRepository) InsertWrappedBTCDepositTxs(txs []*WrappedBTCDepositTx) error {
+// updateStatus updates the status of the events of the tx, or only the event of logIndex if it is not nil,
+// and records the transitions in the status history
+func (r *BNBRepository) updateStatus(txid string, logIndex *uint, status int, change StatusChange) error {
 	return r.db.Transaction(func(dbtx *gorm.DB) error {
+		query := dbtx.Model(&WrappedBTCDepositTx{}).Where("chain = ? AND txid = ?", r.chainName, txid)
+		if logIndex != nil {
+			query = query.Where("log_index = ?", *logIndex)
+		}
+		var txs []*WrappedBTCDepositTx
+		if err := query.Order("log_index").Find(&txs).Error; err != nil {
+			return err
+		}
+		if len(txs) == 0 {
+			archived := dbtx.Model(&ArchivedWrappedBTCDepositTx{}).Where("chain = ? AND txid = ?", r.chainName, txid)
+			if logIndex != nil {
+				archived = archived.Where("log_index = ?", *logIndex)
+			}
+			return checkArchived(archived)
+		}
+
 		for _, tx := range txs {
-			if ok, err := r.hasWrappedBTCDepositTxByTxid(dbtx, tx.Chain, tx.Txid); err != nil {
+			if tx.stripped() {
+				return fmt.Errorf("event %d: %w", tx.LogIndex, ErrDepositStripped)
+			}
+			if err := change.check(tx.Status); err != nil {
+				return fmt.Errorf("event %d: %w", tx.LogIndex, err)
+			}
+		}
+
+		updates := map[string]interface{}{"status": status}
+		if change.Reason != "" {
+			updates["reason"] = change.Reason
+		}
+		for _, tx := range txs {
+			query := dbtx.Model(&WrappedBTCDepositTx{}).Where("id = ?", tx.Id)
+			if change.Check != nil {
+				query = query.Where("status = ?", tx.Status)
+			}
+			if tx.Status == StatusSuccess {
+				// the retention job may strip the deposit concurrently
+				query = query.Where("(receipt <> '' OR proof <> '')")
+			}
+			result := query.Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if change.Check != nil && result.RowsAffected == 0 {
+				return fmt.Errorf("event %d: %w", tx.LogIndex, ErrStatusChanged)
+			}
+			history := newDepositStatusHistory(r.chainName, txid, tx.LogIndex, tx.Status, status, change)
+			if err := dbtx.Create(history).Error; err != nil {
+				return err
+			}
+			if event := statusEvent(NewWrappedBTCDepositEvent(tx, ""), status, change); event != nil {
+				if err := dbtx.Create(event).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *BNBRepository) InsertWrappedBTCDepositTxs(txs []*WrappedBTCDepositTx, height uint64, blockHash string) error {
+	return r.db.Transaction(func(dbtx *gorm.DB) error {
+		for _, tx := range txs {
+			if ok, err := r.hasWrappedBTCDepositTx(dbtx, tx.Chain, tx.Txid, tx.LogIndex); err != nil {
 				return err
 			} else if ok {
 				continue
@@ -35,16 +147,19 @@ func (r *BNBRepository) InsertWrappedBTCDepositTxs(txs []*WrappedBTCDepositTx) e
 			if err != nil {
 				return err
 			}
+			if err := dbtx.Create(NewWrappedBTCDepositEvent(tx, EventDetected)).Error; err != nil {
+				return err
+			}
 		}
 
-		return nil
+		return upsertCheckpoint(dbtx, r.chainName, height, blockHash)
 	})
 }
 
 func (r *BNBRepository) GetUnhandledWrappedBTCDepositTxs(lorenzoBTCTip uint64) ([]*WrappedBTCDepositTx, error) {
 	var txs []*WrappedBTCDepositTx
-	result := r.db.Model(&WrappedBTCDepositTx{}).Where("status = ? AND height <= ?", StatusPending, lorenzoBTCTip).
-		Order("height").Find(&txs)
+	result := r.db.Model(&WrappedBTCDepositTx{}).Where("chain = ? AND status = ? AND height <= ?", r.chainName, StatusPending, lorenzoBTCTip).
+		Order("height, txid, log_index").Find(&txs)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -52,34 +167,46 @@ func (r *BNBRepository) GetUnhandledWrappedBTCDepositTxs(lorenzoBTCTip uint64) (
 	return txs, nil
 }
 
-func (r *BNBRepository) UpdateSyncPoint(height uint64) error {
-	return SetUint64(r.db, r.syncPointKey, height)
+func (r *BNBRepository) UpdateSyncPoint(height uint64, blockHash string) error {
+	return upsertCheckpoint(r.db, r.chainName, height, blockHash)
 }
 
 func (r *BNBRepository) GetSyncPoint() (uint64, error) {
-	return GetUint64(r.db, r.syncPointKey)
-}
-
-func (r *BNBRepository) hasWrappedBTCDepositTxByTxid(dbtx *gorm.DB, chain string, txid string) (bool, error) {
-	var count int64
-	result := dbtx.Model(&WrappedBTCDepositTx{}).Where("chain=? AND txid = ?", chain, txid).Count(&count)
-	if result.Error != nil {
-		return false, result.Error
+	checkpoint, err := r.GetCheckpoint()
+	if err != nil {
+		return 0, err
 	}
 
-	return count > 0, nil
+	return checkpoint.Height, nil
+}
+
+func (r *BNBRepository) GetCheckpoint() (*SyncCheckpoint, error) {
+	return getCheckpoint(r.db, r.chainName)
 }
 
-func NewBNBRepository(chainName string) (IBNBRepository, error) {
-	if DB == nil {
-		return nil, errors.New("DB is not initialized yet")
+// hasWrappedBTCDepositTx reports whether the event is known, archived events included so a rescan does not add them again
+func (r *BNBRepository) hasWrappedBTCDepositTx(dbtx *gorm.DB, chain string, txid string, logIndex uint) (bool, error) {
+	for _, model := range []interface{}{&WrappedBTCDepositTx{}, &ArchivedWrappedBTCDepositTx{}} {
+		var count int64
+		result := dbtx.Model(model).Where("chain=? AND txid = ? AND log_index = ?", chain, txid, logIndex).Count(&count)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if count > 0 {
+			return true, nil
+		}
 	}
 
-	mysqlDb := &BNBRepository{
-		db:           DB,
-		chainName:    chainName,
-		syncPointKey: submitterBnbSyncPointKey,
+	return false, nil
+}
+
+func NewBNBRepository(db *gorm.DB, chainName string) (IBNBRepository, error) {
+	if db == nil {
+		return nil, errors.New("db handle is nil")
 	}
 
-	return mysqlDb, nil
+	return &BNBRepository{
+		db:        db,
+		chainName: chainName,
+	}, nil
 }
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SyncCheckpoint is the last block of a chain whose deposits have been persisted
+type SyncCheckpoint struct {
+	Chain       string `gorm:"primaryKey;size:31"`
+	Height      uint64
+	BlockHash   string    `gorm:"size:256"`
+	UpdatedTime time.Time `gorm:"autoUpdateTime"`
+}
+
+func (SyncCheckpoint) TableName() string {
+	return "sync_checkpoint"
+}
+
+// getCheckpoint returns the checkpoint of the chain, or an empty checkpoint if it is not set
+func getCheckpoint(db *gorm.DB, chain string) (*SyncCheckpoint, error) {
+	var checkpoint SyncCheckpoint
+	err := db.Model(&SyncCheckpoint{}).Where("chain = ?", chain).First(&checkpoint).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &SyncCheckpoint{Chain: chain}, nil
+		}
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// upsertCheckpoint inserts or updates the checkpoint of the chain in a single statement
+func upsertCheckpoint(db *gorm.DB, chain string, height uint64, blockHash string) error {
+	checkpoint := SyncCheckpoint{
+		Chain:       chain,
+		Height:      height,
+		BlockHash:   blockHash,
+		UpdatedTime: time.Now(),
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain"}},
+		DoUpdates: clause.AssignmentColumns([]string{"height", "block_hash", "updated_time"}),
+	}).Create(&checkpoint).Error
+}
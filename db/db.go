@@ -1,25 +1,74 @@
 package db
 
+import "time"
+
 type ISyncPointRepository interface {
-	UpdateSyncPoint(height uint64) error
+	// UpdateSyncPoint moves the checkpoint without inserting deposits, e.g. on start or rollback
+	UpdateSyncPoint(height uint64, blockHash string) error
 	GetSyncPoint() (uint64, error)
+	GetCheckpoint() (*SyncCheckpoint, error)
 }
 
 type IWrappedBTCDepositTxRepository interface {
-	InsertWrappedBTCDepositTxs(txs []*WrappedBTCDepositTx) error
+	// InsertWrappedBTCDepositTxs inserts the txs and advances the checkpoint to the height in one transaction
+	InsertWrappedBTCDepositTxs(txs []*WrappedBTCDepositTx, height uint64, blockHash string) error
 	GetUnhandledWrappedBTCDepositTxs(lorenzoBTCTip uint64) ([]*WrappedBTCDepositTx, error)
-	MarkSuccess(txid string) error
-	MarkInvalid(txid string) error
+	// MarkSuccess and MarkInvalid set the status of one event of the tx, its sibling events are left as they are
+	MarkSuccess(txid string, logIndex uint, change StatusChange) error
+	MarkInvalid(txid string, logIndex uint, change StatusChange) error
+	// MarkInvalidPlan rejects one event of the tx with the reason of the change
+	MarkInvalidPlan(txid string, logIndex uint, change StatusChange) error
+	// UpdateStatus sets the status of the events of the tx, or only the event of logIndex if it is not nil
+	UpdateStatus(txid string, logIndex *uint, status int, change StatusChange) error
+}
+
+type IStatusHistoryRepository interface {
+	// GetStatusHistory returns the status transitions of the tx in the order they happened
+	GetStatusHistory(txid string) ([]*DepositStatusHistory, error)
+	// GetStatusHistoryAfter returns up to limit status transitions of the chain with an id greater than afterId,
+	// created since the time, in the order they happened
+	GetStatusHistoryAfter(afterId int, since time.Time, limit int) ([]*DepositStatusHistory, error)
+}
+
+type IPendingDepositRepository interface {
+	// GetPendingDeposits summarizes the deposits of the chain still pending that were inserted before the time
+	GetPendingDeposits(before time.Time) (*PendingDeposits, error)
 }
 
 type IBTCRepository interface {
 	ISyncPointRepository
-	InsertBtcDepositTxs(txs []*BtcDepositTx) error
+	// InsertBtcDepositTxs inserts the txs and advances the checkpoint to the height in one transaction
+	InsertBtcDepositTxs(txs []*BtcDepositTx, height uint64, blockHash string) error
 	GetUnhandledBtcDepositTxs(lorenzoBTCTip uint64) ([]*BtcDepositTx, error)
-	UpdateTxStatus(txid string, status int) error
+	UpdateTxStatus(txid string, status int, change StatusChange) error
+	// QueryBtcDepositTxs returns up to filter.Limit deposits of the filter ordered by id
+	QueryBtcDepositTxs(filter DepositFilter) ([]*BtcDepositTx, error)
+	IStatusHistoryRepository
+	IPendingDepositRepository
+	IDepositEventRepository
 }
 
 type IBNBRepository interface {
 	ISyncPointRepository
 	IWrappedBTCDepositTxRepository
+	// QueryWrappedBTCDepositTxs returns up to filter.Limit deposits of the filter ordered by id
+	QueryWrappedBTCDepositTxs(filter DepositFilter) ([]*WrappedBTCDepositTx, error)
+	IStatusHistoryRepository
+	IPendingDepositRepository
+	IDepositEventRepository
+	IHubAddressRepository
+}
+
+type IRetentionRepository interface {
+	// StripWrappedBTCDepositProofs empties the receipts and proofs of up to limit successful deposits
+	// last updated before the time, it returns the number of stripped deposits
+	StripWrappedBTCDepositProofs(before time.Time, limit int) (int, error)
+	// ArchiveBtcDepositTxs moves up to limit handled deposits last updated before the time
+	// into the archive table, it returns the number of archived deposits
+	ArchiveBtcDepositTxs(before time.Time, limit int) (int, error)
+	ArchiveWrappedBTCDepositTxs(before time.Time, limit int) (int, error)
+	// ExportArchivedBtcDepositTxs passes up to limit archived deposits not exported yet to the exporter
+	// and marks them exported, it returns the number of exported deposits
+	ExportArchivedBtcDepositTxs(limit int, exporter DepositExporter) (int, error)
+	ExportArchivedWrappedBTCDepositTxs(limit int, exporter DepositExporter) (int, error)
 }
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// types of the deposit lifecycle events
+const (
+	// EventDetected the deposit is inserted by the scan loop
+	EventDetected = "detected"
+	// EventConfirmed the deposit is confirmed by the Lorenzo light client and picked by the submit loop
+	EventConfirmed = "confirmed"
+	// EventSubmitted the deposit is sent to Lorenzo
+	EventSubmitted = "submitted"
+	// EventMinted the deposit is marked successful
+	EventMinted = "minted"
+	// EventInvalid the deposit is marked invalid, receiver not agent or invalid plan
+	EventInvalid = "invalid"
+)
+
+// eventStages orders the lifecycle events, minted and invalid end a lifecycle
+var eventStages = map[string]int{
+	EventDetected:  0,
+	EventConfirmed: 1,
+	EventSubmitted: 2,
+	EventMinted:    3,
+	EventInvalid:   3,
+}
+
+// DepositEvent is a deposit lifecycle event in the transactional outbox.
+// The events of status changes are recorded in the transactions of the changes, so none is lost across restarts.
+type DepositEvent struct {
+	Id       int
+	Chain    string `gorm:"size:31"`
+	Txid     string `gorm:"size:256"`
+	LogIndex uint
+	Type     string `gorm:"size:31"`
+	// Status is the status of the deposit after the event
+	Status int
+	// Reason is the error or the reason of an invalid deposit
+	Reason string
+	// Data is the JSON of the deposit
+	Data        string
+	CreatedTime time.Time `gorm:"autoCreateTime"`
+}
+
+func (DepositEvent) TableName() string {
+	return "deposit_event_outbox"
+}
+
+// btcDepositEventData is the data of the BTC deposit events
+type btcDepositEventData struct {
+	AgentId         uint64    `json:"agentId"`
+	ReceiverName    string    `json:"receiverName"`
+	ReceiverAddress string    `json:"receiverAddress"`
+	Amount          uint64    `json:"amount"`
+	Height          uint64    `json:"height"`
+	BlockHash       string    `json:"blockHash"`
+	BlockTime       time.Time `json:"blockTime"`
+}
+
+// bnbDepositEventData is the data of the BNB deposit events
+type bnbDepositEventData struct {
+	EventName          string    `json:"eventName"`
+	StakeIndex         uint64    `json:"stakeIndex"`
+	PlanId             uint64    `json:"planId"`
+	UserAddress        string    `json:"userAddress"`
+	BtcContractAddress string    `json:"btcContractAddress"`
+	StakeAmount        string    `json:"stakeAmount"`
+	StBTCAmount        string    `json:"stBTCAmount"`
+	Height             uint64    `json:"height"`
+	BlockHash          string    `json:"blockHash"`
+	BlockTime          time.Time `json:"blockTime"`
+}
+
+// NewBtcDepositEvent returns an event of the BTC deposit in its current status
+func NewBtcDepositEvent(tx *BtcDepositTx, eventType string) *DepositEvent {
+	data, _ := json.Marshal(btcDepositEventData{
+		AgentId:         tx.AgentId,
+		ReceiverName:    tx.ReceiverName,
+		ReceiverAddress: tx.ReceiverAddress,
+		Amount:          tx.Amount,
+		Height:          tx.Height,
+		BlockHash:       tx.BlockHash,
+		BlockTime:       tx.BlockTime,
+	})
+	return &DepositEvent{
+		Chain:  btcChain,
+		Txid:   tx.Txid,
+		Type:   eventType,
+		Status: tx.Status,
+		Data:   string(data),
+	}
+}
+
+// NewWrappedBTCDepositEvent returns an event of the BNB deposit in its current status
+func NewWrappedBTCDepositEvent(tx *WrappedBTCDepositTx, eventType string) *DepositEvent {
+	data, _ := json.Marshal(bnbDepositEventData{
+		EventName:          tx.EventName,
+		StakeIndex:         tx.StakeIndex,
+		PlanId:             tx.PlanId,
+		UserAddress:        tx.UserAddress,
+		BtcContractAddress: tx.BtcContractAddress,
+		StakeAmount:        tx.StakeAmount,
+		StBTCAmount:        tx.StBTCAmount,
+		Height:             tx.Height,
+		BlockHash:          tx.BlockHash,
+		BlockTime:          tx.BlockTime,
+	})
+	return &DepositEvent{
+		Chain:    tx.Chain,
+		Txid:     tx.Txid,
+		LogIndex: tx.LogIndex,
+		Type:     eventType,
+		Status:   tx.Status,
+		Data:     string(data),
+	}
+}
+
+// statusEvent completes the event of a deposit changed to the status, it returns nil if the status has no event,
+// e.g. a deposit set back to pending
+func statusEvent(event *DepositEvent, status int, change StatusChange) *DepositEvent {
+	switch status {
+	case StatusSuccess:
+		event.Type = EventMinted
+	case StatusInvalid, StatusReceiverIsNotBelongToAgent, StatusInvalidPlan:
+		event.Type = EventInvalid
+	default:
+		return nil
+	}
+	event.Status = status
+	event.Reason = change.Error
+	if event.Reason == "" {
+		event.Reason = change.Reason
+	}
+	return event
+}
+
+type IDepositEventRepository interface {
+	// RecordDepositEvent records an event of the relayer, e.g. a confirmed or submitted deposit. It is skipped
+	// if the deposit has already reached the stage of the event since it was detected or last minted or invalid,
+	// so the retries of a deposit do not record it again.
+	RecordDepositEvent(event *DepositEvent) error
+	// GetDepositEvents returns up to limit events of the chain with an id greater than afterId in the order they were recorded
+	GetDepositEvents(afterId int, limit int) ([]*DepositEvent, error)
+	// ReadDepositEvents reads up to limit events of the outbox of all chains after the position, an event id, and
+	// returns the ones of the chain and the new position. The ids are allocated before the transactions commit, so
+	// it stops at a gap in the ids while the next event was recorded after visibleBefore: the missing event may
+	// be committed later. Older gaps are left by rolled back transactions and skipped.
+	ReadDepositEvents(position int, limit int, visibleBefore time.Time) ([]*DepositEvent, int, error)
+	// GetEventCursor returns the position of the publisher in the outbox of the chain, 0 if none
+	GetEventCursor(publisher string) (int, error)
+	SetEventCursor(publisher string, id int) error
+}
+
+// isDepositEventReached tells whether an event of the type is skipped after the last event of the deposit
+func isDepositEventReached(last *DepositEvent, eventType string) bool {
+	if last == nil || last.Type == EventMinted || last.Type == EventInvalid {
+		return false
+	}
+	return eventStages[last.Type] >= eventStages[eventType]
+}
+
+func recordDepositEvent(db *gorm.DB, event *DepositEvent) error {
+	return db.Transaction(func(dbtx *gorm.DB) error {
+		var last DepositEvent
+		err := dbtx.Model(&DepositEvent{}).Where("chain = ? AND txid = ? AND log_index = ?", event.Chain, event.Txid, event.LogIndex).
+			Order("id DESC").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && isDepositEventReached(&last, event.Type) {
+			return nil
+		}
+		return dbtx.Create(event).Error
+	})
+}
+
+func getDepositEvents(db *gorm.DB, chain string, afterId int, limit int) ([]*DepositEvent, error) {
+	var events []*DepositEvent
+	err := db.Model(&DepositEvent{}).Where("chain = ? AND id > ?", chain, afterId).
+		Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func readDepositEvents(db *gorm.DB, chain string, position int, limit int, visibleBefore time.Time) ([]*DepositEvent, int, error) {
+	var records []*DepositEvent
+	err := db.Model(&DepositEvent{}).Where("id > ?", position).Order("id").Limit(limit).Find(&records).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var events []*DepositEvent
+	for _, record := range records {
+		if record.Id != position+1 && !record.CreatedTime.Before(visibleBefore) {
+			break
+		}
+		position = record.Id
+		if record.Chain == chain {
+			events = append(events, record)
+		}
+	}
+	return events, position, nil
+}
+
+// eventCursorKey is the key of the publisher cursor of the chain in the config table
+func eventCursorKey(publisher string, chain string) string {
+	return fmt.Sprintf("event-cursor/%s/%s", publisher, chain)
+}
+
+func getEventCursor(db *gorm.DB, publisher string, chain string) (int, error) {
+	id, err := GetUint64(db, eventCursorKey(publisher, chain))
+	return int(id), err
+}
+
+func setEventCursor(db *gorm.DB, publisher string, chain string, id int) error {
+	return SetUint64(db, eventCursorKey(publisher, chain), uint64(id))
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// HubAddress is a stake plan hub address of a chain and the height it is effective from
+type HubAddress struct {
+	Address       string
+	EffectiveFrom uint64
+	// Approximate is set if EffectiveFrom is only a lower bound of the height the address changed at
+	Approximate bool
+}
+
+type IHubAddressRepository interface {
+	// GetHubAddresses returns the stake plan hub addresses of the chain ordered by the height they are effective from
+	GetHubAddresses() ([]*HubAddress, error)
+	// AddHubAddress records the address, replacing the addresses effective from the same or a later height.
+	// The checkpoint is moved back before the height in the same transaction if it was scanned already,
+	// it returns true if the checkpoint is moved.
+	AddHubAddress(address *HubAddress) (bool, error)
+}
+
+// hubAddressesKey is the key of the hub address history of the chain in the config table
+func hubAddressesKey(chain string) string {
+	return fmt.Sprintf("hub-addresses/%s", chain)
+}
+
+func getHubAddresses(db *gorm.DB, chain string) ([]*HubAddress, error) {
+	value, err := Get(db, hubAddressesKey(chain))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var addresses []*HubAddress
+	if err := json.Unmarshal([]byte(value), &addresses); err != nil {
+		return nil, fmt.Errorf("invalid hub addresses of %s: %w", chain, err)
+	}
+	return addresses, nil
+}
+
+// appendHubAddress appends the address to the history ordered by height, dropping the addresses it replaces
+func appendHubAddress(addresses []*HubAddress, address *HubAddress) []*HubAddress {
+	i := len(addresses)
+	for i > 0 && addresses[i-1].EffectiveFrom >= address.EffectiveFrom {
+		i--
+	}
+	stored := *address
+	return append(addresses[:i], &stored)
+}
+
+func addHubAddress(db *gorm.DB, chain string, address *HubAddress) (bool, error) {
+	rolledBack := false
+	err := db.Transaction(func(dbtx *gorm.DB) error {
+		addresses, err := getHubAddresses(dbtx, chain)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(appendHubAddress(addresses, address))
+		if err != nil {
+			return err
+		}
+		if err := Set(dbtx, hubAddressesKey(chain), string(value)); err != nil {
+			return err
+		}
+
+		checkpoint, err := getCheckpoint(dbtx, chain)
+		if err != nil {
+			return err
+		}
+		if address.EffectiveFrom > 0 && checkpoint.Height >= address.EffectiveFrom {
+			rolledBack = true
+			return upsertCheckpoint(dbtx, chain, address.EffectiveFrom-1, "")
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rolledBack, nil
+}
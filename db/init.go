@@ -2,25 +2,63 @@ package db
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
 )
 
-var DB *gorm.DB
+// Open opens a database handle, repositories are constructed from it
+func Open(cfg config.Database) (*gorm.DB, error) {
+	dialector, err := newDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-func Init(cfg config.Database) error {
-	dns := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.DBName)
-	db, err := gorm.Open(mysql.Open(dns), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if cfg.Driver == config.DatabaseDriverSQLite {
+		// sqlite allows one writer at a time, and each connection of :memory: is a separate database
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
 	}
 
-	DB = db
-	return nil
+	return db, nil
+}
+
+func newDialector(cfg config.Database) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", config.DatabaseDriverMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.DBName)
+		return mysql.Open(dsn), nil
+	case config.DatabaseDriverPostgres:
+		dsn := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(cfg.Username, cfg.Password),
+			Host:   cfg.Host + ":" + strconv.Itoa(cfg.Port),
+			Path:   "/" + cfg.DBName,
+		}
+		if cfg.SSLMode != "" {
+			dsn.RawQuery = url.Values{"sslmode": []string{cfg.SSLMode}}.Encode()
+		}
+		return postgres.Open(dsn.String()), nil
+	case config.DatabaseDriverSQLite:
+		return sqlite.Open(cfg.DBName + "?_pragma=busy_timeout(5000)"), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
 }
@@ -2,25 +2,19 @@ package db
 
 import (
 	"errors"
-	"gorm.io/gorm"
 	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Set sets the value of a key in the database
 func Set(db *gorm.DB, key string, value string) error {
-	var cfg Config
-	err := db.Model(&Config{}).Where("name = ?", key).First(&cfg).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			cfg.Name = key
-			cfg.Value = value
-			return db.Create(&cfg).Error
-		}
-
-		return err
-	}
-
-	return db.Model(&Config{}).Where("name = ?", key).Update("value", value).Error
+	cfg := Config{Name: key, Value: value}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_time"}),
+	}).Create(&cfg).Error
 }
 
 // Get retrieves the value of a key from the database
@@ -0,0 +1,674 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryBTCRepository is an in-memory IBTCRepository for tests and dry runs,
+// it has the same semantics as BtcRepository
+type MemoryBTCRepository struct {
+	lock sync.RWMutex
+
+	checkpoint SyncCheckpoint
+	txs        []*BtcDepositTx
+	txidIndex  map[string]*BtcDepositTx
+	lastId     int
+	history    memoryStatusHistory
+	events     memoryDepositEvents
+}
+
+func NewMemoryBTCRepository() IBTCRepository {
+	return &MemoryBTCRepository{
+		checkpoint: SyncCheckpoint{Chain: btcChain},
+		txidIndex:  make(map[string]*BtcDepositTx),
+	}
+}
+
+func (r *MemoryBTCRepository) UpdateSyncPoint(height uint64, blockHash string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.checkpoint.update(height, blockHash)
+	return nil
+}
+
+func (r *MemoryBTCRepository) GetSyncPoint() (uint64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.checkpoint.Height, nil
+}
+
+func (r *MemoryBTCRepository) GetCheckpoint() (*SyncCheckpoint, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	checkpoint := r.checkpoint
+	return &checkpoint, nil
+}
+
+func (r *MemoryBTCRepository) InsertBtcDepositTxs(txs []*BtcDepositTx, height uint64, blockHash string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	for _, tx := range txs {
+		if _, ok := r.txidIndex[tx.Txid]; ok {
+			continue
+		}
+
+		r.lastId++
+		tx.Id = r.lastId
+		tx.CreatedTime = now
+		tx.UpdatedTime = now
+		stored := *tx
+		r.txs = append(r.txs, &stored)
+		r.txidIndex[tx.Txid] = &stored
+		r.events.append(NewBtcDepositEvent(&stored, EventDetected))
+	}
+	r.checkpoint.update(height, blockHash)
+
+	return nil
+}
+
+func (r *MemoryBTCRepository) GetUnhandledBtcDepositTxs(lorenzoBTCTip uint64) ([]*BtcDepositTx, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var txs []*BtcDepositTx
+	for _, tx := range r.txs {
+		if tx.Status == StatusPending && isBtcDepositTxConfirmed(tx, lorenzoBTCTip) {
+			copied := *tx
+			txs = append(txs, &copied)
+		}
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].Height < txs[j].Height
+	})
+	if len(txs) > BatchHandleBtcDepositTxsNum {
+		txs = txs[:BatchHandleBtcDepositTxsNum]
+	}
+
+	return txs, nil
+}
+
+func (r *MemoryBTCRepository) UpdateTxStatus(txid string, status int, change StatusChange) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if tx, ok := r.txidIndex[txid]; ok {
+		if err := change.check(tx.Status); err != nil {
+			return err
+		}
+		r.history.append(newDepositStatusHistory(btcChain, txid, 0, tx.Status, status, change))
+		if event := statusEvent(NewBtcDepositEvent(tx, ""), status, change); event != nil {
+			r.events.append(event)
+		}
+		tx.Status = status
+		tx.UpdatedTime = time.Now()
+	}
+	return nil
+}
+
+func (r *MemoryBTCRepository) GetStatusHistory(txid string) ([]*DepositStatusHistory, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.history.get(btcChain, txid), nil
+}
+
+func (r *MemoryBTCRepository) GetStatusHistoryAfter(afterId int, since time.Time, limit int) ([]*DepositStatusHistory, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.history.after(btcChain, afterId, since, limit), nil
+}
+
+func (r *MemoryBTCRepository) RecordDepositEvent(event *DepositEvent) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events.record(event)
+	return nil
+}
+
+func (r *MemoryBTCRepository) GetDepositEvents(afterId int, limit int) ([]*DepositEvent, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.events.after(afterId, limit), nil
+}
+
+func (r *MemoryBTCRepository) ReadDepositEvents(position int, limit int, _ time.Time) ([]*DepositEvent, int, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	events := r.events.after(position, limit)
+	if len(events) > 0 {
+		position = events[len(events)-1].Id
+	}
+	return events, position, nil
+}
+
+func (r *MemoryBTCRepository) GetEventCursor(publisher string) (int, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.events.cursors[publisher], nil
+}
+
+func (r *MemoryBTCRepository) SetEventCursor(publisher string, id int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events.setCursor(publisher, id)
+	return nil
+}
+
+func (r *MemoryBTCRepository) GetPendingDeposits(before time.Time) (*PendingDeposits, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var pending PendingDeposits
+	for _, tx := range r.txs {
+		if tx.Status == StatusPending && tx.CreatedTime.Before(before) {
+			pending.addPendingDeposit(tx.Txid, tx.CreatedTime)
+		}
+	}
+	return &pending, nil
+}
+
+// isBtcDepositTxConfirmed is the tiered selection of BtcRepository.GetUnhandledBtcDepositTxs,
+// larger deposits wait for more Lorenzo BTC light client confirmations
+func isBtcDepositTxConfirmed(tx *BtcDepositTx, lorenzoBTCTip uint64) bool {
+	switch {
+	case tx.Amount < uint64(Dep0Amount):
+		return true
+	case tx.Amount < uint64(Dep1Amount):
+		return tx.Height <= heightBefore(lorenzoBTCTip, 1)
+	case tx.Amount < uint64(Dep2Amount):
+		return tx.Height <= heightBefore(lorenzoBTCTip, 2)
+	case tx.Amount < uint64(Dep3Amount):
+		return tx.Height <= heightBefore(lorenzoBTCTip, 3)
+	default:
+		return tx.Height <= heightBefore(lorenzoBTCTip, 4)
+	}
+}
+
+// MemoryBNBRepository is an in-memory IBNBRepository for tests and dry runs,
+// it has the same semantics as BNBRepository
+type MemoryBNBRepository struct {
+	lock sync.RWMutex
+
+	chainName  string
+	checkpoint SyncCheckpoint
+	txs        []*WrappedBTCDepositTx
+	lastId     int
+	history    memoryStatusHistory
+	events     memoryDepositEvents
+	hubs       []*HubAddress
+}
+
+func NewMemoryBNBRepository(chainName string) IBNBRepository {
+	return &MemoryBNBRepository{
+		chainName:  chainName,
+		checkpoint: SyncCheckpoint{Chain: chainName},
+	}
+}
+
+func (r *MemoryBNBRepository) UpdateSyncPoint(height uint64, blockHash string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.checkpoint.update(height, blockHash)
+	return nil
+}
+
+func (r *MemoryBNBRepository) GetSyncPoint() (uint64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.checkpoint.Height, nil
+}
+
+func (r *MemoryBNBRepository) GetCheckpoint() (*SyncCheckpoint, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	checkpoint := r.checkpoint
+	return &checkpoint, nil
+}
+
+func (r *MemoryBNBRepository) InsertWrappedBTCDepositTxs(txs []*WrappedBTCDepositTx, height uint64, blockHash string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	for _, tx := range txs {
+		if r.find(tx.Chain, tx.Txid, tx.LogIndex) != nil {
+			continue
+		}
+
+		r.lastId++
+		tx.Id = r.lastId
+		tx.CreatedTime = now
+		tx.UpdatedTime = now
+		stored := *tx
+		r.txs = append(r.txs, &stored)
+		r.events.append(NewWrappedBTCDepositEvent(&stored, EventDetected))
+	}
+	r.checkpoint.update(height, blockHash)
+
+	return nil
+}
+
+func (r *MemoryBNBRepository) GetUnhandledWrappedBTCDepositTxs(lorenzoBTCTip uint64) ([]*WrappedBTCDepositTx, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var txs []*WrappedBTCDepositTx
+	for _, tx := range r.txs {
+		if tx.Chain == r.chainName && tx.Status == StatusPending && tx.Height <= lorenzoBTCTip {
+			copied := *tx
+			txs = append(txs, &copied)
+		}
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		if txs[i].Height != txs[j].Height {
+			return txs[i].Height < txs[j].Height
+		}
+		if txs[i].Txid != txs[j].Txid {
+			return txs[i].Txid < txs[j].Txid
+		}
+		return txs[i].LogIndex < txs[j].LogIndex
+	})
+
+	return txs, nil
+}
+
+func (r *MemoryBNBRepository) MarkSuccess(txid string, logIndex uint, change StatusChange) error {
+	return r.updateStatus(txid, &logIndex, StatusSuccess, change)
+}
+
+func (r *MemoryBNBRepository) MarkInvalid(txid string, logIndex uint, change StatusChange) error {
+	return r.updateStatus(txid, &logIndex, StatusInvalid, change)
+}
+
+func (r *MemoryBNBRepository) MarkInvalidPlan(txid string, logIndex uint, change StatusChange) error {
+	return r.updateStatus(txid, &logIndex, StatusInvalidPlan, change)
+}
+
+func (r *MemoryBNBRepository) UpdateStatus(txid string, logIndex *uint, status int, change StatusChange) error {
+	return r.updateStatus(txid, logIndex, status, change)
+}
+
+func (r *MemoryBNBRepository) GetStatusHistory(txid string) ([]*DepositStatusHistory, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.history.get(r.chainName, txid), nil
+}
+
+func (r *MemoryBNBRepository) GetStatusHistoryAfter(afterId int, since time.Time, limit int) ([]*DepositStatusHistory, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.history.after(r.chainName, afterId, since, limit), nil
+}
+
+func (r *MemoryBNBRepository) RecordDepositEvent(event *DepositEvent) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events.record(event)
+	return nil
+}
+
+func (r *MemoryBNBRepository) GetDepositEvents(afterId int, limit int) ([]*DepositEvent, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.events.after(afterId, limit), nil
+}
+
+func (r *MemoryBNBRepository) ReadDepositEvents(position int, limit int, _ time.Time) ([]*DepositEvent, int, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	events := r.events.after(position, limit)
+	if len(events) > 0 {
+		position = events[len(events)-1].Id
+	}
+	return events, position, nil
+}
+
+func (r *MemoryBNBRepository) GetEventCursor(publisher string) (int, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.events.cursors[publisher], nil
+}
+
+func (r *MemoryBNBRepository) SetEventCursor(publisher string, id int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events.setCursor(publisher, id)
+	return nil
+}
+
+func (r *MemoryBNBRepository) GetHubAddresses() ([]*HubAddress, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	addresses := make([]*HubAddress, 0, len(r.hubs))
+	for _, address := range r.hubs {
+		stored := *address
+		addresses = append(addresses, &stored)
+	}
+	return addresses, nil
+}
+
+func (r *MemoryBNBRepository) AddHubAddress(address *HubAddress) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.hubs = appendHubAddress(r.hubs, address)
+	if address.EffectiveFrom > 0 && r.checkpoint.Height >= address.EffectiveFrom {
+		r.checkpoint.update(address.EffectiveFrom-1, "")
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *MemoryBNBRepository) GetPendingDeposits(before time.Time) (*PendingDeposits, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var pending PendingDeposits
+	for _, tx := range r.txs {
+		if tx.Chain == r.chainName && tx.Status == StatusPending && tx.CreatedTime.Before(before) {
+			pending.addPendingDeposit(tx.Txid, tx.CreatedTime)
+		}
+	}
+	return &pending, nil
+}
+
+// updateStatus updates the status of the events of the tx, or only the event of logIndex if it is not nil
+func (r *MemoryBNBRepository) updateStatus(txid string, logIndex *uint, status int, change StatusChange) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var txs []*WrappedBTCDepositTx
+	for _, tx := range r.txs {
+		if tx.Chain == r.chainName && tx.Txid == txid && (logIndex == nil || tx.LogIndex == *logIndex) {
+			txs = append(txs, tx)
+		}
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].LogIndex < txs[j].LogIndex
+	})
+
+	for _, tx := range txs {
+		if err := change.check(tx.Status); err != nil {
+			return fmt.Errorf("event %d: %w", tx.LogIndex, err)
+		}
+	}
+	for _, tx := range txs {
+		r.history.append(newDepositStatusHistory(r.chainName, txid, tx.LogIndex, tx.Status, status, change))
+		if event := statusEvent(NewWrappedBTCDepositEvent(tx, ""), status, change); event != nil {
+			r.events.append(event)
+		}
+		tx.Status = status
+		if change.Reason != "" {
+			tx.Reason = change.Reason
+		}
+		tx.UpdatedTime = time.Now()
+	}
+	return nil
+}
+
+func (r *MemoryBNBRepository) find(chain string, txid string, logIndex uint) *WrappedBTCDepositTx {
+	for _, tx := range r.txs {
+		if tx.Chain == chain && tx.Txid == txid && tx.LogIndex == logIndex {
+			return tx
+		}
+	}
+	return nil
+}
+
+// MemoryAuditRepository is an in-memory IAuditRepository for tests and dry runs
+type MemoryAuditRepository struct {
+	lock sync.RWMutex
+	logs []*AdminAuditLog
+}
+
+func NewMemoryAuditRepository() IAuditRepository {
+	return &MemoryAuditRepository{}
+}
+
+func (r *MemoryAuditRepository) InsertAuditLog(log *AdminAuditLog) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	log.Id = len(r.logs) + 1
+	log.CreatedTime = time.Now()
+	stored := *log
+	r.logs = append(r.logs, &stored)
+	return nil
+}
+
+func (r *MemoryAuditRepository) CompleteAuditLog(id int, actionErr string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if id > 0 && id <= len(r.logs) {
+		r.logs[id-1].Error = actionErr
+	}
+	return nil
+}
+
+func (r *MemoryAuditRepository) GetAuditLogs(afterId int, limit int) ([]*AdminAuditLog, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var logs []*AdminAuditLog
+	for _, log := range r.logs {
+		if len(logs) == limit {
+			break
+		}
+		if log.Id > afterId {
+			copied := *log
+			logs = append(logs, &copied)
+		}
+	}
+	return logs, nil
+}
+
+type MemoryWebhookRepository struct {
+	lock       sync.RWMutex
+	deliveries []*WebhookDelivery
+}
+
+func NewMemoryWebhookRepository() IWebhookRepository {
+	return &MemoryWebhookRepository{}
+}
+
+func (r *MemoryWebhookRepository) InsertWebhookDeliveries(deliveries []*WebhookDelivery) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	for _, delivery := range deliveries {
+		delivery.Id = len(r.deliveries) + 1
+		delivery.CreatedTime = now
+		delivery.UpdatedTime = now
+		stored := *delivery
+		r.deliveries = append(r.deliveries, &stored)
+	}
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetDueWebhookSubscriptions(now time.Time) ([]string, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	due := make(map[string]bool)
+	var subscriptions []string
+	for _, delivery := range r.deliveries {
+		if delivery.Status == WebhookDeliveryPending && !delivery.NextAttemptTime.After(now) && !due[delivery.Subscription] {
+			due[delivery.Subscription] = true
+			subscriptions = append(subscriptions, delivery.Subscription)
+		}
+	}
+	sort.Strings(subscriptions)
+	return subscriptions, nil
+}
+
+func (r *MemoryWebhookRepository) GetDueWebhookDeliveries(subscription string, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var deliveries []*WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Subscription == subscription && delivery.Status == WebhookDeliveryPending && !delivery.NextAttemptTime.After(now) {
+			copied := *delivery
+			deliveries = append(deliveries, &copied)
+		}
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptTime.Before(deliveries[j].NextAttemptTime)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (r *MemoryWebhookRepository) UpdateWebhookDelivery(delivery *WebhookDelivery) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if delivery.Id <= 0 || delivery.Id > len(r.deliveries) {
+		return nil
+	}
+	stored := r.deliveries[delivery.Id-1]
+	stored.URL = delivery.URL
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.ResponseCode = delivery.ResponseCode
+	stored.Error = delivery.Error
+	stored.NextAttemptTime = delivery.NextAttemptTime
+	stored.UpdatedTime = time.Now()
+	return nil
+}
+
+func (r *MemoryWebhookRepository) GetWebhookDeliveries(txid string) ([]*WebhookDelivery, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var deliveries []*WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Txid == txid {
+			copied := *delivery
+			deliveries = append(deliveries, &copied)
+		}
+	}
+	return deliveries, nil
+}
+
+// memoryStatusHistory is the status history of the in-memory repositories, guarded by their locks
+type memoryStatusHistory struct {
+	records []*DepositStatusHistory
+}
+
+func (h *memoryStatusHistory) append(record *DepositStatusHistory) {
+	record.Id = len(h.records) + 1
+	record.CreatedTime = time.Now()
+	h.records = append(h.records, record)
+}
+
+func (h *memoryStatusHistory) get(chain string, txid string) []*DepositStatusHistory {
+	var records []*DepositStatusHistory
+	for _, record := range h.records {
+		if record.Chain == chain && record.Txid == txid {
+			copied := *record
+			records = append(records, &copied)
+		}
+	}
+	return records
+}
+
+func (h *memoryStatusHistory) after(chain string, afterId int, since time.Time, limit int) []*DepositStatusHistory {
+	var records []*DepositStatusHistory
+	for _, record := range h.records {
+		if len(records) == limit {
+			break
+		}
+		if record.Chain == chain && record.Id > afterId && !record.CreatedTime.Before(since) {
+			copied := *record
+			records = append(records, &copied)
+		}
+	}
+	return records
+}
+
+// memoryDepositEvents is the event outbox of the in-memory repositories, guarded by their locks.
+// The ids are allocated in the order the events are committed, so the outbox has no gaps.
+type memoryDepositEvents struct {
+	records []*DepositEvent
+	cursors map[string]int
+}
+
+func (e *memoryDepositEvents) append(event *DepositEvent) {
+	event.Id = len(e.records) + 1
+	event.CreatedTime = time.Now()
+	e.records = append(e.records, event)
+}
+
+func (e *memoryDepositEvents) record(event *DepositEvent) {
+	var last *DepositEvent
+	for _, record := range e.records {
+		if record.Chain == event.Chain && record.Txid == event.Txid && record.LogIndex == event.LogIndex {
+			last = record
+		}
+	}
+	if isDepositEventReached(last, event.Type) {
+		return
+	}
+	copied := *event
+	e.append(&copied)
+	event.Id = copied.Id
+	event.CreatedTime = copied.CreatedTime
+}
+
+func (e *memoryDepositEvents) after(afterId int, limit int) []*DepositEvent {
+	var events []*DepositEvent
+	for _, record := range e.records {
+		if len(events) == limit {
+			break
+		}
+		if record.Id > afterId {
+			copied := *record
+			events = append(events, &copied)
+		}
+	}
+	return events
+}
+
+func (e *memoryDepositEvents) setCursor(publisher string, id int) {
+	if e.cursors == nil {
+		e.cursors = make(map[string]int)
+	}
+	e.cursors[publisher] = id
+}
+
+// update moves the checkpoint of the in-memory repositories, guarded by their locks
+func (c *SyncCheckpoint) update(height uint64, blockHash string) {
+	c.Height = height
+	c.BlockHash = blockHash
+	c.UpdatedTime = time.Now()
+}
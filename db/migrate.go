@@ -0,0 +1,396 @@
+package db
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+// migrations/<driver>/<version>_<name>.up.sql and .down.sql
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+// ErrSchemaTooNew is returned if the database schema is migrated by a newer binary
+var ErrSchemaTooNew = errors.New("database schema is newer than the binary")
+
+// ErrSchemaOutdated is returned if migrations are pending and they are not applied automatically
+var ErrSchemaOutdated = errors.New("database schema is outdated, run the migrate command")
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+const (
+	migrationUp   = "up"
+	migrationDown = "down"
+)
+
+// SchemaVersion is an applied migration, or a migration partially applied or reverted if Dirty is set.
+// MySQL commits every DDL statement implicitly, so its migrations cannot run in one transaction:
+// their statements are applied one by one and the progress is recorded after each of them,
+// a failed migration is resumed after its last applied statement.
+type SchemaVersion struct {
+	Version uint `gorm:"primaryKey;autoIncrement:false"`
+	Name    string
+	// Dirty is the direction of the partially run script, up or down, empty once the migration is applied
+	Dirty string `gorm:"size:7;not null;default:''"`
+	// Statements is the number of statements of the dirty script applied
+	Statements  int       `gorm:"not null;default:0"`
+	AppliedTime time.Time `gorm:"autoCreateTime"`
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// LoadMigrations returns the embedded migrations of the driver ordered by version
+func LoadMigrations(driver string) ([]*Migration, error) {
+	if driver == "" {
+		driver = config.DatabaseDriverMySQL
+	}
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationsFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations of database driver %s", driver)
+	}
+
+	migrations := make(map[uint]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = migrationUp
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = migrationDown
+		default:
+			continue
+		}
+
+		versionStr, migrationName, ok := strings.Cut(strings.TrimSuffix(name, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", name)
+		}
+		version, err := strconv.ParseUint(versionStr, 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", name)
+		}
+		content, err := migrationsFS.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := migrations[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: migrationName}
+			migrations[uint(version)] = migration
+		}
+		if direction == migrationUp {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	list := make([]*Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", migration.Version)
+		}
+		list = append(list, migration)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Version < list[j].Version
+	})
+	for i, migration := range list {
+		if migration.Version != uint(i+1) {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+	}
+
+	return list, nil
+}
+
+// GetSchemaVersion returns the latest applied migration version, 0 if none is applied.
+// A partially applied or reverted migration is not counted.
+func GetSchemaVersion(db *gorm.DB) (uint, error) {
+	if !db.Migrator().HasTable(&SchemaVersion{}) {
+		return 0, nil
+	}
+
+	query := db.Model(&SchemaVersion{})
+	// the table of an older binary has no dirty column, all its migrations are applied
+	if db.Migrator().HasColumn(&SchemaVersion{}, "Dirty") {
+		query = query.Where("dirty = ?", "")
+	}
+	var version uint
+	err := query.Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// GetDirtySchemaVersion returns the migration left partially applied or reverted by a failure, nil if none
+func GetDirtySchemaVersion(db *gorm.DB) (*SchemaVersion, error) {
+	if !db.Migrator().HasTable(&SchemaVersion{}) || !db.Migrator().HasColumn(&SchemaVersion{}, "Dirty") {
+		return nil, nil
+	}
+
+	var versions []*SchemaVersion
+	if err := db.Where("dirty <> ?", "").Order("version").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	return versions[0], nil
+}
+
+// CheckSchemaVersion returns ErrSchemaTooNew if the schema is newer than the migrations of the binary,
+// and ErrSchemaOutdated if migrations are pending
+func CheckSchemaVersion(db *gorm.DB, driver string) error {
+	migrations, err := LoadMigrations(driver)
+	if err != nil {
+		return err
+	}
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	latest := uint(len(migrations))
+	if version > latest {
+		return fmt.Errorf("%w, schema version: %d, latest known version: %d", ErrSchemaTooNew, version, latest)
+	}
+	if version < latest {
+		return fmt.Errorf("%w, schema version: %d, latest version: %d", ErrSchemaOutdated, version, latest)
+	}
+	return nil
+}
+
+// Migrate applies all pending migrations
+func Migrate(db *gorm.DB, driver string) error {
+	migrations, err := LoadMigrations(driver)
+	if err != nil {
+		return err
+	}
+	return MigrateTo(db, driver, uint(len(migrations)))
+}
+
+// MigrateTo migrates the schema up or down to the target version
+func MigrateTo(db *gorm.DB, driver string, target uint) error {
+	migrations, err := LoadMigrations(driver)
+	if err != nil {
+		return err
+	}
+	return migrateTo(db, migrations, target, hasTransactionalDDL(driver))
+}
+
+// hasTransactionalDDL returns false for MySQL, it commits the transaction before and after each DDL statement
+func hasTransactionalDDL(driver string) bool {
+	return driver != "" && driver != config.DatabaseDriverMySQL
+}
+
+func migrateTo(db *gorm.DB, migrations []*Migration, target uint, transactional bool) error {
+	if target > uint(len(migrations)) {
+		return fmt.Errorf("unknown migration version: %d", target)
+	}
+
+	if err := db.Migrator().AutoMigrate(&SchemaVersion{}); err != nil {
+		return err
+	}
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > uint(len(migrations)) {
+		return fmt.Errorf("%w, schema version: %d, latest known version: %d", ErrSchemaTooNew, version, len(migrations))
+	}
+
+	// a migration interrupted by a failure is finished in its direction first
+	dirty, err := GetDirtySchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if dirty != nil {
+		if dirty.Version > uint(len(migrations)) {
+			return fmt.Errorf("%w, dirty schema version: %d, latest known version: %d", ErrSchemaTooNew, dirty.Version, len(migrations))
+		}
+		migration := migrations[dirty.Version-1]
+		if err := runMigration(db, migration, dirty.Dirty, dirty.Statements, transactional); err != nil {
+			return err
+		}
+		if version, err = GetSchemaVersion(db); err != nil {
+			return err
+		}
+	}
+
+	for version < target {
+		migration := migrations[version]
+		if err := runMigration(db, migration, migrationUp, 0, transactional); err != nil {
+			return err
+		}
+		version = migration.Version
+	}
+
+	for version > target {
+		migration := migrations[version-1]
+		if migration.Down == "" {
+			return fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+		}
+		if err := runMigration(db, migration, migrationDown, 0, transactional); err != nil {
+			return err
+		}
+		version = migration.Version - 1
+	}
+
+	return nil
+}
+
+// runMigration applies or reverts the migration, skipping the statements of its script applied already.
+// Without transactional DDL each statement commits on its own, the progress is recorded after each of them;
+// a statement committed right before a crash of the process is not recorded and it runs again on the next attempt.
+func runMigration(db *gorm.DB, migration *Migration, direction string, applied int, transactional bool) error {
+	script := migration.Up
+	action := "apply"
+	if direction == migrationDown {
+		script = migration.Down
+		action = "revert"
+	}
+	statements := splitStatements(script)
+
+	finish := func(db *gorm.DB) error {
+		if direction == migrationDown {
+			return db.Where("version = ?", migration.Version).Delete(&SchemaVersion{}).Error
+		}
+		return db.Save(&SchemaVersion{Version: migration.Version, Name: migration.Name, AppliedTime: time.Now()}).Error
+	}
+
+	var err error
+	if transactional {
+		err = db.Transaction(func(dbtx *gorm.DB) error {
+			for _, statement := range statements {
+				if err := dbtx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return finish(dbtx)
+		})
+	} else {
+		err = func() error {
+			for i := applied; i < len(statements); i++ {
+				if err := db.Exec(statements[i]).Error; err != nil {
+					return fmt.Errorf("statement %d: %w", i+1, err)
+				}
+				if i+1 == len(statements) {
+					break
+				}
+				progress := &SchemaVersion{
+					Version:     migration.Version,
+					Name:        migration.Name,
+					Dirty:       direction,
+					Statements:  i + 1,
+					AppliedTime: time.Now(),
+				}
+				if err := db.Save(progress).Error; err != nil {
+					return err
+				}
+			}
+			return finish(db)
+		}()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to %s migration %d_%s: %v", action, migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// splitStatements splits a script at the semicolons outside of quotes, comments and postgres dollar quotes,
+// the comments are dropped. Compound statements with inner semicolons, e.g. sqlite triggers, are not supported.
+func splitStatements(script string) []string {
+	var statements []string
+	var statement strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(statement.String()); s != "" {
+			statements = append(statements, s)
+		}
+		statement.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end
+				statement.WriteByte('\n')
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+				statement.WriteByte(' ')
+			}
+		case c == '\'' || c == '"' || c == '`':
+			// a doubled quote is an escaped quote, it continues the quoted text
+			end := i + 1
+			for end < len(script) && script[end] != c {
+				end++
+			}
+			statement.WriteString(script[i:min(end+1, len(script))])
+			i = end
+		case c == '$':
+			tag := dollarQuoteTag(script[i:])
+			if tag == "" {
+				statement.WriteByte(c)
+				continue
+			}
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				end = len(script) - i - len(tag)
+			} else {
+				end += len(tag)
+			}
+			statement.WriteString(script[i : i+len(tag)+end])
+			i += len(tag) + end - 1
+		case c == ';':
+			flush()
+		default:
+			statement.WriteByte(c)
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// dollarQuoteTag returns the $tag$ opening a postgres dollar quoted string at the start of s, empty if none
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+func newTestSQLiteDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestLoadMigrations(t *testing.T) {
+	var latest int
+	for i, driver := range []string{config.DatabaseDriverMySQL, config.DatabaseDriverPostgres, config.DatabaseDriverSQLite} {
+		migrations, err := LoadMigrations(driver)
+		if err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		if i > 0 && len(migrations) != latest {
+			t.Errorf("%s has %d migrations, expect %d", driver, len(migrations), latest)
+		}
+		latest = len(migrations)
+		for _, migration := range migrations {
+			// the initial schema is not reverted
+			if migration.Version > 1 && migration.Down == "" {
+				t.Errorf("%s: migration %d_%s has no down script", driver, migration.Version, migration.Name)
+			}
+		}
+	}
+	if _, err := LoadMigrations("oracle"); err == nil {
+		t.Error("expect error of unknown driver")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	driver := config.DatabaseDriverSQLite
+	migrations, err := LoadMigrations(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := uint(len(migrations))
+
+	if err := CheckSchemaVersion(db, driver); !errors.Is(err, ErrSchemaOutdated) {
+		t.Errorf("expect outdated schema, got %v", err)
+	}
+	if err := Migrate(db, driver); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := GetSchemaVersion(db); err != nil || version != latest {
+		t.Fatalf("unexpected schema version: %d, error: %v", version, err)
+	}
+	if err := CheckSchemaVersion(db, driver); err != nil {
+		t.Fatal(err)
+	}
+	// migrating again is a no-op
+	if err := Migrate(db, driver); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"btc_deposit_tx", "config", "wrapped_btc_deposit_tx"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("table %s is not created", table)
+		}
+	}
+
+	if err := MigrateTo(db, driver, 1); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := GetSchemaVersion(db); err != nil || version != 1 {
+		t.Fatalf("unexpected schema version: %d, error: %v", version, err)
+	}
+	if db.Migrator().HasColumn("wrapped_btc_deposit_tx", "log_index") {
+		t.Error("column log_index should be dropped")
+	}
+	if err := MigrateTo(db, driver, 0); err == nil || !db.Migrator().HasTable("wrapped_btc_deposit_tx") {
+		t.Errorf("expect the initial schema kept, error: %v", err)
+	}
+	if err := Migrate(db, driver); err != nil {
+		t.Fatal(err)
+	}
+
+	// a schema migrated by a newer binary is refused
+	if err := db.Create(&SchemaVersion{Version: latest + 1, Name: "future"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSchemaVersion(db, driver); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expect schema too new, got %v", err)
+	}
+	if err := Migrate(db, driver); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expect schema too new, got %v", err)
+	}
+}
+
+func TestMigrateSyncPointsToCheckpoints(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	driver := config.DatabaseDriverSQLite
+	if err := MigrateTo(db, driver, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUint64(db, "submitter/btc-sync-point", 2815059); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetUint64(db, "submitter/bnb-sync-point", 43000000); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(db, driver); err != nil {
+		t.Fatal(err)
+	}
+	for chain, height := range map[string]uint64{"btc": 2815059, "bnb": 43000000} {
+		checkpoint, err := getCheckpoint(db, chain)
+		if err != nil || checkpoint.Height != height {
+			t.Errorf("unexpected checkpoint of %s: %+v, error: %v", chain, checkpoint, err)
+		}
+	}
+
+	if err := MigrateTo(db, driver, 3); err != nil {
+		t.Fatal(err)
+	}
+	if height, err := GetUint64(db, "submitter/btc-sync-point"); err != nil || height != 2815059 {
+		t.Errorf("unexpected btc sync point: %d, error: %v", height, err)
+	}
+}
+
+// a deployment created from the former schema file is adopted as version 1 and upgraded
+func TestMigrateExistingDeployment(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	driver := config.DatabaseDriverSQLite
+	migrations, err := LoadMigrations(driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range splitStatements(migrations[0].Up) {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = db.Exec("INSERT INTO wrapped_btc_deposit_tx (chain, txid, block_time, proof, receipt, status, created_time) " +
+		"VALUES ('bnb', '0xa', '2024-01-01 00:00:00', '0x', '0x', 1, '2024-01-01 00:00:00')").Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(db, driver); err != nil {
+		t.Fatal(err)
+	}
+	repository, err := NewBNBRepository(db, "bnb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs, err := repository.GetUnhandledWrappedBTCDepositTxs(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx WrappedBTCDepositTx
+	if err := db.Where("txid = ?", "0xa").First(&tx).Error; err != nil {
+		t.Fatal(err)
+	}
+	if tx.Status != StatusSuccess || tx.LogIndex != 0 || tx.EventName != "StakeBTC2JoinStakePlan" || len(txs) != 0 {
+		t.Errorf("unexpected migrated deposit: %+v", tx)
+	}
+	// the other events of the transaction can be inserted
+	events := []*WrappedBTCDepositTx{{Chain: "bnb", Txid: "0xa", LogIndex: 1, EventName: "StakeBTC2JoinStakePlan"}}
+	if err := repository.InsertWrappedBTCDepositTxs(events, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// without transactional DDL, as in MySQL, a failed migration is resumed after its last applied statement
+func TestMigrateResume(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	migrations := []*Migration{{
+		Version: 1,
+		Name:    "tables",
+		Up:      "CREATE TABLE a (id int); CREATE TABLE b (id int); INSERT INTO c VALUES (1);",
+		Down:    "DROP TABLE b; DROP TABLE c; DROP TABLE a;",
+	}}
+
+	if err := migrateTo(db, migrations, 1, false); err == nil {
+		t.Fatal("expect the migration to fail")
+	}
+	if version, err := GetSchemaVersion(db); err != nil || version != 0 {
+		t.Fatalf("unexpected schema version: %d, error: %v", version, err)
+	}
+	dirty, err := GetDirtySchemaVersion(db)
+	if err != nil || dirty == nil || dirty.Dirty != migrationUp || dirty.Statements != 2 {
+		t.Fatalf("unexpected dirty schema version: %+v, error: %v", dirty, err)
+	}
+
+	// the tables created already are not created again
+	migrations[0].Up = "CREATE TABLE a (id int); CREATE TABLE b (id int); CREATE TABLE c (id int);"
+	if err := migrateTo(db, migrations, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := GetSchemaVersion(db); err != nil || version != 1 {
+		t.Fatalf("unexpected schema version: %d, error: %v", version, err)
+	}
+	if dirty, err := GetDirtySchemaVersion(db); err != nil || dirty != nil {
+		t.Fatalf("unexpected dirty schema version: %+v, error: %v", dirty, err)
+	}
+
+	// a failed revert is finished before migrating up again
+	if err := db.Exec("DROP TABLE c").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateTo(db, migrations, 0, false); err == nil {
+		t.Fatal("expect the revert to fail")
+	}
+	if version, err := GetSchemaVersion(db); err != nil || version != 0 || db.Migrator().HasTable("b") {
+		t.Fatalf("unexpected schema version: %d, error: %v", version, err)
+	}
+	migrations[0].Down = "DROP TABLE b; DROP TABLE IF EXISTS c; DROP TABLE a;"
+	if err := migrateTo(db, migrations, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := GetSchemaVersion(db); err != nil || version != 1 {
+		t.Fatalf("unexpected schema version: %d, error: %v", version, err)
+	}
+	for _, table := range []string{"a", "b", "c"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("table %s is not created", table)
+		}
+	}
+}
+
+// the schema version table of an older binary has no progress columns, its migrations are all applied
+func TestMigrateSchemaVersionWithoutProgress(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	driver := config.DatabaseDriverSQLite
+	if err := MigrateTo(db, driver, 2); err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{"dirty", "statements"} {
+		if err := db.Migrator().DropColumn(&SchemaVersion{}, column); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if version, err := GetSchemaVersion(db); err != nil || version != 2 {
+		t.Fatalf("unexpected schema version: %d, error: %v", version, err)
+	}
+	if err := Migrate(db, driver); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSchemaVersion(db, driver); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- a comment; with a semicolon
+CREATE TABLE a (name varchar(10) DEFAULT 'x;y', note text DEFAULT 'it''s'); /* block; comment */
+INSERT INTO a (name) VALUES ("--not a comment");CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;
+SELECT $1`
+	expect := []string{
+		"CREATE TABLE a (name varchar(10) DEFAULT 'x;y', note text DEFAULT 'it''s')",
+		`INSERT INTO a (name) VALUES ("--not a comment")`,
+		"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql",
+		"SELECT $1",
+	}
+	statements := splitStatements(script)
+	if len(statements) != len(expect) {
+		t.Fatalf("unexpected statements: %q", statements)
+	}
+	for i := range expect {
+		if statements[i] != expect[i] {
+			t.Errorf("statement %d: %q, expect: %q", i, statements[i], expect[i])
+		}
+	}
+}
@@ -1,4 +1,7 @@
-CREATE TABLE `btc_deposit_tx` (
+-- The schema of the former db/schema.sql, the tables of the deployments created from it are kept as they are.
+-- The initial schema is not reverted, the tables may hold the deposits of those deployments.
+
+CREATE TABLE IF NOT EXISTS `btc_deposit_tx` (
   `id` int NOT NULL AUTO_INCREMENT,
   `txid` varchar(256) NOT NULL,
   `agent_id` bigint DEFAULT 0, -- Forward compatibility
@@ -16,7 +19,7 @@ CREATE TABLE `btc_deposit_tx` (
   KEY (`status`)
 );
 
-CREATE TABLE `config` (
+CREATE TABLE IF NOT EXISTS `config` (
   `id` int NOT NULL AUTO_INCREMENT,
   `name` varchar(256) NOT NULL,
   `value` varchar(256) NOT NULL,
@@ -26,7 +29,7 @@ CREATE TABLE `config` (
   UNIQUE KEY (`name`)
 );
 
-CREATE TABLE `wrapped_btc_deposit_tx` (
+CREATE TABLE IF NOT EXISTS `wrapped_btc_deposit_tx` (
   `id` int NOT NULL AUTO_INCREMENT,
   `chain` varchar(31) NOT NULL,
   `txid` varchar(128) NOT NULL,
@@ -43,4 +46,4 @@ CREATE TABLE `wrapped_btc_deposit_tx` (
   PRIMARY KEY (`id`),
   UNIQUE KEY (`chain`,`txid`),
   KEY (`status`)
-);
\ No newline at end of file
+);
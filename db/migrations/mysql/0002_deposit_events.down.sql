@@ -0,0 +1,16 @@
+-- Fails while a transaction has more than one event, they cannot be kept in one row
+ALTER TABLE `wrapped_btc_deposit_tx`
+  DROP INDEX `plan_id`,
+  DROP INDEX `user_address`,
+  DROP INDEX `chain`,
+  ADD UNIQUE KEY `chain` (`chain`,`txid`),
+  DROP COLUMN `st_btc_amount`,
+  DROP COLUMN `stake_amount`,
+  DROP COLUMN `btc_contract_address`,
+  DROP COLUMN `user_address`,
+  DROP COLUMN `plan_id`,
+  DROP COLUMN `stake_index`,
+  DROP COLUMN `reason`,
+  DROP COLUMN `event_data`,
+  DROP COLUMN `event_name`,
+  DROP COLUMN `log_index`;
@@ -0,0 +1,16 @@
+-- One row per hub event of a BNB transaction, with the decoded fields of the event
+ALTER TABLE `wrapped_btc_deposit_tx`
+  ADD COLUMN `log_index` int NOT NULL DEFAULT 0 AFTER `txid`,
+  ADD COLUMN `event_name` varchar(64) NOT NULL DEFAULT 'StakeBTC2JoinStakePlan' AFTER `log_index`,
+  ADD COLUMN `event_data` TEXT AFTER `event_name`,
+  ADD COLUMN `reason` varchar(255) NOT NULL DEFAULT '' AFTER `status`,
+  ADD COLUMN `stake_index` bigint unsigned NOT NULL DEFAULT 0 AFTER `reason`,
+  ADD COLUMN `plan_id` bigint unsigned NOT NULL DEFAULT 0 AFTER `stake_index`,
+  ADD COLUMN `user_address` varchar(64) NOT NULL DEFAULT '' AFTER `plan_id`,
+  ADD COLUMN `btc_contract_address` varchar(64) NOT NULL DEFAULT '' AFTER `user_address`,
+  ADD COLUMN `stake_amount` varchar(80) NOT NULL DEFAULT '0' AFTER `btc_contract_address`,
+  ADD COLUMN `st_btc_amount` varchar(80) NOT NULL DEFAULT '0' AFTER `stake_amount`,
+  DROP INDEX `chain`,
+  ADD UNIQUE KEY `chain` (`chain`,`txid`,`log_index`),
+  ADD KEY `user_address` (`user_address`),
+  ADD KEY `plan_id` (`plan_id`);
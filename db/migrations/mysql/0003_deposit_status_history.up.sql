@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `deposit_status_history` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `chain` varchar(31) NOT NULL,
+  `txid` varchar(256) NOT NULL,
+  `log_index` int NOT NULL DEFAULT 0,
+  `old_status` tinyint NOT NULL,
+  `new_status` tinyint NOT NULL,
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `error` TEXT,
+  `actor` varchar(31) NOT NULL,
+  `created_time` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY (`chain`,`txid`)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `wrapped_btc_deposit_tx_archive`;
+DROP TABLE IF EXISTS `btc_deposit_tx_archive`;
@@ -0,0 +1,14 @@
+-- Admin actions of the API, e.g. retries, forced statuses, paused loops and rescans
+CREATE TABLE IF NOT EXISTS `admin_audit_log` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `actor` varchar(31) NOT NULL,
+  `identity` varchar(255) NOT NULL DEFAULT '',
+  `action` varchar(31) NOT NULL,
+  `chain` varchar(31) NOT NULL DEFAULT '',
+  `target` varchar(256) NOT NULL DEFAULT '',
+  `params` TEXT,
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `error` TEXT,
+  `created_time` datetime NOT NULL,
+  PRIMARY KEY (`id`)
+);
@@ -0,0 +1,15 @@
+-- Transactional outbox of the deposit lifecycle events, written with the deposit changes
+CREATE TABLE IF NOT EXISTS `deposit_event_outbox` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `chain` varchar(31) NOT NULL,
+  `txid` varchar(256) NOT NULL,
+  `log_index` int NOT NULL DEFAULT 0,
+  `type` varchar(31) NOT NULL,
+  `status` tinyint NOT NULL,
+  `reason` TEXT,
+  `data` TEXT,
+  `created_time` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY (`chain`,`id`),
+  KEY (`chain`,`txid`,`log_index`)
+);
@@ -0,0 +1,22 @@
+-- Webhook deliveries of the deposit events to the agent subscriptions, one row per delivered event and subscription
+CREATE TABLE IF NOT EXISTS `webhook_delivery` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `subscription` varchar(63) NOT NULL,
+  `url` varchar(1024) NOT NULL,
+  `event_id` int NOT NULL,
+  `chain` varchar(31) NOT NULL,
+  `txid` varchar(256) NOT NULL,
+  `log_index` int NOT NULL DEFAULT 0,
+  `event_type` varchar(31) NOT NULL,
+  `payload` TEXT,
+  `status` varchar(15) NOT NULL,
+  `attempts` int NOT NULL DEFAULT 0,
+  `response_code` int NOT NULL DEFAULT 0,
+  `error` TEXT,
+  `next_attempt_time` datetime NOT NULL,
+  `created_time` datetime NOT NULL,
+  `updated_time` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY (`status`,`next_attempt_time`),
+  KEY (`txid`)
+);
@@ -0,0 +1,3 @@
+-- Archived deposits are exported after the archive transaction commits, exported rows are kept
+ALTER TABLE `btc_deposit_tx_archive` ADD COLUMN `exported_time` datetime NULL;
+ALTER TABLE `wrapped_btc_deposit_tx_archive` ADD COLUMN `exported_time` datetime NULL;
@@ -0,0 +1,48 @@
+-- The initial schema, it is not reverted since the tables may hold the deposits of existing deployments.
+
+CREATE TABLE IF NOT EXISTS btc_deposit_tx (
+  id serial NOT NULL,
+  txid varchar(256) NOT NULL,
+  agent_id bigint DEFAULT 0, -- Forward compatibility
+  receiver_name varchar(256),
+  receiver_address varchar(256),
+  amount bigint,
+  status smallint NOT NULL,
+  height bigint,
+  block_hash varchar(256),
+  block_time timestamp NOT NULL,
+  updated_time timestamp,
+  created_time timestamp NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE (txid)
+);
+CREATE INDEX IF NOT EXISTS btc_deposit_tx_status ON btc_deposit_tx (status);
+
+CREATE TABLE IF NOT EXISTS config (
+  id serial NOT NULL,
+  name varchar(256) NOT NULL,
+  value varchar(256) NOT NULL,
+  created_time timestamp,
+  updated_time timestamp,
+  PRIMARY KEY (id),
+  UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS wrapped_btc_deposit_tx (
+  id serial NOT NULL,
+  chain varchar(31) NOT NULL,
+  txid varchar(128) NOT NULL,
+  height bigint,
+  block_hash varchar(256),
+  block_time timestamp NOT NULL,
+  proof text NOT NULL,
+  receipt text NOT NULL,
+  status smallint NOT NULL,
+
+  updated_time timestamp,
+  created_time timestamp NOT NULL,
+
+  PRIMARY KEY (id),
+  UNIQUE (chain, txid)
+);
+CREATE INDEX IF NOT EXISTS wrapped_btc_deposit_tx_status ON wrapped_btc_deposit_tx (status);
@@ -0,0 +1,17 @@
+-- One row per hub event of a BNB transaction, with the decoded fields of the event
+ALTER TABLE wrapped_btc_deposit_tx
+  ADD COLUMN IF NOT EXISTS log_index int NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS event_name varchar(64) NOT NULL DEFAULT 'StakeBTC2JoinStakePlan',
+  ADD COLUMN IF NOT EXISTS event_data text,
+  ADD COLUMN IF NOT EXISTS reason varchar(255) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS stake_index bigint NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS plan_id bigint NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS user_address varchar(64) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS btc_contract_address varchar(64) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS stake_amount varchar(80) NOT NULL DEFAULT '0',
+  ADD COLUMN IF NOT EXISTS st_btc_amount varchar(80) NOT NULL DEFAULT '0',
+  DROP CONSTRAINT IF EXISTS wrapped_btc_deposit_tx_chain_txid_key,
+  DROP CONSTRAINT IF EXISTS wrapped_btc_deposit_tx_chain_txid_log_index_key,
+  ADD CONSTRAINT wrapped_btc_deposit_tx_chain_txid_log_index_key UNIQUE (chain, txid, log_index);
+CREATE INDEX IF NOT EXISTS wrapped_btc_deposit_tx_user_address ON wrapped_btc_deposit_tx (user_address);
+CREATE INDEX IF NOT EXISTS wrapped_btc_deposit_tx_plan_id ON wrapped_btc_deposit_tx (plan_id);
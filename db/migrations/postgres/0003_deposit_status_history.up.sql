@@ -0,0 +1,14 @@
+CREATE TABLE deposit_status_history (
+  id serial NOT NULL,
+  chain varchar(31) NOT NULL,
+  txid varchar(256) NOT NULL,
+  log_index int NOT NULL DEFAULT 0,
+  old_status smallint NOT NULL,
+  new_status smallint NOT NULL,
+  reason varchar(255) NOT NULL DEFAULT '',
+  error text,
+  actor varchar(31) NOT NULL,
+  created_time timestamp NOT NULL,
+  PRIMARY KEY (id)
+);
+CREATE INDEX deposit_status_history_chain_txid ON deposit_status_history (chain, txid);
@@ -0,0 +1,48 @@
+-- Handled deposits moved out by the retention job, ids are the ids of the deposit tables
+CREATE TABLE btc_deposit_tx_archive (
+  id int NOT NULL,
+  txid varchar(256) NOT NULL,
+  agent_id bigint DEFAULT 0,
+  receiver_name varchar(256),
+  receiver_address varchar(256),
+  amount bigint,
+  status smallint NOT NULL,
+  height bigint,
+  block_hash varchar(256),
+  block_time timestamp NOT NULL,
+  updated_time timestamp,
+  created_time timestamp NOT NULL,
+  archived_time timestamp NOT NULL,
+  PRIMARY KEY (id)
+);
+CREATE INDEX btc_deposit_tx_archive_txid ON btc_deposit_tx_archive (txid);
+
+CREATE TABLE wrapped_btc_deposit_tx_archive (
+  id int NOT NULL,
+  chain varchar(31) NOT NULL,
+  txid varchar(128) NOT NULL,
+  log_index int NOT NULL DEFAULT 0,
+  event_name varchar(64) NOT NULL DEFAULT '',
+  event_data text,
+  height bigint,
+  block_hash varchar(256),
+  block_time timestamp NOT NULL,
+  proof text NOT NULL,
+  receipt text NOT NULL,
+  status smallint NOT NULL,
+  reason varchar(255) NOT NULL DEFAULT '',
+
+  stake_index bigint NOT NULL DEFAULT 0,
+  plan_id bigint NOT NULL DEFAULT 0,
+  user_address varchar(64) NOT NULL DEFAULT '',
+  btc_contract_address varchar(64) NOT NULL DEFAULT '',
+  stake_amount varchar(80) NOT NULL DEFAULT '0',
+  st_btc_amount varchar(80) NOT NULL DEFAULT '0',
+
+  updated_time timestamp,
+  created_time timestamp NOT NULL,
+  archived_time timestamp NOT NULL,
+
+  PRIMARY KEY (id)
+);
+CREATE INDEX wrapped_btc_deposit_tx_archive_chain_txid ON wrapped_btc_deposit_tx_archive (chain, txid);
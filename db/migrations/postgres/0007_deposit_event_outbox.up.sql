@@ -0,0 +1,15 @@
+-- Transactional outbox of the deposit lifecycle events, written with the deposit changes
+CREATE TABLE deposit_event_outbox (
+  id serial NOT NULL,
+  chain varchar(31) NOT NULL,
+  txid varchar(256) NOT NULL,
+  log_index int NOT NULL DEFAULT 0,
+  type varchar(31) NOT NULL,
+  status smallint NOT NULL,
+  reason text,
+  data text,
+  created_time timestamp NOT NULL,
+  PRIMARY KEY (id)
+);
+CREATE INDEX deposit_event_outbox_chain_id ON deposit_event_outbox (chain, id);
+CREATE INDEX deposit_event_outbox_chain_txid ON deposit_event_outbox (chain, txid, log_index);
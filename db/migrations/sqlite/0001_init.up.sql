@@ -0,0 +1,45 @@
+-- The initial schema, it is not reverted since the tables may hold the deposits of existing deployments.
+
+CREATE TABLE IF NOT EXISTS btc_deposit_tx (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  txid varchar(256) NOT NULL,
+  agent_id bigint DEFAULT 0, -- Forward compatibility
+  receiver_name varchar(256),
+  receiver_address varchar(256),
+  amount bigint,
+  status tinyint NOT NULL,
+  height bigint,
+  block_hash varchar(256),
+  block_time datetime NOT NULL,
+  updated_time datetime,
+  created_time datetime NOT NULL,
+  UNIQUE (txid)
+);
+CREATE INDEX IF NOT EXISTS btc_deposit_tx_status ON btc_deposit_tx (status);
+
+CREATE TABLE IF NOT EXISTS config (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  name varchar(256) NOT NULL,
+  value varchar(256) NOT NULL,
+  created_time datetime,
+  updated_time datetime,
+  UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS wrapped_btc_deposit_tx (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  chain varchar(31) NOT NULL,
+  txid varchar(128) NOT NULL,
+  height bigint,
+  block_hash varchar(256),
+  block_time datetime NOT NULL,
+  proof text NOT NULL,
+  receipt text NOT NULL,
+  status tinyint NOT NULL,
+
+  updated_time datetime,
+  created_time datetime NOT NULL,
+
+  UNIQUE (chain, txid)
+);
+CREATE INDEX IF NOT EXISTS wrapped_btc_deposit_tx_status ON wrapped_btc_deposit_tx (status);
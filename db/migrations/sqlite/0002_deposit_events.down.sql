@@ -0,0 +1,22 @@
+-- Fails while a transaction has more than one event, they cannot be kept in one row
+CREATE TABLE wrapped_btc_deposit_tx_old (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  chain varchar(31) NOT NULL,
+  txid varchar(128) NOT NULL,
+  height bigint,
+  block_hash varchar(256),
+  block_time datetime NOT NULL,
+  proof text NOT NULL,
+  receipt text NOT NULL,
+  status tinyint NOT NULL,
+
+  updated_time datetime,
+  created_time datetime NOT NULL,
+
+  UNIQUE (chain, txid)
+);
+INSERT INTO wrapped_btc_deposit_tx_old (id, chain, txid, height, block_hash, block_time, proof, receipt, status, updated_time, created_time)
+  SELECT id, chain, txid, height, block_hash, block_time, proof, receipt, status, updated_time, created_time FROM wrapped_btc_deposit_tx;
+DROP TABLE wrapped_btc_deposit_tx;
+ALTER TABLE wrapped_btc_deposit_tx_old RENAME TO wrapped_btc_deposit_tx;
+CREATE INDEX wrapped_btc_deposit_tx_status ON wrapped_btc_deposit_tx (status);
@@ -0,0 +1,36 @@
+-- One row per hub event of a BNB transaction, with the decoded fields of the event.
+-- SQLite cannot change a unique constraint, the table is rebuilt.
+CREATE TABLE wrapped_btc_deposit_tx_new (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  chain varchar(31) NOT NULL,
+  txid varchar(128) NOT NULL,
+  log_index int NOT NULL DEFAULT 0,
+  event_name varchar(64) NOT NULL DEFAULT 'StakeBTC2JoinStakePlan',
+  event_data text,
+  height bigint,
+  block_hash varchar(256),
+  block_time datetime NOT NULL,
+  proof text NOT NULL,
+  receipt text NOT NULL,
+  status tinyint NOT NULL,
+  reason varchar(255) NOT NULL DEFAULT '',
+
+  stake_index bigint NOT NULL DEFAULT 0,
+  plan_id bigint NOT NULL DEFAULT 0,
+  user_address varchar(64) NOT NULL DEFAULT '',
+  btc_contract_address varchar(64) NOT NULL DEFAULT '',
+  stake_amount varchar(80) NOT NULL DEFAULT '0',
+  st_btc_amount varchar(80) NOT NULL DEFAULT '0',
+
+  updated_time datetime,
+  created_time datetime NOT NULL,
+
+  UNIQUE (chain, txid, log_index)
+);
+INSERT INTO wrapped_btc_deposit_tx_new (id, chain, txid, height, block_hash, block_time, proof, receipt, status, updated_time, created_time)
+  SELECT id, chain, txid, height, block_hash, block_time, proof, receipt, status, updated_time, created_time FROM wrapped_btc_deposit_tx;
+DROP TABLE wrapped_btc_deposit_tx;
+ALTER TABLE wrapped_btc_deposit_tx_new RENAME TO wrapped_btc_deposit_tx;
+CREATE INDEX wrapped_btc_deposit_tx_status ON wrapped_btc_deposit_tx (status);
+CREATE INDEX wrapped_btc_deposit_tx_user_address ON wrapped_btc_deposit_tx (user_address);
+CREATE INDEX wrapped_btc_deposit_tx_plan_id ON wrapped_btc_deposit_tx (plan_id);
@@ -0,0 +1,13 @@
+CREATE TABLE deposit_status_history (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  chain varchar(31) NOT NULL,
+  txid varchar(256) NOT NULL,
+  log_index int NOT NULL DEFAULT 0,
+  old_status tinyint NOT NULL,
+  new_status tinyint NOT NULL,
+  reason varchar(255) NOT NULL DEFAULT '',
+  error text,
+  actor varchar(31) NOT NULL,
+  created_time datetime NOT NULL
+);
+CREATE INDEX deposit_status_history_chain_txid ON deposit_status_history (chain, txid);
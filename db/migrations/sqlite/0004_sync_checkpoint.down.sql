@@ -0,0 +1,3 @@
+INSERT INTO config (name, value, created_time, updated_time)
+SELECT 'submitter/' || chain || '-sync-point', CAST(height AS text), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP FROM sync_checkpoint WHERE chain IN ('btc', 'bnb');
+DROP TABLE IF EXISTS sync_checkpoint;
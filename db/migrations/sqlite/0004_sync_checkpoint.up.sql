@@ -0,0 +1,13 @@
+CREATE TABLE sync_checkpoint (
+  chain varchar(31) NOT NULL PRIMARY KEY,
+  height bigint NOT NULL,
+  block_hash varchar(256) NOT NULL DEFAULT '',
+  updated_time datetime NOT NULL
+);
+
+-- The sync points were heights in the config table
+INSERT INTO sync_checkpoint (chain, height, block_hash, updated_time)
+SELECT 'btc', CAST(value AS integer), '', CURRENT_TIMESTAMP FROM config WHERE name = 'submitter/btc-sync-point';
+INSERT INTO sync_checkpoint (chain, height, block_hash, updated_time)
+SELECT 'bnb', CAST(value AS integer), '', CURRENT_TIMESTAMP FROM config WHERE name = 'submitter/bnb-sync-point';
+DELETE FROM config WHERE name IN ('submitter/btc-sync-point', 'submitter/bnb-sync-point');
@@ -0,0 +1,13 @@
+-- Admin actions of the API, e.g. retries, forced statuses, paused loops and rescans
+CREATE TABLE admin_audit_log (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  actor varchar(31) NOT NULL,
+  identity varchar(255) NOT NULL DEFAULT '',
+  action varchar(31) NOT NULL,
+  chain varchar(31) NOT NULL DEFAULT '',
+  target varchar(256) NOT NULL DEFAULT '',
+  params text,
+  reason varchar(255) NOT NULL DEFAULT '',
+  error text,
+  created_time datetime NOT NULL
+);
@@ -0,0 +1,21 @@
+-- Webhook deliveries of the deposit events to the agent subscriptions, one row per delivered event and subscription
+CREATE TABLE webhook_delivery (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  subscription varchar(63) NOT NULL,
+  url varchar(1024) NOT NULL,
+  event_id int NOT NULL,
+  chain varchar(31) NOT NULL,
+  txid varchar(256) NOT NULL,
+  log_index int NOT NULL DEFAULT 0,
+  event_type varchar(31) NOT NULL,
+  payload text,
+  status varchar(15) NOT NULL,
+  attempts int NOT NULL DEFAULT 0,
+  response_code int NOT NULL DEFAULT 0,
+  error text,
+  next_attempt_time datetime NOT NULL,
+  created_time datetime NOT NULL,
+  updated_time datetime NOT NULL
+);
+CREATE INDEX webhook_delivery_status_next_attempt ON webhook_delivery (status, next_attempt_time);
+CREATE INDEX webhook_delivery_txid ON webhook_delivery (txid);
@@ -0,0 +1,2 @@
+ALTER TABLE wrapped_btc_deposit_tx_archive DROP COLUMN exported_time;
+ALTER TABLE btc_deposit_tx_archive DROP COLUMN exported_time;
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PendingDeposits summarizes the pending deposits inserted before a time
+type PendingDeposits struct {
+	Count             int64
+	OldestTxid        string
+	OldestCreatedTime time.Time
+}
+
+// getPendingDeposits summarizes the pending rows of the query, it is scoped to the table and chain by the caller
+func getPendingDeposits(query *gorm.DB, before time.Time) (*PendingDeposits, error) {
+	var pending PendingDeposits
+	query = query.Where("status = ? AND created_time < ?", StatusPending, before)
+	if err := query.Session(&gorm.Session{}).Count(&pending.Count).Error; err != nil {
+		return nil, err
+	}
+	if pending.Count == 0 {
+		return &pending, nil
+	}
+
+	var oldest struct {
+		Txid        string
+		CreatedTime time.Time
+	}
+	err := query.Session(&gorm.Session{}).Select("txid, created_time").Order("created_time, id").Take(&oldest).Error
+	if err != nil {
+		return nil, err
+	}
+	pending.OldestTxid = oldest.Txid
+	pending.OldestCreatedTime = oldest.CreatedTime
+
+	return &pending, nil
+}
+
+// addPendingDeposit adds the deposit to the summary of the in-memory repositories
+func (p *PendingDeposits) addPendingDeposit(txid string, createdTime time.Time) {
+	p.Count++
+	if p.OldestTxid == "" || createdTime.Before(p.OldestCreatedTime) {
+		p.OldestTxid = txid
+		p.OldestCreatedTime = createdTime
+	}
+}
@@ -0,0 +1,115 @@
+package db
+
+import "gorm.io/gorm"
+
+// DepositFilter selects the deposits of QueryBtcDepositTxs and QueryWrappedBTCDepositTxs,
+// zero fields do not filter
+type DepositFilter struct {
+	Txid string
+	// Address is the receiver address of BTC deposits or the user address of BNB deposits
+	Address string
+	// AgentId filters BTC deposits only
+	AgentId uint64
+	// PlanId filters BNB deposits only
+	PlanId     uint64
+	Status     *int
+	FromHeight uint64
+	ToHeight   uint64
+	// AfterId returns the deposits with a greater id, the last id of a page is the cursor of the next page
+	AfterId int
+	Limit   int
+}
+
+func (f DepositFilter) apply(query *gorm.DB, addressColumn string) *gorm.DB {
+	if f.Txid != "" {
+		query = query.Where("txid = ?", f.Txid)
+	}
+	if f.Address != "" {
+		query = query.Where(addressColumn+" = ?", f.Address)
+	}
+	if f.Status != nil {
+		query = query.Where("status = ?", *f.Status)
+	}
+	if f.FromHeight > 0 {
+		query = query.Where("height >= ?", f.FromHeight)
+	}
+	if f.ToHeight > 0 {
+		query = query.Where("height <= ?", f.ToHeight)
+	}
+	if f.AfterId > 0 {
+		query = query.Where("id > ?", f.AfterId)
+	}
+	return query.Order("id").Limit(f.Limit)
+}
+
+// match is the filter of the in-memory repositories, it has the same semantics as apply
+func (f DepositFilter) match(id int, txid string, address string, status int, height uint64) bool {
+	return (f.Txid == "" || txid == f.Txid) &&
+		(f.Address == "" || address == f.Address) &&
+		(f.Status == nil || status == *f.Status) &&
+		(f.FromHeight == 0 || height >= f.FromHeight) &&
+		(f.ToHeight == 0 || height <= f.ToHeight) &&
+		id > f.AfterId
+}
+
+func (r *BtcRepository) QueryBtcDepositTxs(filter DepositFilter) ([]*BtcDepositTx, error) {
+	query := r.db.Model(&BtcDepositTx{})
+	if filter.AgentId > 0 {
+		query = query.Where("agent_id = ?", filter.AgentId)
+	}
+
+	var txs []*BtcDepositTx
+	if err := filter.apply(query, "receiver_address").Find(&txs).Error; err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+func (r *BNBRepository) QueryWrappedBTCDepositTxs(filter DepositFilter) ([]*WrappedBTCDepositTx, error) {
+	query := r.db.Model(&WrappedBTCDepositTx{}).Where("chain = ?", r.chainName)
+	if filter.PlanId > 0 {
+		query = query.Where("plan_id = ?", filter.PlanId)
+	}
+
+	var txs []*WrappedBTCDepositTx
+	if err := filter.apply(query, "user_address").Find(&txs).Error; err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+func (r *MemoryBTCRepository) QueryBtcDepositTxs(filter DepositFilter) ([]*BtcDepositTx, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var txs []*BtcDepositTx
+	for _, tx := range r.txs {
+		if len(txs) == filter.Limit {
+			break
+		}
+		if (filter.AgentId == 0 || tx.AgentId == filter.AgentId) &&
+			filter.match(tx.Id, tx.Txid, tx.ReceiverAddress, tx.Status, tx.Height) {
+			copied := *tx
+			txs = append(txs, &copied)
+		}
+	}
+	return txs, nil
+}
+
+func (r *MemoryBNBRepository) QueryWrappedBTCDepositTxs(filter DepositFilter) ([]*WrappedBTCDepositTx, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	var txs []*WrappedBTCDepositTx
+	for _, tx := range r.txs {
+		if len(txs) == filter.Limit {
+			break
+		}
+		if tx.Chain == r.chainName && (filter.PlanId == 0 || tx.PlanId == filter.PlanId) &&
+			filter.match(tx.Id, tx.Txid, tx.UserAddress, tx.Status, tx.Height) {
+			copied := *tx
+			txs = append(txs, &copied)
+		}
+	}
+	return txs, nil
+}
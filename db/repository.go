@@ -2,56 +2,65 @@ package db
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
 
-const submitterBtcSyncPointKey = "submitter/btc-sync-point"
-const submitterBnbSyncPointKey = "submitter/bnb-sync-point"
-
 const (
 	StatusPending                    = 0
 	StatusSuccess                    = 1
 	StatusInvalid                    = 2
 	StatusReceiverIsNotBelongToAgent = 3
+	// StatusInvalidPlan the stake plan of the event is rejected, see the reason of the row
+	StatusInvalidPlan = 4
 )
 
+// StatusNames are the names of the statuses in the metrics and the admin API
+var StatusNames = map[int]string{
+	StatusPending:                    "pending",
+	StatusSuccess:                    "success",
+	StatusInvalid:                    "invalid",
+	StatusReceiverIsNotBelongToAgent: "receiver_not_agent",
+	StatusInvalidPlan:                "invalid_plan",
+}
+
 const (
 	BatchHandleBtcDepositTxsNum = 50
 )
 
 type BtcRepository struct {
 	db *gorm.DB
-
-	syncPointKey string
 }
 
-func NewBTCRepository() (IBTCRepository, error) {
-	if DB == nil {
-		return nil, errors.New("DB is not initialized yet")
-	}
-
-	mysqlDb := &BtcRepository{
-		db:           DB,
-		syncPointKey: submitterBtcSyncPointKey,
+func NewBTCRepository(db *gorm.DB) (IBTCRepository, error) {
+	if db == nil {
+		return nil, errors.New("db handle is nil")
 	}
 
-	return mysqlDb, nil
+	return &BtcRepository{
+		db: db,
+	}, nil
 }
 
-func (r *BtcRepository) UpdateSyncPoint(height uint64) error {
-	return SetUint64(r.db, r.syncPointKey, height)
+func (r *BtcRepository) UpdateSyncPoint(height uint64, blockHash string) error {
+	return upsertCheckpoint(r.db, btcChain, height, blockHash)
 }
 
 func (r *BtcRepository) GetSyncPoint() (uint64, error) {
-	return GetUint64(r.db, r.syncPointKey)
+	checkpoint, err := r.GetCheckpoint()
+	if err != nil {
+		return 0, err
+	}
+
+	return checkpoint.Height, nil
 }
 
-func (r *BtcRepository) InsertBtcDepositTxs(txs []*BtcDepositTx) (err error) {
-	if len(txs) == 0 {
-		return nil
-	}
+func (r *BtcRepository) GetCheckpoint() (*SyncCheckpoint, error) {
+	return getCheckpoint(r.db, btcChain)
+}
 
+func (r *BtcRepository) InsertBtcDepositTxs(txs []*BtcDepositTx, height uint64, blockHash string) error {
 	return r.db.Transaction(func(dbtx *gorm.DB) error {
 		for _, tx := range txs {
 			//check tx is already exist
@@ -65,18 +74,23 @@ func (r *BtcRepository) InsertBtcDepositTxs(txs []*BtcDepositTx) (err error) {
 			if err != nil {
 				return err
 			}
+			if err := dbtx.Create(NewBtcDepositEvent(tx, EventDetected)).Error; err != nil {
+				return err
+			}
 		}
 
-		return nil
+		return upsertCheckpoint(dbtx, btcChain, height, blockHash)
 	})
 }
 
 func (r *BtcRepository) GetUnhandledBtcDepositTxs(lorenzoBTCTip uint64) ([]*BtcDepositTx, error) {
 	var txs []*BtcDepositTx
 	// BTC block timestamp is not strictly increasing.
+	// Heights must not underflow, postgres rejects integers out of the bigint range.
 	err := r.db.Model(&BtcDepositTx{}).
 		Where("status=? AND (amount<? OR (amount<? AND height<=?) OR (amount<? AND height<=?) OR (amount<? AND height<=?) OR (amount>=? AND height<=?))",
-			StatusPending, Dep0Amount, Dep1Amount, lorenzoBTCTip-1, Dep2Amount, lorenzoBTCTip-2, Dep3Amount, lorenzoBTCTip-3, Dep3Amount, lorenzoBTCTip-4).
+			StatusPending, uint64(Dep0Amount), uint64(Dep1Amount), heightBefore(lorenzoBTCTip, 1), uint64(Dep2Amount), heightBefore(lorenzoBTCTip, 2),
+			uint64(Dep3Amount), heightBefore(lorenzoBTCTip, 3), uint64(Dep3Amount), heightBefore(lorenzoBTCTip, 4)).
 		Order("height ASC").Limit(BatchHandleBtcDepositTxsNum).Find(&txs).Error
 	if err != nil {
 		return nil, err
@@ -85,17 +99,93 @@ func (r *BtcRepository) GetUnhandledBtcDepositTxs(lorenzoBTCTip uint64) ([]*BtcD
 	return txs, nil
 }
 
-func (r *BtcRepository) UpdateTxStatus(txid string, status int) error {
-	result := r.db.Model(&BtcDepositTx{}).Where("txid = ?", txid).Update("status", status)
-	return result.Error
+func (r *BtcRepository) UpdateTxStatus(txid string, status int, change StatusChange) error {
+	return r.db.Transaction(func(dbtx *gorm.DB) error {
+		var tx BtcDepositTx
+		err := dbtx.Model(&BtcDepositTx{}).Where("txid = ?", txid).First(&tx).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return checkArchived(dbtx.Model(&ArchivedBtcDepositTx{}).Where("txid = ?", txid))
+			}
+			return err
+		}
+
+		if err := change.check(tx.Status); err != nil {
+			return err
+		}
+		query := dbtx.Model(&BtcDepositTx{}).Where("txid = ?", txid)
+		if change.Check != nil {
+			query = query.Where("status = ?", tx.Status)
+		}
+		result := query.Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if change.Check != nil && result.RowsAffected == 0 {
+			return ErrStatusChanged
+		}
+		if err := dbtx.Create(newDepositStatusHistory(btcChain, txid, 0, tx.Status, status, change)).Error; err != nil {
+			return err
+		}
+		if event := statusEvent(NewBtcDepositEvent(&tx, ""), status, change); event != nil {
+			return dbtx.Create(event).Error
+		}
+		return nil
+	})
+}
+
+func (r *BtcRepository) GetStatusHistory(txid string) ([]*DepositStatusHistory, error) {
+	return getStatusHistory(r.db, btcChain, txid)
+}
+
+func (r *BtcRepository) GetStatusHistoryAfter(afterId int, since time.Time, limit int) ([]*DepositStatusHistory, error) {
+	return getStatusHistoryAfter(r.db, btcChain, afterId, since, limit)
+}
+
+func (r *BtcRepository) RecordDepositEvent(event *DepositEvent) error {
+	return recordDepositEvent(r.db, event)
+}
+
+func (r *BtcRepository) GetDepositEvents(afterId int, limit int) ([]*DepositEvent, error) {
+	return getDepositEvents(r.db, btcChain, afterId, limit)
+}
+
+func (r *BtcRepository) ReadDepositEvents(position int, limit int, visibleBefore time.Time) ([]*DepositEvent, int, error) {
+	return readDepositEvents(r.db, btcChain, position, limit, visibleBefore)
+}
+
+func (r *BtcRepository) GetEventCursor(publisher string) (int, error) {
+	return getEventCursor(r.db, publisher, btcChain)
+}
+
+func (r *BtcRepository) SetEventCursor(publisher string, id int) error {
+	return setEventCursor(r.db, publisher, btcChain, id)
+}
+
+func (r *BtcRepository) GetPendingDeposits(before time.Time) (*PendingDeposits, error) {
+	return getPendingDeposits(r.db.Model(&BtcDepositTx{}), before)
+}
+
+// heightBefore returns height-n, or 0 if the height is lower than n
+func heightBefore(height uint64, n uint64) uint64 {
+	if height < n {
+		return 0
+	}
+	return height - n
 }
 
+// hasDepositTxByTxid reports whether the deposit is known, archived deposits included so a rescan does not add them again
 func (r *BtcRepository) hasDepositTxByTxid(dbtx *gorm.DB, txid string) (bool, error) {
-	var count int64
-	result := dbtx.Model(&BtcDepositTx{}).Where("txid = ?", txid).Count(&count)
-	if result.Error != nil {
-		return false, result.Error
+	for _, model := range []interface{}{&BtcDepositTx{}, &ArchivedBtcDepositTx{}} {
+		var count int64
+		result := dbtx.Model(model).Where("txid = ?", txid).Count(&count)
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if count > 0 {
+			return true, nil
+		}
 	}
 
-	return count > 0, nil
+	return false, nil
 }
@@ -1,46 +1,895 @@
 package db
 
-//import (
-//	"fmt"
-//	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/config"
-//	"testing"
-//	"time"
-//)
-//
-//func TestMysqlDB_GetSyncPoint(t *testing.T) {
-//	cfg, err := config.NewConfig("../.testnet/sample-config.yml")
-//	if err != nil {
-//		t.Fatal(err)
-//	}
-//
-//	mysqlDB, err := NewBTCRepository(cfg.Database)
-//	if err != nil {
-//		t.Fatal(err)
-//	}
-//
-//	syncPoint, err := mysqlDB.GetSyncPoint()
-//	if err != nil {
-//		t.Fatal(err)
-//	}
-//	fmt.Println(syncPoint)
-//
-//	if err := mysqlDB.UpdateSyncPoint(2811751); err != nil {
-//		t.Fatal(err)
-//	}
-//
-//	if _, err := mysqlDB.GetUnhandledBtcDepositTxs(); err != nil {
-//		t.Fatal(err)
-//	}
-//
-//	depositTx := &BtcDepositTx{
-//		Txid:      "0x12ab",
-//		BlockTime: time.Now(),
-//	}
-//	if err := mysqlDB.InsertBtcDepositTxs([]*BtcDepositTx{depositTx}); err != nil {
-//		t.Fatal(err)
-//	}
-//	// Duplicate insert
-//	if err := mysqlDB.InsertBtcDepositTxs([]*BtcDepositTx{depositTx}); err != nil {
-//		t.Fatal(err)
-//	}
-//}
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+// Every repository backend must pass the conformance tests below
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	testBTCRepositoryConformance(t, func(t *testing.T) IBTCRepository {
+		return NewMemoryBTCRepository()
+	})
+	testBNBRepositoryConformance(t, func(t *testing.T, chainName string) IBNBRepository {
+		return NewMemoryBNBRepository(chainName)
+	})
+	testAuditRepositoryConformance(t, func(t *testing.T) IAuditRepository {
+		return NewMemoryAuditRepository()
+	})
+	testWebhookRepositoryConformance(t, func(t *testing.T) IWebhookRepository {
+		return NewMemoryWebhookRepository()
+	})
+}
+
+func TestSQLiteRepositoryConformance(t *testing.T) {
+	newTestDB := func(t *testing.T) *gorm.DB {
+		db := newTestSQLiteDB(t)
+		if err := Migrate(db, config.DatabaseDriverSQLite); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	testBTCRepositoryConformance(t, func(t *testing.T) IBTCRepository {
+		repository, err := NewBTCRepository(newTestDB(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repository
+	})
+	testBNBRepositoryConformance(t, func(t *testing.T, chainName string) IBNBRepository {
+		repository, err := NewBNBRepository(newTestDB(t), chainName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repository
+	})
+	testAuditRepositoryConformance(t, func(t *testing.T) IAuditRepository {
+		repository, err := NewAuditRepository(newTestDB(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repository
+	})
+	testWebhookRepositoryConformance(t, func(t *testing.T) IWebhookRepository {
+		repository, err := NewWebhookRepository(newTestDB(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repository
+	})
+}
+
+// The ids of the outbox are allocated before the transactions commit, a reader must not skip the ones
+// committed after a greater id
+func TestReadDepositEventsWaitsForUncommittedIds(t *testing.T) {
+	handle := newTestSQLiteDB(t)
+	if err := Migrate(handle, config.DatabaseDriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+	repository, err := NewBTCRepository(handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(id int, chain string, createdTime time.Time) {
+		t.Helper()
+		event := &DepositEvent{Id: id, Chain: chain, Txid: fmt.Sprint(id), Type: EventDetected, CreatedTime: createdTime}
+		if err := handle.Create(event).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(position int, visibleBefore time.Time) ([]int, int) {
+		t.Helper()
+		events, position, err := repository.ReadDepositEvents(position, 100, visibleBefore)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, event := range events {
+			ids = append(ids, event.Id)
+		}
+		return ids, position
+	}
+
+	now := time.Now()
+	visibleBefore := now.Add(-time.Minute)
+	// 2 is still in a transaction while 1 and 3 are committed
+	commit(1, btcChain, now)
+	commit(3, btcChain, now)
+	if ids, position := read(0, visibleBefore); fmt.Sprint(ids) != "[1]" || position != 1 {
+		t.Fatalf("unexpected events: %v, position: %d", ids, position)
+	}
+	// the bnb events only move the position
+	commit(2, "bnb", now)
+	commit(4, btcChain, now)
+	if ids, position := read(1, visibleBefore); fmt.Sprint(ids) != "[3 4]" || position != 4 {
+		t.Fatalf("unexpected events: %v, position: %d", ids, position)
+	}
+	// 5 was rolled back long ago
+	commit(6, btcChain, now.Add(-2*time.Minute))
+	if ids, position := read(4, visibleBefore); fmt.Sprint(ids) != "[6]" || position != 6 {
+		t.Fatalf("unexpected events: %v, position: %d", ids, position)
+	}
+}
+
+func testBTCRepositoryConformance(t *testing.T, newRepository func(t *testing.T) IBTCRepository) {
+	newTx := func(txid string, amount uint64, height uint64) *BtcDepositTx {
+		return &BtcDepositTx{
+			Txid:            txid,
+			Amount:          amount,
+			Height:          height,
+			ReceiverAddress: "0xabc",
+			BlockHash:       "0x" + txid,
+			BlockTime:       time.Unix(1700000000, 0),
+		}
+	}
+
+	t.Run("btc sync point", func(t *testing.T) {
+		r := newRepository(t)
+		if height, err := r.GetSyncPoint(); err != nil || height != 0 {
+			t.Fatalf("unexpected sync point: %d, error: %v", height, err)
+		}
+		for _, height := range []uint64{100, 101} {
+			if err := r.UpdateSyncPoint(height, fmt.Sprintf("0x%d", height)); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := r.GetSyncPoint(); err != nil || got != height {
+				t.Fatalf("unexpected sync point: %d, expect: %d, error: %v", got, height, err)
+			}
+		}
+
+		// inserting deposits advances the checkpoint, even if the block has none
+		if err := r.InsertBtcDepositTxs(nil, 102, "0x102"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.InsertBtcDepositTxs([]*BtcDepositTx{newTx("a", 1, 103)}, 103, "0x103"); err != nil {
+			t.Fatal(err)
+		}
+		checkpoint, err := r.GetCheckpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if checkpoint.Chain != "btc" || checkpoint.Height != 103 || checkpoint.BlockHash != "0x103" || checkpoint.UpdatedTime.IsZero() {
+			t.Fatalf("unexpected checkpoint: %+v", checkpoint)
+		}
+	})
+
+	t.Run("btc tiered pending selection", func(t *testing.T) {
+		r := newRepository(t)
+		tip := uint64(100)
+		txs := []*BtcDepositTx{
+			newTx("t0", uint64(Dep0Amount)-1, tip),
+			newTx("t1", uint64(Dep1Amount)-1, tip-1),
+			newTx("t1-unconfirmed", uint64(Dep1Amount)-1, tip),
+			newTx("t2", uint64(Dep2Amount)-1, tip-2),
+			newTx("t2-unconfirmed", uint64(Dep2Amount)-1, tip-1),
+			newTx("t3", uint64(Dep3Amount)-1, tip-3),
+			newTx("t3-unconfirmed", uint64(Dep3Amount)-1, tip-2),
+			newTx("t4", uint64(Dep3Amount), tip-4),
+			newTx("t4-unconfirmed", uint64(Dep3Amount), tip-3),
+		}
+		if err := r.InsertBtcDepositTxs(txs, tip, "0xtip"); err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range txs {
+			if tx.Id == 0 {
+				t.Errorf("id of %s is not set", tx.Txid)
+			}
+		}
+
+		got, err := r.GetUnhandledBtcDepositTxs(tip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []string{"t4", "t3", "t2", "t1", "t0"}
+		if len(got) != len(expect) {
+			t.Fatalf("expect %d txs, got %d", len(expect), len(got))
+		}
+		for i, tx := range got {
+			if tx.Txid != expect[i] {
+				t.Errorf("unexpected tx at %d: %s, expect: %s", i, tx.Txid, expect[i])
+			}
+			if tx.ReceiverAddress != "0xabc" || tx.BlockHash != "0x"+tx.Txid || !tx.BlockTime.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("fields of %s are not persisted: %+v", tx.Txid, tx)
+			}
+		}
+
+		// a low tip must not underflow
+		if _, err := r.GetUnhandledBtcDepositTxs(1); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("btc dedupe and status", func(t *testing.T) {
+		r := newRepository(t)
+		if err := r.InsertBtcDepositTxs([]*BtcDepositTx{newTx("a", 1, 10), newTx("a", 2, 11), newTx("b", 1, 12)}, 13, "0x13"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.InsertBtcDepositTxs([]*BtcDepositTx{newTx("a", 3, 13)}, 13, "0x13"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.GetUnhandledBtcDepositTxs(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 || got[0].Txid != "a" || got[0].Amount != 1 || got[0].Height != 10 {
+			t.Fatalf("duplicated txid should be ignored, got %d txs", len(got))
+		}
+
+		// returned txs are copies
+		got[1].Status = StatusSuccess
+		if err := r.UpdateTxStatus("a", StatusInvalid, StatusChange{Actor: ActorRelayer, Error: "invalid proof"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.UpdateTxStatus("unknown", StatusInvalid, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+		got, err = r.GetUnhandledBtcDepositTxs(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Txid != "b" {
+			t.Fatalf("unexpected pending txs: %d", len(got))
+		}
+
+		if err := r.UpdateTxStatus("a", StatusPending, StatusChange{Actor: ActorCLI, Reason: "retry"}); err != nil {
+			t.Fatal(err)
+		}
+		history, err := r.GetStatusHistory("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 {
+			t.Fatalf("expect 2 status transitions, got %d", len(history))
+		}
+		if h := history[0]; h.OldStatus != StatusPending || h.NewStatus != StatusInvalid || h.Actor != ActorRelayer ||
+			h.Error != "invalid proof" || h.CreatedTime.IsZero() {
+			t.Errorf("unexpected status transition: %+v", h)
+		}
+		if h := history[1]; h.OldStatus != StatusInvalid || h.NewStatus != StatusPending || h.Actor != ActorCLI || h.Reason != "retry" {
+			t.Errorf("unexpected status transition: %+v", h)
+		}
+		if history, err := r.GetStatusHistory("unknown"); err != nil || len(history) != 0 {
+			t.Errorf("unexpected status history of unknown tx: %d, error: %v", len(history), err)
+		}
+
+		// a rejected checked change leaves the deposit untouched
+		errRejected := errors.New("rejected")
+		change := StatusChange{Actor: ActorAPI, Check: func(status int) error {
+			if status == StatusPending {
+				return errRejected
+			}
+			return nil
+		}}
+		if err := r.UpdateTxStatus("a", StatusSuccess, change); !errors.Is(err, errRejected) {
+			t.Fatalf("expect the change rejected, got: %v", err)
+		}
+		if history, err := r.GetStatusHistory("a"); err != nil || len(history) != 2 {
+			t.Errorf("unexpected status history after the rejected change: %d, error: %v", len(history), err)
+		}
+	})
+
+	t.Run("btc pending deposits and history after", func(t *testing.T) {
+		r := newRepository(t)
+		start := time.Now().Add(-time.Second)
+		if err := r.InsertBtcDepositTxs([]*BtcDepositTx{newTx("a", 1, 10), newTx("b", 1, 11), newTx("c", 1, 12)}, 13, "0x13"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.UpdateTxStatus("c", StatusSuccess, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+
+		pending, err := r.GetPendingDeposits(start)
+		if err != nil || pending.Count != 0 {
+			t.Fatalf("unexpected pending deposits before insert: %+v, error: %v", pending, err)
+		}
+		pending, err = r.GetPendingDeposits(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pending.Count != 2 || (pending.OldestTxid != "a" && pending.OldestTxid != "b") || pending.OldestCreatedTime.IsZero() {
+			t.Fatalf("unexpected pending deposits: %+v", pending)
+		}
+
+		if err := r.UpdateTxStatus("a", StatusInvalid, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+		history, err := r.GetStatusHistoryAfter(0, start, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].Txid != "c" {
+			t.Fatalf("unexpected first status transition: %d records", len(history))
+		}
+		history, err = r.GetStatusHistoryAfter(history[0].Id, start, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].Txid != "a" || history[0].NewStatus != StatusInvalid {
+			t.Fatalf("unexpected status transitions after the first: %d records", len(history))
+		}
+		if history, err := r.GetStatusHistoryAfter(0, time.Now().Add(time.Hour), 10); err != nil || len(history) != 0 {
+			t.Fatalf("unexpected status transitions since the future: %d, error: %v", len(history), err)
+		}
+	})
+
+	t.Run("btc query", func(t *testing.T) {
+		r := newRepository(t)
+		txs := []*BtcDepositTx{newTx("a", 1, 10), newTx("b", 1, 11), newTx("c", 1, 12), newTx("d", 1, 13)}
+		txs[1].ReceiverAddress = "0xdef"
+		txs[2].AgentId = 7
+		if err := r.InsertBtcDepositTxs(txs, 13, "0x13"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.UpdateTxStatus("d", StatusSuccess, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+
+		query := func(filter DepositFilter) string {
+			if filter.Limit == 0 {
+				filter.Limit = 10
+			}
+			got, err := r.QueryBtcDepositTxs(filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var list []string
+			for _, tx := range got {
+				list = append(list, tx.Txid)
+			}
+			return fmt.Sprint(list)
+		}
+		success := StatusSuccess
+		cases := []struct {
+			filter DepositFilter
+			expect string
+		}{
+			{DepositFilter{}, "[a b c d]"},
+			{DepositFilter{Txid: "b"}, "[b]"},
+			{DepositFilter{Address: "0xdef"}, "[b]"},
+			{DepositFilter{AgentId: 7}, "[c]"},
+			{DepositFilter{Status: &success}, "[d]"},
+			{DepositFilter{FromHeight: 11, ToHeight: 12}, "[b c]"},
+			{DepositFilter{Limit: 2}, "[a b]"},
+		}
+		for _, c := range cases {
+			if got := query(c.filter); got != c.expect {
+				t.Errorf("unexpected deposits of %+v: %s, expect: %s", c.filter, got, c.expect)
+			}
+		}
+
+		page, err := r.QueryBtcDepositTxs(DepositFilter{Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := query(DepositFilter{AfterId: page[1].Id, Limit: 2}); got != "[c d]" {
+			t.Errorf("unexpected next page: %s", got)
+		}
+	})
+
+	t.Run("btc events", func(t *testing.T) {
+		r := newRepository(t)
+		if err := r.InsertBtcDepositTxs([]*BtcDepositTx{newTx("a", 1000, 10), newTx("b", 1000, 11)}, 20, "0x20"); err != nil {
+			t.Fatal(err)
+		}
+		// the duplicate is not detected again
+		if err := r.InsertBtcDepositTxs([]*BtcDepositTx{newTx("a", 1000, 10)}, 21, "0x21"); err != nil {
+			t.Fatal(err)
+		}
+		for _, eventType := range []string{EventConfirmed, EventConfirmed, EventSubmitted, EventConfirmed} {
+			if err := r.RecordDepositEvent(&DepositEvent{Chain: btcChain, Txid: "a", Type: eventType}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.UpdateTxStatus("a", StatusSuccess, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.UpdateTxStatus("b", StatusInvalid, StatusChange{Actor: ActorRelayer, Error: "bad proof"}); err != nil {
+			t.Fatal(err)
+		}
+		// a retried deposit has no event until it is confirmed again
+		if err := r.UpdateTxStatus("b", StatusPending, StatusChange{Actor: ActorAPI}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.RecordDepositEvent(&DepositEvent{Chain: btcChain, Txid: "b", Type: EventConfirmed}); err != nil {
+			t.Fatal(err)
+		}
+
+		events, err := r.GetDepositEvents(0, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range events {
+			got = append(got, fmt.Sprintf("%s:%s:%d:%s", e.Txid, e.Type, e.Status, e.Reason))
+		}
+		expect := []string{"a:detected:0:", "b:detected:0:", "a:confirmed:0:", "a:submitted:0:", "a:minted:1:",
+			"b:invalid:2:bad proof", "b:confirmed:0:"}
+		if fmt.Sprint(got) != fmt.Sprint(expect) {
+			t.Fatalf("unexpected events: %v, expect: %v", got, expect)
+		}
+		if !strings.Contains(events[0].Data, `"amount":1000`) || events[0].Chain != btcChain {
+			t.Errorf("unexpected event data: %s", events[0].Data)
+		}
+		if page, err := r.GetDepositEvents(events[4].Id, 1); err != nil || len(page) != 1 || page[0].Id != events[5].Id {
+			t.Fatalf("unexpected events after %d: %v, error: %v", events[4].Id, page, err)
+		}
+
+		if cursor, err := r.GetEventCursor("sse"); err != nil || cursor != 0 {
+			t.Fatalf("unexpected cursor: %d, error: %v", cursor, err)
+		}
+		if err := r.SetEventCursor("sse", events[2].Id); err != nil {
+			t.Fatal(err)
+		}
+		if cursor, err := r.GetEventCursor("sse"); err != nil || cursor != events[2].Id {
+			t.Fatalf("unexpected cursor: %d, error: %v", cursor, err)
+		}
+		if read, position, err := r.ReadDepositEvents(events[4].Id, 100, time.Now()); err != nil || len(read) != 2 || position != events[6].Id {
+			t.Fatalf("unexpected events after %d: %v, position: %d, error: %v", events[4].Id, read, position, err)
+		}
+	})
+
+	t.Run("btc batch limit", func(t *testing.T) {
+		r := newRepository(t)
+		var txs []*BtcDepositTx
+		for i := 0; i < BatchHandleBtcDepositTxsNum+10; i++ {
+			txs = append(txs, newTx(fmt.Sprintf("tx%d", i), 1, uint64(i)))
+		}
+		if err := r.InsertBtcDepositTxs(txs, 100, "0x100"); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.GetUnhandledBtcDepositTxs(1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != BatchHandleBtcDepositTxsNum || got[0].Txid != "tx0" {
+			t.Fatalf("expect the first %d txs, got %d", BatchHandleBtcDepositTxsNum, len(got))
+		}
+	})
+}
+
+func testBNBRepositoryConformance(t *testing.T, newRepository func(t *testing.T, chainName string) IBNBRepository) {
+	chainName := "bnb"
+	newTx := func(txid string, logIndex uint, height uint64) *WrappedBTCDepositTx {
+		return &WrappedBTCDepositTx{
+			Chain:     chainName,
+			Txid:      txid,
+			LogIndex:  logIndex,
+			EventName: "StakeBTC2JoinStakePlan",
+			Height:    height,
+			BlockTime: time.Unix(1700000000, 0),
+			Receipt:   "0x01",
+			Proof:     "0x02",
+			PlanId:    1,
+		}
+	}
+	txids := func(txs []*WrappedBTCDepositTx) []string {
+		var list []string
+		for _, tx := range txs {
+			list = append(list, fmt.Sprintf("%s/%d", tx.Txid, tx.LogIndex))
+		}
+		return list
+	}
+
+	t.Run("bnb sync point", func(t *testing.T) {
+		r := newRepository(t, chainName)
+		if height, err := r.GetSyncPoint(); err != nil || height != 0 {
+			t.Fatalf("unexpected sync point: %d, error: %v", height, err)
+		}
+		if err := r.UpdateSyncPoint(100, ""); err != nil {
+			t.Fatal(err)
+		}
+		if height, err := r.GetSyncPoint(); err != nil || height != 100 {
+			t.Fatalf("unexpected sync point: %d, error: %v", height, err)
+		}
+		if err := r.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{newTx("0xa", 0, 101)}, 110, "0x110"); err != nil {
+			t.Fatal(err)
+		}
+		checkpoint, err := r.GetCheckpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if checkpoint.Chain != chainName || checkpoint.Height != 110 || checkpoint.BlockHash != "0x110" {
+			t.Fatalf("unexpected checkpoint: %+v", checkpoint)
+		}
+	})
+
+	t.Run("bnb dedupe and pending selection", func(t *testing.T) {
+		r := newRepository(t, chainName)
+		if err := r.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{
+			newTx("0xb", 0, 12), newTx("0xa", 1, 10), newTx("0xa", 0, 10), newTx("0xc", 0, 20),
+		}, 20, "0x20"); err != nil {
+			t.Fatal(err)
+		}
+		duplicated := newTx("0xa", 0, 11)
+		if err := r.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{duplicated}, 21, "0x21"); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.GetUnhandledWrappedBTCDepositTxs(15)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []string{"0xa/0", "0xa/1", "0xb/0"}
+		if fmt.Sprint(txids(got)) != fmt.Sprint(expect) {
+			t.Fatalf("unexpected pending txs: %v, expect: %v", txids(got), expect)
+		}
+		if got[0].Height != 10 || got[0].Receipt != "0x01" || got[0].Proof != "0x02" || got[0].PlanId != 1 {
+			t.Errorf("fields are not persisted: %+v", got[0])
+		}
+	})
+
+	t.Run("bnb pending deposits", func(t *testing.T) {
+		r := newRepository(t, chainName)
+		if err := r.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{newTx("0xa", 0, 10), newTx("0xa", 1, 10), newTx("0xb", 0, 11)}, 20, "0x20"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.MarkSuccess("0xb", 0, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+
+		pending, err := r.GetPendingDeposits(time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pending.Count != 2 || pending.OldestTxid != "0xa" {
+			t.Fatalf("unexpected pending deposits: %+v", pending)
+		}
+		history, err := r.GetStatusHistoryAfter(0, time.Now().Add(-time.Minute), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].Txid != "0xb" || history[0].Chain != chainName {
+			t.Fatalf("unexpected status transitions: %d records", len(history))
+		}
+	})
+
+	t.Run("bnb query", func(t *testing.T) {
+		r := newRepository(t, chainName)
+		txs := []*WrappedBTCDepositTx{newTx("0xa", 0, 10), newTx("0xa", 1, 10), newTx("0xb", 0, 11)}
+		txs[1].PlanId = 2
+		txs[2].UserAddress = "0xuser"
+		if err := r.InsertWrappedBTCDepositTxs(txs, 20, "0x20"); err != nil {
+			t.Fatal(err)
+		}
+
+		cases := []struct {
+			filter DepositFilter
+			expect []string
+		}{
+			{DepositFilter{Limit: 10}, []string{"0xa/0", "0xa/1", "0xb/0"}},
+			{DepositFilter{Txid: "0xa", Limit: 10}, []string{"0xa/0", "0xa/1"}},
+			{DepositFilter{PlanId: 2, Limit: 10}, []string{"0xa/1"}},
+			{DepositFilter{Address: "0xuser", Limit: 10}, []string{"0xb/0"}},
+			{DepositFilter{FromHeight: 11, Limit: 10}, []string{"0xb/0"}},
+		}
+		for _, c := range cases {
+			got, err := r.QueryWrappedBTCDepositTxs(c.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(txids(got)) != fmt.Sprint(c.expect) {
+				t.Errorf("unexpected deposits of %+v: %v, expect: %v", c.filter, txids(got), c.expect)
+			}
+		}
+	})
+
+	t.Run("bnb status updates", func(t *testing.T) {
+		r := newRepository(t, chainName)
+		if err := r.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{
+			newTx("0xa", 0, 10), newTx("0xa", 1, 10), newTx("0xb", 0, 11), newTx("0xb", 1, 11), newTx("0xc", 0, 12),
+		}, 20, "0x20"); err != nil {
+			t.Fatal(err)
+		}
+
+		// only the event of the log index is updated
+		if err := r.MarkSuccess("0xa", 0, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.MarkInvalidPlan("0xb", 1, StatusChange{Actor: ActorRelayer, Reason: "plan not found"}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.GetUnhandledWrappedBTCDepositTxs(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []string{"0xa/1", "0xb/0", "0xc/0"}
+		if fmt.Sprint(txids(got)) != fmt.Sprint(expect) {
+			t.Fatalf("unexpected pending txs: %v, expect: %v", txids(got), expect)
+		}
+
+		if err := r.MarkSuccess("0xa", 1, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+		// the rejected sibling event keeps its status and reason
+		if err := r.MarkInvalid("0xb", 0, StatusChange{Actor: ActorRelayer, Error: "out of gas"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.MarkInvalid("0xc", 0, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := r.GetUnhandledWrappedBTCDepositTxs(100); err != nil || len(got) != 0 {
+			t.Fatalf("unexpected pending txs: %v, error: %v", txids(got), err)
+		}
+
+		history, err := r.GetStatusHistory("0xa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 || history[0].LogIndex != 0 || history[1].LogIndex != 1 || history[1].NewStatus != StatusSuccess {
+			t.Errorf("unexpected status history of 0xa: %d records", len(history))
+		}
+		history, err = r.GetStatusHistory("0xb")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectHistory := []string{"1:0->4:plan not found:", "0:0->2::out of gas"}
+		var gotHistory []string
+		for _, h := range history {
+			gotHistory = append(gotHistory, fmt.Sprintf("%d:%d->%d:%s:%s", h.LogIndex, h.OldStatus, h.NewStatus, h.Reason, h.Error))
+		}
+		if fmt.Sprint(gotHistory) != fmt.Sprint(expectHistory) {
+			t.Errorf("unexpected status history of 0xb: %v, expect: %v", gotHistory, expectHistory)
+		}
+
+		// a checked change is applied to no event if one of them is rejected
+		errRejected := errors.New("rejected")
+		if err := r.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{newTx("0xd", 0, 13), newTx("0xd", 1, 13)}, 21, "0x21"); err != nil {
+			t.Fatal(err)
+		}
+		for _, logIndex := range []uint{0, 1} {
+			if err := r.MarkSuccess("0xd", logIndex, StatusChange{Actor: ActorRelayer}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		index := uint(0)
+		if err := r.UpdateStatus("0xd", &index, StatusPending, StatusChange{Actor: ActorAPI}); err != nil {
+			t.Fatal(err)
+		}
+		change := StatusChange{Actor: ActorAPI, Check: func(status int) error {
+			if status == StatusSuccess {
+				return errRejected
+			}
+			return nil
+		}}
+		if err := r.UpdateStatus("0xd", nil, StatusInvalid, change); !errors.Is(err, errRejected) {
+			t.Fatalf("expect the change rejected, got: %v", err)
+		}
+		if got, err := r.GetUnhandledWrappedBTCDepositTxs(100); err != nil || fmt.Sprint(txids(got)) != "[0xd/0]" {
+			t.Errorf("unexpected pending txs after the rejected change: %v, error: %v", txids(got), err)
+		}
+	})
+
+	t.Run("bnb events", func(t *testing.T) {
+		r := newRepository(t, chainName)
+		if err := r.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{newTx("0xa", 0, 10), newTx("0xa", 1, 10)}, 20, "0x20"); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.RecordDepositEvent(&DepositEvent{Chain: chainName, Txid: "0xa", LogIndex: 1, Type: EventConfirmed}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.MarkInvalidPlan("0xa", 1, StatusChange{Actor: ActorRelayer, Reason: "plan not found"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.MarkSuccess("0xa", 0, StatusChange{Actor: ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+
+		events, err := r.GetDepositEvents(0, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range events {
+			got = append(got, fmt.Sprintf("%d:%s:%s", e.LogIndex, e.Type, e.Reason))
+		}
+		expect := []string{"0:detected:", "1:detected:", "1:confirmed:", "1:invalid:plan not found", "0:minted:"}
+		if fmt.Sprint(got) != fmt.Sprint(expect) {
+			t.Fatalf("unexpected events: %v, expect: %v", got, expect)
+		}
+		if !strings.Contains(events[0].Data, `"planId":1`) || events[0].Chain != chainName {
+			t.Errorf("unexpected event data: %s", events[0].Data)
+		}
+		if err := r.SetEventCursor("sse", events[1].Id); err != nil {
+			t.Fatal(err)
+		}
+		if cursor, err := r.GetEventCursor("sse"); err != nil || cursor != events[1].Id {
+			t.Fatalf("unexpected cursor: %d, error: %v", cursor, err)
+		}
+	})
+
+	t.Run("bnb retry", func(t *testing.T) {
+		r := newRepository(t, chainName)
+		if err := r.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{newTx("0xa", 0, 10), newTx("0xa", 1, 10)}, 20, "0x20"); err != nil {
+			t.Fatal(err)
+		}
+		for _, logIndex := range []uint{0, 1} {
+			if err := r.MarkInvalid("0xa", logIndex, StatusChange{Actor: ActorRelayer, Error: "out of gas"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		logIndex := uint(1)
+		if err := r.UpdateStatus("0xa", &logIndex, StatusPending, StatusChange{Actor: ActorAPI, Reason: "retry"}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.GetUnhandledWrappedBTCDepositTxs(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(txids(got)) != "[0xa/1]" || got[0].Reason != "retry" {
+			t.Fatalf("unexpected pending txs: %v", txids(got))
+		}
+		history, err := r.GetStatusHistory("0xa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := history[len(history)-1]; last.Actor != ActorAPI || last.OldStatus != StatusInvalid || last.NewStatus != StatusPending {
+			t.Errorf("unexpected last transition: %+v", last)
+		}
+	})
+
+	t.Run("bnb hub addresses", func(t *testing.T) {
+		r := newRepository(t, chainName)
+		if addresses, err := r.GetHubAddresses(); err != nil || len(addresses) != 0 {
+			t.Fatalf("unexpected hub addresses: %v, error: %v", addresses, err)
+		}
+		if err := r.UpdateSyncPoint(100, "0x100"); err != nil {
+			t.Fatal(err)
+		}
+		for _, address := range []*HubAddress{{Address: "0x01"}, {Address: "0x02", EffectiveFrom: 150, Approximate: true}} {
+			if rolledBack, err := r.AddHubAddress(address); err != nil || rolledBack {
+				t.Fatalf("unexpected rollback: %v, error: %v", rolledBack, err)
+			}
+		}
+		// an address effective from a scanned height rolls the checkpoint back and replaces the later addresses
+		if rolledBack, err := r.AddHubAddress(&HubAddress{Address: "0x03", EffectiveFrom: 90, Approximate: true}); err != nil || !rolledBack {
+			t.Fatalf("expect rollback, got: %v, error: %v", rolledBack, err)
+		}
+		if checkpoint, err := r.GetCheckpoint(); err != nil || checkpoint.Height != 89 || checkpoint.BlockHash != "" {
+			t.Fatalf("unexpected checkpoint: %+v, error: %v", checkpoint, err)
+		}
+		addresses, err := r.GetHubAddresses()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, address := range addresses {
+			got = append(got, fmt.Sprintf("%s:%d:%v", address.Address, address.EffectiveFrom, address.Approximate))
+		}
+		if fmt.Sprint(got) != "[0x01:0:false 0x03:90:true]" {
+			t.Errorf("unexpected hub addresses: %v", got)
+		}
+	})
+}
+
+func testAuditRepositoryConformance(t *testing.T, newRepository func(t *testing.T) IAuditRepository) {
+	t.Run("audit logs", func(t *testing.T) {
+		r := newRepository(t)
+		for _, action := range []string{"retry", "mark", "pause"} {
+			log := &AdminAuditLog{Actor: ActorAPI, Identity: "ops", Action: action, Chain: "btc", Target: "a", Reason: "test"}
+			if err := r.InsertAuditLog(log); err != nil {
+				t.Fatal(err)
+			}
+			if log.Id == 0 {
+				t.Fatal("the id of the audit log is not set")
+			}
+		}
+
+		logs, err := r.GetAuditLogs(0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(logs) != 2 || logs[0].Action != "retry" || logs[1].Action != "mark" || logs[0].Identity != "ops" || logs[0].CreatedTime.IsZero() {
+			t.Fatalf("unexpected audit logs: %d records", len(logs))
+		}
+		logs, err = r.GetAuditLogs(logs[1].Id, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(logs) != 1 || logs[0].Action != "pause" {
+			t.Fatalf("unexpected audit logs after the cursor: %d records", len(logs))
+		}
+
+		log := &AdminAuditLog{Actor: ActorAPI, Action: "rescan", Error: AuditOutcomeUnknown}
+		if err := r.InsertAuditLog(log); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CompleteAuditLog(log.Id, "rescan failed"); err != nil {
+			t.Fatal(err)
+		}
+		if logs, err = r.GetAuditLogs(log.Id-1, 1); err != nil || len(logs) != 1 || logs[0].Error != "rescan failed" {
+			t.Fatalf("unexpected completed audit log: %v, error: %v", logs, err)
+		}
+	})
+}
+
+func testWebhookRepositoryConformance(t *testing.T, newRepository func(t *testing.T) IWebhookRepository) {
+	t.Run("webhook deliveries", func(t *testing.T) {
+		r := newRepository(t)
+		now := time.Now().Truncate(time.Second)
+		deliveries := []*WebhookDelivery{
+			{Subscription: "partner", URL: "https://a", EventId: 1, Chain: "btc", Txid: "a", EventType: EventMinted,
+				Payload: "{}", Status: WebhookDeliveryPending, NextAttemptTime: now.Add(time.Minute)},
+			{Subscription: "partner", URL: "https://a", EventId: 2, Chain: "btc", Txid: "b", EventType: EventMinted,
+				Payload: "{}", Status: WebhookDeliveryPending, NextAttemptTime: now},
+		}
+		if err := r.InsertWebhookDeliveries(deliveries); err != nil {
+			t.Fatal(err)
+		}
+		if deliveries[0].Id == 0 || deliveries[1].Id == 0 {
+			t.Fatal("the ids of the deliveries are not set")
+		}
+
+		due, err := r.GetDueWebhookDeliveries("partner", now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 1 || due[0].Txid != "b" {
+			t.Fatalf("unexpected due deliveries: %v", due)
+		}
+		due, err = r.GetDueWebhookDeliveries("partner", now.Add(time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 2 || due[0].Txid != "b" || due[1].Txid != "a" {
+			t.Fatalf("unexpected due deliveries: %v", due)
+		}
+
+		delivered := due[0]
+		delivered.Status = WebhookDeliveryDelivered
+		delivered.Attempts = 1
+		delivered.ResponseCode = 200
+		if err := r.UpdateWebhookDelivery(delivered); err != nil {
+			t.Fatal(err)
+		}
+		if due, err := r.GetDueWebhookDeliveries("partner", now.Add(time.Hour), 10); err != nil || len(due) != 1 || due[0].Txid != "a" {
+			t.Fatalf("unexpected due deliveries: %v, error: %v", due, err)
+		}
+
+		stored, err := r.GetWebhookDeliveries("b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stored) != 1 || stored[0].Status != WebhookDeliveryDelivered || stored[0].Attempts != 1 ||
+			stored[0].ResponseCode != 200 || stored[0].Payload != "{}" {
+			t.Fatalf("unexpected deliveries: %+v", stored)
+		}
+	})
+
+	t.Run("webhook deliveries by subscription", func(t *testing.T) {
+		r := newRepository(t)
+		now := time.Now().Truncate(time.Second)
+		var deliveries []*WebhookDelivery
+		for i, subscription := range []string{"partner", "audit", "partner", "slow"} {
+			deliveries = append(deliveries, &WebhookDelivery{Subscription: subscription, URL: "https://a", EventId: i + 1,
+				Chain: "btc", Txid: fmt.Sprint(i), EventType: EventMinted, Payload: "{}", Status: WebhookDeliveryPending,
+				NextAttemptTime: now.Add(time.Duration(i) * time.Second)})
+		}
+		if err := r.InsertWebhookDeliveries(deliveries); err != nil {
+			t.Fatal(err)
+		}
+
+		subscriptions, err := r.GetDueWebhookSubscriptions(now.Add(2 * time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(subscriptions) != "[audit partner]" {
+			t.Fatalf("unexpected due subscriptions: %v", subscriptions)
+		}
+		due, err := r.GetDueWebhookDeliveries("partner", now.Add(time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 2 || due[0].Txid != "0" || due[1].Txid != "2" {
+			t.Fatalf("unexpected due deliveries: %v", due)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrDepositStripped is returned when the status of a deposit stripped by the retention job is changed,
+// its receipt and proof are gone so it cannot be submitted again
+var ErrDepositStripped = errors.New("the receipt and proof of the deposit were stripped by the retention job")
+
+// ErrDepositArchived is returned when the status of a deposit moved into the archive by the retention job is changed,
+// archived deposits are read only, e.g. an archived invalid deposit cannot be retried
+var ErrDepositArchived = errors.New("the deposit was archived by the retention job")
+
+// stripped reports whether the receipt and proof of the deposit were emptied by the retention job
+func (tx *WrappedBTCDepositTx) stripped() bool {
+	return tx.Status == StatusSuccess && tx.Receipt == "" && tx.Proof == ""
+}
+
+// checkArchived returns ErrDepositArchived if the query of an archive table finds the deposit
+func checkArchived(query *gorm.DB) error {
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDepositArchived
+	}
+	return nil
+}
+
+// ArchivedBtcDepositTx is a BTC deposit moved out of btc_deposit_tx by the retention job,
+// the row is kept after it is exported so its txid is not synced again
+type ArchivedBtcDepositTx struct {
+	BtcDepositTx
+	ArchivedTime time.Time
+	ExportedTime *time.Time `json:"-"`
+}
+
+func (ArchivedBtcDepositTx) TableName() string {
+	return "btc_deposit_tx_archive"
+}
+
+// ArchivedWrappedBTCDepositTx is a hub event moved out of wrapped_btc_deposit_tx by the retention job,
+// the event data, receipt and proof are emptied once it is exported
+type ArchivedWrappedBTCDepositTx struct {
+	WrappedBTCDepositTx
+	ArchivedTime time.Time
+	ExportedTime *time.Time `json:"-"`
+}
+
+func (ArchivedWrappedBTCDepositTx) TableName() string {
+	return "wrapped_btc_deposit_tx_archive"
+}
+
+// DepositExporter writes archived deposits out of the database, e.g. into files.
+// It is called outside of any transaction and the deposits are marked exported after it returns,
+// a batch is exported again if the marking fails, so the export must be idempotent.
+type DepositExporter interface {
+	ExportBtcDepositTxs(txs []*ArchivedBtcDepositTx) error
+	ExportWrappedBTCDepositTxs(txs []*ArchivedWrappedBTCDepositTx) error
+}
+
+// RetentionRepository removes old handled deposits in small batches,
+// pending deposits are never touched so the relayers are not affected
+type RetentionRepository struct {
+	db *gorm.DB
+}
+
+func NewRetentionRepository(db *gorm.DB) (IRetentionRepository, error) {
+	if db == nil {
+		return nil, errors.New("db handle is nil")
+	}
+
+	return &RetentionRepository{
+		db: db,
+	}, nil
+}
+
+func (r *RetentionRepository) StripWrappedBTCDepositProofs(before time.Time, limit int) (int, error) {
+	var ids []int
+	err := r.db.Model(&WrappedBTCDepositTx{}).
+		Where("status = ? AND updated_time < ? AND (receipt <> '' OR proof <> '')", StatusSuccess, before).
+		Order("id").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	// UpdateColumns keeps the updated time, the rows are archived by their age later
+	// the status is checked again, the deposit may have been set back to pending since
+	result := r.db.Model(&WrappedBTCDepositTx{}).Where("id IN ? AND status = ?", ids, StatusSuccess).
+		UpdateColumns(map[string]interface{}{"receipt": "", "proof": ""})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+func (r *RetentionRepository) ArchiveBtcDepositTxs(before time.Time, limit int) (int, error) {
+	var txs []*BtcDepositTx
+	err := r.db.Transaction(func(dbtx *gorm.DB) error {
+		err := dbtx.Model(&BtcDepositTx{}).Where("status <> ? AND updated_time < ?", StatusPending, before).
+			Order("id").Limit(limit).Find(&txs).Error
+		if err != nil || len(txs) == 0 {
+			return err
+		}
+
+		now := time.Now()
+		archived := make([]*ArchivedBtcDepositTx, 0, len(txs))
+		for _, tx := range txs {
+			archived = append(archived, &ArchivedBtcDepositTx{BtcDepositTx: *tx, ArchivedTime: now})
+		}
+		if err := dbtx.Create(archived).Error; err != nil {
+			return err
+		}
+		return dbtx.Where("id IN ?", depositIds(txs, func(tx *BtcDepositTx) int { return tx.Id })).
+			Delete(&BtcDepositTx{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(txs), nil
+}
+
+func (r *RetentionRepository) ArchiveWrappedBTCDepositTxs(before time.Time, limit int) (int, error) {
+	var txs []*WrappedBTCDepositTx
+	err := r.db.Transaction(func(dbtx *gorm.DB) error {
+		// the events of a tx are kept together until none of them is pending
+		pending := dbtx.Model(&WrappedBTCDepositTx{}).Select("txid").Where("status = ?", StatusPending)
+		err := dbtx.Model(&WrappedBTCDepositTx{}).
+			Where("status <> ? AND updated_time < ? AND txid NOT IN (?)", StatusPending, before, pending).
+			Order("id").Limit(limit).Find(&txs).Error
+		if err != nil || len(txs) == 0 {
+			return err
+		}
+
+		now := time.Now()
+		archived := make([]*ArchivedWrappedBTCDepositTx, 0, len(txs))
+		for _, tx := range txs {
+			archived = append(archived, &ArchivedWrappedBTCDepositTx{WrappedBTCDepositTx: *tx, ArchivedTime: now})
+		}
+		if err := dbtx.Create(archived).Error; err != nil {
+			return err
+		}
+		return dbtx.Where("id IN ?", depositIds(txs, func(tx *WrappedBTCDepositTx) int { return tx.Id })).
+			Delete(&WrappedBTCDepositTx{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(txs), nil
+}
+
+func (r *RetentionRepository) ExportArchivedBtcDepositTxs(limit int, exporter DepositExporter) (int, error) {
+	var txs []*ArchivedBtcDepositTx
+	err := r.db.Where("exported_time IS NULL").Order("id").Limit(limit).Find(&txs).Error
+	if err != nil || len(txs) == 0 {
+		return 0, err
+	}
+
+	if err := exporter.ExportBtcDepositTxs(txs); err != nil {
+		return 0, err
+	}
+	err = r.db.Model(&ArchivedBtcDepositTx{}).
+		Where("id IN ?", depositIds(txs, func(tx *ArchivedBtcDepositTx) int { return tx.Id })).
+		UpdateColumn("exported_time", time.Now()).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return len(txs), nil
+}
+
+func (r *RetentionRepository) ExportArchivedWrappedBTCDepositTxs(limit int, exporter DepositExporter) (int, error) {
+	var txs []*ArchivedWrappedBTCDepositTx
+	err := r.db.Where("exported_time IS NULL").Order("id").Limit(limit).Find(&txs).Error
+	if err != nil || len(txs) == 0 {
+		return 0, err
+	}
+
+	if err := exporter.ExportWrappedBTCDepositTxs(txs); err != nil {
+		return 0, err
+	}
+	// the exported bulk columns are emptied, the rest of the row keeps the event from being synced again
+	err = r.db.Model(&ArchivedWrappedBTCDepositTx{}).
+		Where("id IN ?", depositIds(txs, func(tx *ArchivedWrappedBTCDepositTx) int { return tx.Id })).
+		UpdateColumns(map[string]interface{}{"event_data": "", "receipt": "", "proof": "", "exported_time": time.Now()}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return len(txs), nil
+}
+
+func depositIds[T any](txs []T, id func(T) int) []int {
+	ids := make([]int, 0, len(txs))
+	for _, tx := range txs {
+		ids = append(ids, id(tx))
+	}
+	return ids
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+func TestRetention(t *testing.T) {
+	handle := newTestSQLiteDB(t)
+	if err := Migrate(handle, config.DatabaseDriverSQLite); err != nil {
+		t.Fatal(err)
+	}
+	btcRepository, _ := NewBTCRepository(handle)
+	bnbRepository, _ := NewBNBRepository(handle, "bnb")
+	r, err := NewRetentionRepository(handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blockTime := time.Unix(1700000000, 0)
+	btcTxs := []*BtcDepositTx{
+		{Txid: "success", Amount: 1, Height: 10, BlockTime: blockTime},
+		{Txid: "invalid", Amount: 1, Height: 10, BlockTime: blockTime},
+		{Txid: "pending", Amount: 1, Height: 10, BlockTime: blockTime},
+	}
+	if err := btcRepository.InsertBtcDepositTxs(btcTxs, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	newWrappedTx := func(txid string, logIndex uint) *WrappedBTCDepositTx {
+		return &WrappedBTCDepositTx{Chain: "bnb", Txid: txid, LogIndex: logIndex, BlockTime: blockTime, Receipt: "0x01", Proof: "0x02"}
+	}
+	wrappedTxs := []*WrappedBTCDepositTx{
+		newWrappedTx("0xa", 0), newWrappedTx("0xb", 0), newWrappedTx("0xb", 1), newWrappedTx("0xc", 0),
+	}
+	if err := bnbRepository.InsertWrappedBTCDepositTxs(wrappedTxs, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	change := StatusChange{Actor: ActorRelayer}
+	_ = btcRepository.UpdateTxStatus("success", StatusSuccess, change)
+	_ = btcRepository.UpdateTxStatus("invalid", StatusInvalid, change)
+	_ = bnbRepository.MarkSuccess("0xa", 0, change)
+	_ = bnbRepository.MarkInvalidPlan("0xb", 0, change)
+	_ = bnbRepository.MarkSuccess("0xc", 0, change)
+	// the deposits were updated a day ago, except 0xc
+	dayAgo := time.Now().Add(-24 * time.Hour)
+	handle.Model(&BtcDepositTx{}).Where("1 = 1").UpdateColumn("updated_time", dayAgo)
+	handle.Model(&WrappedBTCDepositTx{}).Where("txid <> ?", "0xc").UpdateColumn("updated_time", dayAgo)
+	before := time.Now().Add(-time.Hour)
+
+	// strip proofs in batches of one
+	for i, expect := range []int{1, 0} {
+		if n, err := r.StripWrappedBTCDepositProofs(before, 1); err != nil || n != expect {
+			t.Fatalf("unexpected stripped deposits of run %d: %d, error: %v", i, n, err)
+		}
+	}
+	var stripped WrappedBTCDepositTx
+	handle.Where("txid = ?", "0xa").First(&stripped)
+	if stripped.Receipt != "" || stripped.Proof != "" || stripped.UpdatedTime.After(before) {
+		t.Errorf("unexpected stripped deposit: %+v", stripped)
+	}
+
+	// the status of a stripped deposit cannot be changed, it cannot be submitted again
+	retry := StatusChange{Actor: ActorAPI, Check: func(int) error { return nil }}
+	if err := bnbRepository.UpdateStatus("0xa", nil, StatusPending, retry); !errors.Is(err, ErrDepositStripped) {
+		t.Fatalf("expect the change of a stripped deposit rejected, got: %v", err)
+	}
+
+	if n, err := r.ArchiveBtcDepositTxs(before, 10); err != nil || n != 2 {
+		t.Fatalf("unexpected archived BTC deposits: %d, error: %v", n, err)
+	}
+	var archivedBtc []*ArchivedBtcDepositTx
+	handle.Order("id").Find(&archivedBtc)
+	if len(archivedBtc) != 2 || archivedBtc[0].Txid != "success" || archivedBtc[0].Status != StatusSuccess || archivedBtc[0].ArchivedTime.IsZero() {
+		t.Fatalf("unexpected archived BTC deposits: %+v", archivedBtc)
+	}
+	if pending, _ := btcRepository.GetUnhandledBtcDepositTxs(100); len(pending) != 1 || pending[0].Txid != "pending" {
+		t.Fatalf("pending BTC deposit should be kept: %+v", pending)
+	}
+	// an archived invalid deposit cannot be retried
+	if err := btcRepository.UpdateTxStatus("invalid", StatusPending, retry); !errors.Is(err, ErrDepositArchived) {
+		t.Fatalf("expect the retry of an archived deposit rejected, got: %v", err)
+	}
+
+	// 0xb has a pending event and 0xc is recent, both are kept
+	if n, err := r.ArchiveWrappedBTCDepositTxs(before, 10); err != nil || n != 1 {
+		t.Fatalf("unexpected archived BNB deposits: %d, error: %v", n, err)
+	}
+	var archivedWrapped []*ArchivedWrappedBTCDepositTx
+	handle.Find(&archivedWrapped)
+	if len(archivedWrapped) != 1 || archivedWrapped[0].Txid != "0xa" || archivedWrapped[0].Id != wrappedTxs[0].Id {
+		t.Fatalf("unexpected archived BNB deposits: %+v", archivedWrapped)
+	}
+	var count int64
+	handle.Model(&WrappedBTCDepositTx{}).Count(&count)
+	if count != 3 {
+		t.Errorf("expect 3 BNB deposits left, got %d", count)
+	}
+	if history, _ := bnbRepository.GetStatusHistory("0xa"); len(history) != 1 {
+		t.Errorf("status history should be kept: %+v", history)
+	}
+	if err := bnbRepository.UpdateStatus("0xa", nil, StatusPending, retry); !errors.Is(err, ErrDepositArchived) {
+		t.Fatalf("expect the retry of an archived deposit rejected, got: %v", err)
+	}
+	if err := bnbRepository.UpdateStatus("0xd", nil, StatusPending, retry); err != nil {
+		t.Fatalf("expect the update of an unknown deposit ignored, got: %v", err)
+	}
+
+	// a rescan does not add the archived deposits again
+	if err := btcRepository.InsertBtcDepositTxs([]*BtcDepositTx{{Txid: "success", Amount: 1, Height: 10, BlockTime: blockTime}}, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := bnbRepository.InsertWrappedBTCDepositTxs([]*WrappedBTCDepositTx{newWrappedTx("0xa", 0)}, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	handle.Model(&BtcDepositTx{}).Where("txid = ?", "success").Count(&count)
+	if count != 0 {
+		t.Errorf("archived BTC deposit was synced again")
+	}
+	handle.Model(&WrappedBTCDepositTx{}).Where("txid = ?", "0xa").Count(&count)
+	if count != 0 {
+		t.Errorf("archived BNB deposit was synced again")
+	}
+
+	// a failed export is retried, exported deposits are kept without their bulk columns
+	exporter := &testExporter{err: errors.New("disk full")}
+	if n, err := r.ExportArchivedBtcDepositTxs(1, exporter); err == nil || n != 0 {
+		t.Fatalf("expect the export failed, got: %d, error: %v", n, err)
+	}
+	exporter.err = nil
+	for i, expect := range []int{1, 1, 0} {
+		if n, err := r.ExportArchivedBtcDepositTxs(1, exporter); err != nil || n != expect {
+			t.Fatalf("unexpected exported BTC deposits of run %d: %d, error: %v", i, n, err)
+		}
+	}
+	if n, err := r.ExportArchivedWrappedBTCDepositTxs(10, exporter); err != nil || n != 1 {
+		t.Fatalf("unexpected exported BNB deposits: %d, error: %v", n, err)
+	}
+	if fmt.Sprint(exporter.txids) != "[success success invalid 0xa]" {
+		t.Errorf("unexpected exports: %v", exporter.txids)
+	}
+	handle.Order("id").Find(&archivedBtc)
+	if len(archivedBtc) != 2 || archivedBtc[0].ExportedTime == nil || archivedBtc[1].ExportedTime == nil {
+		t.Errorf("unexpected exported BTC deposits: %+v", archivedBtc)
+	}
+	handle.Find(&archivedWrapped)
+	if len(archivedWrapped) != 1 || archivedWrapped[0].ExportedTime == nil || archivedWrapped[0].EventData != "" || archivedWrapped[0].Status != StatusSuccess {
+		t.Errorf("unexpected exported BNB deposits: %+v", archivedWrapped)
+	}
+}
+
+type testExporter struct {
+	err   error
+	txids []string
+}
+
+func (e *testExporter) ExportBtcDepositTxs(txs []*ArchivedBtcDepositTx) error {
+	for _, tx := range txs {
+		e.txids = append(e.txids, tx.Txid)
+	}
+	return e.err
+}
+
+func (e *testExporter) ExportWrappedBTCDepositTxs(txs []*ArchivedWrappedBTCDepositTx) error {
+	for _, tx := range txs {
+		e.txids = append(e.txids, tx.Txid)
+	}
+	return e.err
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// chain of BTC deposits in the status history and sync checkpoints
+const btcChain = "btc"
+
+// actors of status changes
+const (
+	ActorRelayer = "relayer"
+	ActorCLI     = "cli"
+	ActorAPI     = "api"
+)
+
+// ErrStatusChanged is returned when the status of a deposit changed after it was checked
+var ErrStatusChanged = errors.New("the status of the deposit changed concurrently")
+
+// StatusChange describes why and by whom the status of a deposit is changed
+type StatusChange struct {
+	Actor  string
+	Reason string
+	Error  string
+	// Check, if set, accepts the current status of each deposit in the transaction of the change,
+	// the status is then only updated if it is still the checked one
+	Check func(status int) error
+}
+
+func (c StatusChange) check(status int) error {
+	if c.Check == nil {
+		return nil
+	}
+	return c.Check(status)
+}
+
+// DepositStatusHistory is an append-only record of a deposit status transition
+type DepositStatusHistory struct {
+	Id          int
+	Chain       string `gorm:"size:31"`
+	Txid        string `gorm:"size:256"`
+	LogIndex    uint
+	OldStatus   int
+	NewStatus   int
+	Reason      string `gorm:"size:255"`
+	Error       string
+	Actor       string    `gorm:"size:31"`
+	CreatedTime time.Time `gorm:"autoCreateTime"`
+}
+
+func (DepositStatusHistory) TableName() string {
+	return "deposit_status_history"
+}
+
+func newDepositStatusHistory(chain string, txid string, logIndex uint, oldStatus int, newStatus int, change StatusChange) *DepositStatusHistory {
+	return &DepositStatusHistory{
+		Chain:     chain,
+		Txid:      txid,
+		LogIndex:  logIndex,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Reason:    change.Reason,
+		Error:     change.Error,
+		Actor:     change.Actor,
+	}
+}
+
+func getStatusHistory(db *gorm.DB, chain string, txid string) ([]*DepositStatusHistory, error) {
+	var history []*DepositStatusHistory
+	err := db.Model(&DepositStatusHistory{}).Where("chain = ? AND txid = ?", chain, txid).
+		Order("id").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func getStatusHistoryAfter(db *gorm.DB, chain string, afterId int, since time.Time, limit int) ([]*DepositStatusHistory, error) {
+	var history []*DepositStatusHistory
+	err := db.Model(&DepositStatusHistory{}).Where("chain = ? AND id > ? AND created_time >= ?", chain, afterId, since).
+		Order("id").Limit(limit).Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -44,15 +44,30 @@ func (BtcDepositTx) TableName() string {
 	return "btc_deposit_tx"
 }
 
+// WrappedBTCDepositTx is a relayed hub event, one row per event keyed by (chain, txid, log_index)
 type WrappedBTCDepositTx struct {
 	Chain     string
 	Txid      string
+	LogIndex  uint
+	EventName string `gorm:"size:64"`
+	// EventData is the JSON of the decoded event
+	EventData string
 	Height    uint64
 	BlockHash string
 	BlockTime time.Time
 	Receipt   string
 	Proof     string
 	Status    int
+	// Reason explains why the row is rejected, e.g. the stake plan mismatch
+	Reason string `gorm:"size:255"`
+
+	// decoded StakeBTC2JoinStakePlan event fields, amounts are decimal strings of uint256
+	StakeIndex         uint64
+	PlanId             uint64
+	UserAddress        string `gorm:"size:64"`
+	BtcContractAddress string `gorm:"size:64"`
+	StakeAmount        string `gorm:"size:80"`
+	StBTCAmount        string `gorm:"column:st_btc_amount;size:80"`
 
 	BaseTable
 }
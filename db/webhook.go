@@ -0,0 +1,118 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// statuses of the webhook deliveries
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	// WebhookDeliveryFailed the delivery ran out of attempts
+	WebhookDeliveryFailed = "failed"
+)
+
+// WebhookDelivery is the delivery of a deposit event to a webhook subscription and the result of its last attempt
+type WebhookDelivery struct {
+	Id           int
+	Subscription string `gorm:"size:63"`
+	URL          string `gorm:"column:url;size:1024"`
+	// EventId is the id of the event in the outbox of the chain
+	EventId   int
+	Chain     string `gorm:"size:31"`
+	Txid      string `gorm:"size:256"`
+	LogIndex  uint
+	EventType string `gorm:"size:31"`
+	// Payload is the JSON body of the delivery
+	Payload  string
+	Status   string `gorm:"size:15"`
+	Attempts int
+	// ResponseCode is the HTTP status of the last attempt, 0 if no response was received
+	ResponseCode int
+	// Error is the error of the last failed attempt
+	Error           string
+	NextAttemptTime time.Time
+	CreatedTime     time.Time `gorm:"autoCreateTime"`
+	UpdatedTime     time.Time `gorm:"autoUpdateTime"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
+
+type IWebhookRepository interface {
+	// InsertWebhookDeliveries enqueues the deliveries in one transaction
+	InsertWebhookDeliveries(deliveries []*WebhookDelivery) error
+	// GetDueWebhookSubscriptions returns the names of the subscriptions with pending deliveries due at now
+	GetDueWebhookSubscriptions(now time.Time) ([]string, error)
+	// GetDueWebhookDeliveries returns up to limit pending deliveries of the subscription due at now, the earliest first
+	GetDueWebhookDeliveries(subscription string, now time.Time, limit int) ([]*WebhookDelivery, error)
+	// UpdateWebhookDelivery saves the result of a delivery attempt
+	UpdateWebhookDelivery(delivery *WebhookDelivery) error
+	// GetWebhookDeliveries returns the deliveries of the txid in the order they were enqueued
+	GetWebhookDeliveries(txid string) ([]*WebhookDelivery, error)
+}
+
+// WebhookRepository keeps the webhook deliveries in the webhook_delivery table
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) (IWebhookRepository, error) {
+	if db == nil {
+		return nil, errors.New("db handle is nil")
+	}
+
+	return &WebhookRepository{
+		db: db,
+	}, nil
+}
+
+func (r *WebhookRepository) InsertWebhookDeliveries(deliveries []*WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(deliveries).Error
+}
+
+func (r *WebhookRepository) GetDueWebhookSubscriptions(now time.Time) ([]string, error) {
+	var subscriptions []string
+	err := r.db.Model(&WebhookDelivery{}).Where("status = ? AND next_attempt_time <= ?", WebhookDeliveryPending, now).
+		Distinct("subscription").Order("subscription").Pluck("subscription", &subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) GetDueWebhookDeliveries(subscription string, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	err := r.db.Model(&WebhookDelivery{}).
+		Where("subscription = ? AND status = ? AND next_attempt_time <= ?", subscription, WebhookDeliveryPending, now).
+		Order("next_attempt_time").Order("id").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) UpdateWebhookDelivery(delivery *WebhookDelivery) error {
+	return r.db.Model(delivery).
+		Select("url", "status", "attempts", "response_code", "error", "next_attempt_time", "updated_time").
+		Updates(delivery).Error
+}
+
+func (r *WebhookRepository) GetWebhookDeliveries(txid string) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	err := r.db.Model(&WebhookDelivery{}).Where("txid = ?", txid).Order("id").Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
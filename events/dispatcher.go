@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+// Dispatcher polls the outbox of a chain and hands the new events to a publisher.
+// The cursor of the publisher is saved after every published batch, so a restart resumes after the last
+// published event and an undelivered batch is published again. The cursor does not move past an id of an
+// event that may not be committed yet, see db.IDepositEventRepository.ReadDepositEvents.
+type Dispatcher struct {
+	logger     *zap.SugaredLogger
+	chain      string
+	repository db.IDepositEventRepository
+	publisher  Publisher
+	interval   time.Duration
+	batchSize  int
+	// visibilityDelay is how long a gap in the outbox ids is waited for
+	visibilityDelay time.Duration
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+func NewDispatcher(logger *zap.SugaredLogger, cfg config.Events, chain string, repository db.IDepositEventRepository, publisher Publisher) *Dispatcher {
+	return &Dispatcher{
+		logger:          logger.Named("events").With("chain", chain, "publisher", publisher.Name()),
+		chain:           chain,
+		repository:      repository,
+		publisher:       publisher,
+		interval:        cfg.PollInterval,
+		batchSize:       cfg.BatchSize,
+		visibilityDelay: cfg.VisibilityDelay,
+		quit:            make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.loop()
+	}()
+}
+
+func (d *Dispatcher) Stop() {
+	close(d.quit)
+}
+
+func (d *Dispatcher) WaitForShutdown() {
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) loop() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), d.interval+10*time.Second)
+		_, moved, err := d.dispatch(ctx)
+		cancel()
+		if err != nil {
+			d.logger.Warnf("Failed to dispatch deposit events, error: %v", err)
+		}
+
+		// a batch is followed by the next one right away until the outbox is read up
+		if err == nil && moved {
+			select {
+			case <-d.quit:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-d.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce publishes the next batch of events after the cursor, it returns the number of published events
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	n, _, err := d.dispatch(ctx)
+	return n, err
+}
+
+// dispatch publishes the next batch of events, it also tells whether the cursor moved
+func (d *Dispatcher) dispatch(ctx context.Context) (int, bool, error) {
+	cursor, err := d.repository.GetEventCursor(d.publisher.Name())
+	if err != nil {
+		return 0, false, fmt.Errorf("get cursor: %w", err)
+	}
+	records, position, err := d.repository.ReadDepositEvents(cursor, d.batchSize, time.Now().Add(-d.visibilityDelay))
+	if err != nil {
+		return 0, false, fmt.Errorf("get events: %w", err)
+	}
+	if position == cursor {
+		return 0, false, nil
+	}
+
+	// a batch of the other chains only moves the cursor
+	if len(records) > 0 {
+		events := make([]Event, 0, len(records))
+		for _, record := range records {
+			events = append(events, NewEvent(record))
+		}
+		if err := d.publisher.Publish(ctx, events); err != nil {
+			return 0, false, fmt.Errorf("publish events after %d: %w", cursor, err)
+		}
+	}
+	if err := d.repository.SetEventCursor(d.publisher.Name(), position); err != nil {
+		return 0, false, fmt.Errorf("set cursor to %d: %w", position, err)
+	}
+
+	return len(records), true, nil
+}
@@ -0,0 +1,51 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+// Event is the JSON of a deposit lifecycle event published to the downstream services
+type Event struct {
+	// Id increases with the order the events of the chain were recorded in
+	Id       int    `json:"id"`
+	Chain    string `json:"chain"`
+	Txid     string `json:"txid"`
+	LogIndex uint   `json:"logIndex"`
+	// Type is detected, confirmed, submitted, minted or invalid
+	Type string `json:"type"`
+	// Status is the status of the deposit after the event
+	Status      string          `json:"status"`
+	Reason      string          `json:"reason,omitempty"`
+	Data        json.RawMessage `json:"data"`
+	CreatedTime time.Time       `json:"createdTime"`
+}
+
+func NewEvent(event *db.DepositEvent) Event {
+	data := json.RawMessage(event.Data)
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+	return Event{
+		Id:          event.Id,
+		Chain:       event.Chain,
+		Txid:        event.Txid,
+		LogIndex:    event.LogIndex,
+		Type:        event.Type,
+		Status:      db.StatusNames[event.Status],
+		Reason:      event.Reason,
+		Data:        data,
+		CreatedTime: event.CreatedTime,
+	}
+}
+
+// Publisher delivers the events of the outbox. The events of a chain are published in order and at least once,
+// a batch is published again if Publish returns an error.
+type Publisher interface {
+	// Name identifies the cursor of the publisher in the database, it must not change across restarts
+	Name() string
+	Publish(ctx context.Context, events []Event) error
+}
@@ -0,0 +1,296 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+var testEventsConfig = config.Events{PollInterval: time.Second, BatchSize: 2}
+
+const testToken = "0123456789abcdef"
+
+// tokenAuthenticator authenticates the requests with the bearer test token
+type tokenAuthenticator struct{}
+
+func (tokenAuthenticator) Authenticate(r *http.Request) (string, bool) {
+	return "test", r.Header.Get("Authorization") == "Bearer "+testToken
+}
+
+func authHeader() http.Header {
+	return http.Header{"Authorization": []string{"Bearer " + testToken}}
+}
+
+type recordingPublisher struct {
+	events []Event
+	err    error
+}
+
+func (p *recordingPublisher) Name() string {
+	return "recording"
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, events []Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, events...)
+	return nil
+}
+
+func insertBtcDeposits(t *testing.T, repository db.IBTCRepository, txids ...string) {
+	t.Helper()
+	var txs []*db.BtcDepositTx
+	for _, txid := range txids {
+		txs = append(txs, &db.BtcDepositTx{Txid: txid, Amount: 1000})
+	}
+	if err := repository.InsertBtcDepositTxs(txs, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventKeys(events []Event) string {
+	var keys []string
+	for _, event := range events {
+		keys = append(keys, event.Txid+":"+event.Type)
+	}
+	return strings.Join(keys, ",")
+}
+
+func TestDispatcherResumesAfterFailure(t *testing.T) {
+	repository := db.NewMemoryBTCRepository()
+	insertBtcDeposits(t, repository, "a", "b", "c")
+	publisher := &recordingPublisher{err: errors.New("publisher down")}
+	dispatcher := NewDispatcher(zap.NewNop().Sugar(), testEventsConfig, "btc", repository, publisher)
+	ctx := context.Background()
+
+	if _, err := dispatcher.DispatchOnce(ctx); err == nil {
+		t.Fatal("expect the publish error")
+	}
+	if cursor, _ := repository.GetEventCursor(publisher.Name()); cursor != 0 {
+		t.Fatalf("cursor moved after a failure: %d", cursor)
+	}
+
+	publisher.err = nil
+	if n, err := dispatcher.DispatchOnce(ctx); err != nil || n != 2 {
+		t.Fatalf("unexpected dispatch: %d, error: %v", n, err)
+	}
+	if err := repository.UpdateTxStatus("a", db.StatusSuccess, db.StatusChange{Actor: db.ActorRelayer}); err != nil {
+		t.Fatal(err)
+	}
+	// a new dispatcher resumes from the saved cursor
+	dispatcher = NewDispatcher(zap.NewNop().Sugar(), testEventsConfig, "btc", repository, publisher)
+	if n, err := dispatcher.DispatchOnce(ctx); err != nil || n != 2 {
+		t.Fatalf("unexpected dispatch: %d, error: %v", n, err)
+	}
+	if n, err := dispatcher.DispatchOnce(ctx); err != nil || n != 0 {
+		t.Fatalf("unexpected dispatch: %d, error: %v", n, err)
+	}
+
+	if got := eventKeys(publisher.events); got != "a:detected,b:detected,c:detected,a:minted" {
+		t.Fatalf("unexpected events: %s", got)
+	}
+	minted := publisher.events[3]
+	if minted.Status != "success" || !strings.Contains(string(minted.Data), `"amount":1000`) {
+		t.Errorf("unexpected minted event: %+v", minted)
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	cursor, err := ParseCursor("btc:12,bnb:40")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor["btc"] != 12 || cursor["bnb"] != 40 || cursor.String() != "bnb:40,btc:12" {
+		t.Fatalf("unexpected cursor: %v", cursor)
+	}
+	for _, invalid := range []string{"btc", "btc:x", "btc:-1"} {
+		if _, err := ParseCursor(invalid); err == nil {
+			t.Errorf("expect an error for %q", invalid)
+		}
+	}
+}
+
+type sseEvent struct {
+	id    string
+	event string
+	data  Event
+}
+
+// readSSE reads n events from the stream, comments are skipped
+func readSSE(t *testing.T, reader *bufio.Reader, n int) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	for len(events) < n {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if current.event != "" {
+				events = append(events, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return events
+}
+
+func newTestHub(t *testing.T) (*Hub, db.IBTCRepository, db.IBNBRepository, *httptest.Server) {
+	t.Helper()
+	btcRepository := db.NewMemoryBTCRepository()
+	bnbRepository := db.NewMemoryBNBRepository("bnb")
+	hub, err := NewHub(zap.NewNop().Sugar(), config.Events{}, map[string]db.IDepositEventRepository{
+		"btc": btcRepository,
+		"bnb": bnbRepository,
+	}, tokenAuthenticator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	hub.Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return hub, btcRepository, bnbRepository, server
+}
+
+func dispatch(t *testing.T, hub *Hub, chain string, repository db.IDepositEventRepository) {
+	t.Helper()
+	cfg := config.Events{PollInterval: time.Second, BatchSize: 100}
+	if _, err := NewDispatcher(zap.NewNop().Sugar(), cfg, chain, repository, hub).DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHubSSEReplaysAfterCursor(t *testing.T) {
+	hub, btcRepository, bnbRepository, server := newTestHub(t)
+	insertBtcDeposits(t, btcRepository, "a", "b")
+	bnbTxs := []*db.WrappedBTCDepositTx{{Chain: "bnb", Txid: "0xa", LogIndex: 3}}
+	if err := bnbRepository.InsertWrappedBTCDepositTxs(bnbTxs, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+	dispatch(t, hub, "btc", btcRepository)
+	dispatch(t, hub, "bnb", bnbRepository)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/events?chain=btc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = authHeader()
+	req.Header.Set("Last-Event-ID", "btc:1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	// the missed event is replayed from the outbox
+	events := readSSE(t, reader, 1)
+	if events[0].id != "btc:2" || events[0].event != db.EventDetected || events[0].data.Txid != "b" {
+		t.Fatalf("unexpected replayed event: %+v", events[0])
+	}
+
+	// live events of the other chains are filtered out
+	if err := bnbRepository.MarkSuccess("0xa", 3, db.StatusChange{Actor: db.ActorRelayer}); err != nil {
+		t.Fatal(err)
+	}
+	if err := btcRepository.UpdateTxStatus("a", db.StatusInvalid, db.StatusChange{Actor: db.ActorRelayer, Error: "bad proof"}); err != nil {
+		t.Fatal(err)
+	}
+	dispatch(t, hub, "bnb", bnbRepository)
+	dispatch(t, hub, "btc", btcRepository)
+	events = readSSE(t, reader, 1)
+	if events[0].id != "btc:3" || events[0].event != db.EventInvalid || events[0].data.Reason != "bad proof" {
+		t.Fatalf("unexpected live event: %+v", events[0])
+	}
+}
+
+func TestHubWebSocket(t *testing.T) {
+	hub, btcRepository, _, server := newTestHub(t)
+	insertBtcDeposits(t, btcRepository, "a")
+	dispatch(t, hub, "btc", btcRepository)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/events/ws?lastEventId=btc:0"
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expect the stream without a token unauthorized, error: %v", err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, authHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var message websocketMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Cursor != "bnb:0,btc:1" || message.Event.Txid != "a" || message.Event.Type != db.EventDetected {
+		t.Fatalf("unexpected message: %+v", message)
+	}
+
+	resp, err := http.Get(server.URL + "/api/v1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expect 401 without a token, got %d", resp.StatusCode)
+	}
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/events?chain=eth", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = authHeader()
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expect 400 for an unknown chain, got %d", resp.StatusCode)
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub, _, _, _ := newTestHub(t)
+	sub := &subscriber{chains: map[string]bool{"btc": true}, events: make(chan Event, 1), dropped: make(chan struct{})}
+	hub.subscribers[sub] = struct{}{}
+
+	if err := hub.Publish(context.Background(), []Event{{Id: 1, Chain: "btc"}, {Id: 2, Chain: "btc"}}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-sub.dropped:
+	default:
+		t.Fatal("expect the slow subscriber dropped")
+	}
+	if len(hub.subscribers) != 0 || hub.published["btc"] != 2 {
+		t.Fatalf("unexpected hub state: %d subscribers, published %v", len(hub.subscribers), hub.published)
+	}
+}
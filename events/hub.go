@@ -0,0 +1,373 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+const (
+	// HubPublisherName is the cursor name of the events pushed to the streams
+	HubPublisherName = "stream"
+
+	// subscriberBufferSize bounds the live events queued for a stream, a slower client is disconnected
+	subscriberBufferSize  = 256
+	replayBatchSize       = 100
+	websocketWriteTimeout = 10 * time.Second
+)
+
+var errSubscriberTooSlow = errors.New("subscriber too slow")
+
+// Cursor is the id of the last received event per chain, it is encoded as "bnb:40,btc:12"
+type Cursor map[string]int
+
+func ParseCursor(s string) (Cursor, error) {
+	cursor := make(Cursor)
+	if s == "" {
+		return cursor, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		chain, id, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid cursor: %s", s)
+		}
+		n, err := strconv.Atoi(id)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid cursor: %s", s)
+		}
+		cursor[chain] = n
+	}
+	return cursor, nil
+}
+
+func (c Cursor) String() string {
+	parts := make([]string, 0, len(c))
+	for chain, id := range c {
+		parts = append(parts, fmt.Sprintf("%s:%d", chain, id))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+type subscriber struct {
+	chains map[string]bool
+	events chan Event
+	// dropped is closed when the subscriber falls behind
+	dropped chan struct{}
+}
+
+// websocketMessage is a WebSocket message, the cursor resumes the stream with the lastEventId query
+type websocketMessage struct {
+	Cursor string `json:"cursor"`
+	Event  Event  `json:"event"`
+}
+
+// Hub is the publisher of the SSE and WebSocket streams of the deposit events.
+// A client resuming with the cursor of its last event receives the missed events from the outbox first,
+// so no event is lost across reconnects and restarts.
+type Hub struct {
+	logger       *zap.SugaredLogger
+	repositories map[string]db.IDepositEventRepository
+	auth         Authenticator
+	heartbeat    time.Duration
+	upgrader     websocket.Upgrader
+
+	lock sync.Mutex
+	// published is the id of the last event pushed to the subscribers per chain
+	published   map[string]int
+	subscribers map[*subscriber]struct{}
+}
+
+// Authenticator authenticates the stream clients, e.g. by the admin API tokens
+type Authenticator interface {
+	// Authenticate returns the identity of the client of the request and whether it is authenticated
+	Authenticate(r *http.Request) (string, bool)
+}
+
+// NewHub returns the hub of the outbox of every chain in repositories, its streams are served to the clients
+// authenticated by auth
+func NewHub(logger *zap.SugaredLogger, cfg config.Events, repositories map[string]db.IDepositEventRepository, auth Authenticator) (*Hub, error) {
+	published := make(map[string]int)
+	for chain, repository := range repositories {
+		id, err := repository.GetEventCursor(HubPublisherName)
+		if err != nil {
+			return nil, fmt.Errorf("get %s event cursor: %w", chain, err)
+		}
+		published[chain] = id
+	}
+
+	return &Hub{
+		logger:       logger.Named("events"),
+		repositories: repositories,
+		auth:         auth,
+		heartbeat:    cfg.HeartbeatInterval,
+		published:    published,
+		subscribers:  make(map[*subscriber]struct{}),
+	}, nil
+}
+
+func (h *Hub) Name() string {
+	return HubPublisherName
+}
+
+// Publish pushes the events to the subscribers, it never blocks on a subscriber
+func (h *Hub) Publish(_ context.Context, events []Event) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, event := range events {
+		h.published[event.Chain] = event.Id
+		for sub := range h.subscribers {
+			if !sub.chains[event.Chain] {
+				continue
+			}
+			select {
+			case sub.events <- event:
+			default:
+				close(sub.dropped)
+				delete(h.subscribers, sub)
+			}
+		}
+	}
+	return nil
+}
+
+// Register adds the stream endpoints, both require an authenticated client and accept a chain query of comma separated chains and resume after
+// the cursor in the Last-Event-ID header or the lastEventId query:
+//
+//	GET /api/v1/events     Server-Sent Events, the event id is the cursor
+//	GET /api/v1/events/ws  WebSocket, every message carries the cursor and the event
+func (h *Hub) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/events", h.authenticated(h.serveSSE))
+	mux.HandleFunc("/api/v1/events/ws", h.authenticated(h.serveWebSocket))
+}
+
+func (h *Hub) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := h.auth.Authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="events"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.logger.Debugf("Stream of %s opened by %s", r.RemoteAddr, identity)
+		handler(w, r)
+	}
+}
+
+func (h *Hub) serveSSE(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	chains, cursor, err := h.parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	err = h.stream(r.Context(), chains, cursor, func(event Event, position Cursor) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", position, event.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}, func() error {
+		if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil {
+		h.logger.Debugf("SSE stream of %s closed, error: %v", r.RemoteAddr, err)
+	}
+}
+
+func (h *Hub) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	chains, cursor, err := h.parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Upgrade replies with the error itself
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	// the reads only handle the control frames, the stream ends when the client closes the connection
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = h.stream(ctx, chains, cursor, func(event Event, position Cursor) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+		return conn.WriteJSON(websocketMessage{Cursor: position.String(), Event: event})
+	}, func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketWriteTimeout))
+	})
+	if errors.Is(err, errSubscriberTooSlow) {
+		message := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error())
+		_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(websocketWriteTimeout))
+	}
+	if err != nil {
+		h.logger.Debugf("WebSocket stream of %s closed, error: %v", r.RemoteAddr, err)
+	}
+}
+
+// parseRequest returns the requested chains, all chains if none, and the cursor to resume after
+func (h *Hub) parseRequest(r *http.Request) (map[string]bool, Cursor, error) {
+	chains := make(map[string]bool)
+	if query := r.URL.Query().Get("chain"); query != "" {
+		for _, chain := range strings.Split(query, ",") {
+			if _, ok := h.repositories[chain]; !ok {
+				return nil, nil, fmt.Errorf("unknown chain: %s", chain)
+			}
+			chains[chain] = true
+		}
+	} else {
+		for chain := range h.repositories {
+			chains[chain] = true
+		}
+	}
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+	cursor, err := ParseCursor(lastEventId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chains, cursor, nil
+}
+
+// stream sends the events of the chains after the cursor from the outbox, then the live events until ctx is done
+// or the subscriber falls behind. The chains missing from the cursor start with the live events.
+func (h *Hub) stream(ctx context.Context, chains map[string]bool, cursor Cursor,
+	send func(event Event, position Cursor) error, heartbeat func() error) error {
+	sub := &subscriber{
+		chains:  chains,
+		events:  make(chan Event, subscriberBufferSize),
+		dropped: make(chan struct{}),
+	}
+	// the events up to the published ids are replayed from the outbox, the later ones are queued
+	until := make(Cursor)
+	h.lock.Lock()
+	h.subscribers[sub] = struct{}{}
+	for chain := range chains {
+		until[chain] = h.published[chain]
+	}
+	h.lock.Unlock()
+	defer h.unsubscribe(sub)
+
+	// every chain is sent at its replay position, so a client disconnected during the replay misses nothing
+	position := make(Cursor)
+	for chain := range chains {
+		position[chain] = until[chain]
+		if after, ok := cursor[chain]; ok && after < until[chain] {
+			position[chain] = after
+		}
+	}
+	for chain := range chains {
+		if err := h.replay(chain, until[chain], position, send); err != nil {
+			return err
+		}
+		position[chain] = until[chain]
+	}
+
+	var heartbeats <-chan time.Time
+	if h.heartbeat > 0 {
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sub.dropped:
+			return errSubscriberTooSlow
+		case event := <-sub.events:
+			if event.Id <= position[event.Chain] {
+				continue
+			}
+			position[event.Chain] = event.Id
+			if err := send(event, position); err != nil {
+				return err
+			}
+		case <-heartbeats:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// replay sends the events of the chain from the outbox after the position up to until
+func (h *Hub) replay(chain string, until int, position Cursor, send func(event Event, position Cursor) error) error {
+	for position[chain] < until {
+		records, err := h.repositories[chain].GetDepositEvents(position[chain], replayBatchSize)
+		if err != nil {
+			return fmt.Errorf("get %s events: %w", chain, err)
+		}
+		for _, record := range records {
+			if record.Id > until {
+				return nil
+			}
+			position[chain] = record.Id
+			if err := send(NewEvent(record), position); err != nil {
+				return err
+			}
+		}
+		if len(records) < replayBatchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (h *Hub) unsubscribe(sub *subscriber) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.subscribers, sub)
+}
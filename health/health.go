@@ -0,0 +1,243 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+// Check reports whether a dependency is reachable
+type Check func(ctx context.Context) error
+
+// Monitor tracks the liveness of the relayer loops, the dependencies and the sync lag of the chains
+type Monitor struct {
+	lock sync.RWMutex
+	now  func() time.Time
+
+	livenessTimeout time.Duration
+	checkTimeout    time.Duration
+	maxLags         map[string]uint64
+
+	// last completed iteration of each loop, the registration time before the first one
+	loops    map[string]time.Time
+	progress map[string]Progress
+	checks   map[string]Check
+}
+
+// Progress is the sync point of a chain against its tip
+type Progress struct {
+	SyncPoint uint64 `json:"syncPoint"`
+	ChainTip  uint64 `json:"chainTip"`
+}
+
+func (p Progress) lag() uint64 {
+	if p.ChainTip < p.SyncPoint {
+		return 0
+	}
+	return p.ChainTip - p.SyncPoint
+}
+
+// Default is the monitor reported to by the relayers
+var Default = NewMonitor()
+
+func NewMonitor() *Monitor {
+	return &Monitor{
+		now:             time.Now,
+		livenessTimeout: config.DefaultHealthLivenessTimeout,
+		checkTimeout:    config.DefaultHealthCheckTimeout,
+		maxLags:         make(map[string]uint64),
+		loops:           make(map[string]time.Time),
+		progress:        make(map[string]Progress),
+		checks:          make(map[string]Check),
+	}
+}
+
+// Configure applies the liveness timeout, the check timeout and the lag thresholds
+func (m *Monitor) Configure(cfg config.Health) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.livenessTimeout = cfg.LivenessTimeout
+	m.checkTimeout = cfg.CheckTimeout
+	m.maxLags = map[string]uint64{
+		"btc": cfg.MaxBTCLag,
+		"bnb": cfg.MaxBNBLag,
+	}
+}
+
+// RegisterLoop starts tracking a loop, it is dead if it does not beat within the liveness timeout
+func (m *Monitor) RegisterLoop(name string) {
+	m.Beat(name)
+}
+
+// Beat records a completed iteration of the loop
+func (m *Monitor) Beat(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.loops[name] = m.now()
+}
+
+// SetProgress records the sync point and the tip of the chain
+func (m *Monitor) SetProgress(chain string, syncPoint uint64, chainTip uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.progress[chain] = Progress{SyncPoint: syncPoint, ChainTip: chainTip}
+}
+
+// AddCheck adds a dependency check run by the readiness endpoint
+func (m *Monitor) AddCheck(name string, check Check) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.checks[name] = check
+}
+
+// LoopStatus is the liveness of a loop
+type LoopStatus struct {
+	OK       bool      `json:"ok"`
+	LastBeat time.Time `json:"lastBeat"`
+}
+
+// DependencyStatus is the result of a dependency check
+type DependencyStatus struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// LagStatus is the sync lag of a chain, MaxLag 0 means no threshold
+type LagStatus struct {
+	OK bool `json:"ok"`
+	Progress
+	Lag    uint64 `json:"lag"`
+	MaxLag uint64 `json:"maxLag"`
+}
+
+type Liveness struct {
+	OK    bool                  `json:"ok"`
+	Loops map[string]LoopStatus `json:"loops"`
+}
+
+type Readiness struct {
+	OK           bool                        `json:"ok"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+	Lags         map[string]LagStatus        `json:"lags"`
+}
+
+// Liveness reports the loops which have not beaten within the liveness timeout
+func (m *Monitor) Liveness() Liveness {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	liveness := Liveness{OK: true, Loops: make(map[string]LoopStatus)}
+	now := m.now()
+	for name, lastBeat := range m.loops {
+		ok := now.Sub(lastBeat) <= m.livenessTimeout
+		liveness.Loops[name] = LoopStatus{OK: ok, LastBeat: lastBeat}
+		liveness.OK = liveness.OK && ok
+	}
+	return liveness
+}
+
+// Readiness runs the dependency checks concurrently and compares the sync lags with the thresholds
+func (m *Monitor) Readiness(ctx context.Context) Readiness {
+	m.lock.RLock()
+	checks := make(map[string]Check, len(m.checks))
+	for name, check := range m.checks {
+		checks[name] = check
+	}
+	checkTimeout := m.checkTimeout
+	readiness := Readiness{OK: true, Dependencies: make(map[string]DependencyStatus), Lags: make(map[string]LagStatus)}
+	for chain, progress := range m.progress {
+		status := LagStatus{OK: true, Progress: progress, Lag: progress.lag(), MaxLag: m.maxLags[chain]}
+		if status.MaxLag > 0 && status.Lag > status.MaxLag {
+			status.OK = false
+			readiness.OK = false
+		}
+		readiness.Lags[chain] = status
+	}
+	m.lock.RUnlock()
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			status := DependencyStatus{OK: true}
+			if err := runCheck(ctx, check); err != nil {
+				status = DependencyStatus{OK: false, Error: err.Error()}
+			}
+			lock.Lock()
+			readiness.Dependencies[name] = status
+			readiness.OK = readiness.OK && status.OK
+			lock.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	return readiness
+}
+
+// runCheck returns when the check returns or the context is done, checks of clients without context support
+// keep running in the background
+func runCheck(ctx context.Context, check Check) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Register mounts /healthz and /readyz on the mux, they respond 503 when failing
+func (m *Monitor) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		liveness := m.Liveness()
+		writeJSON(w, liveness.OK, liveness)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		readiness := m.Readiness(r.Context())
+		writeJSON(w, readiness.OK, readiness)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, ok bool, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// RegisterLoop starts tracking a loop on the default monitor
+func RegisterLoop(name string) {
+	Default.RegisterLoop(name)
+}
+
+// Beat records a completed iteration of the loop on the default monitor
+func Beat(name string) {
+	Default.Beat(name)
+}
+
+// SetProgress records the sync point and the tip of the chain on the default monitor
+func SetProgress(chain string, syncPoint uint64, chainTip uint64) {
+	Default.SetProgress(chain, syncPoint, chainTip)
+}
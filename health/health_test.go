@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+func TestLiveness(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := NewMonitor()
+	m.now = func() time.Time { return now }
+	m.Configure(config.Health{LivenessTimeout: time.Minute, CheckTimeout: time.Second})
+
+	m.RegisterLoop("btc/scan")
+	m.RegisterLoop("btc/submit")
+	now = now.Add(2 * time.Minute)
+	m.Beat("btc/scan")
+
+	liveness := m.Liveness()
+	if liveness.OK || !liveness.Loops["btc/scan"].OK || liveness.Loops["btc/submit"].OK {
+		t.Fatalf("btc/submit should be dead: %+v", liveness)
+	}
+	m.Beat("btc/submit")
+	if liveness := m.Liveness(); !liveness.OK {
+		t.Fatalf("all loops should be alive: %+v", liveness)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	m := NewMonitor()
+	m.Configure(config.Health{LivenessTimeout: time.Minute, CheckTimeout: 50 * time.Millisecond, MaxBTCLag: 6})
+	m.AddCheck("database", func(ctx context.Context) error { return nil })
+	m.SetProgress("btc", 100, 106)
+	m.SetProgress("bnb", 100, 10000)
+
+	recorder := httptest.NewRecorder()
+	mux := http.NewServeMux()
+	m.Register(mux)
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d, body: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// a failing dependency, a hanging dependency and a lagging chain
+	m.AddCheck("btc_api", func(ctx context.Context) error { return errors.New("connection refused") })
+	m.AddCheck("lorenzo_rpc", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	m.SetProgress("btc", 100, 107)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", recorder.Code)
+	}
+	var readiness Readiness
+	if err := json.Unmarshal(recorder.Body.Bytes(), &readiness); err != nil {
+		t.Fatal(err)
+	}
+	if !readiness.Dependencies["database"].OK || readiness.Dependencies["btc_api"].Error != "connection refused" ||
+		readiness.Dependencies["lorenzo_rpc"].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("unexpected dependencies: %+v", readiness.Dependencies)
+	}
+	if readiness.Lags["btc"].OK || readiness.Lags["btc"].Lag != 7 || !readiness.Lags["bnb"].OK {
+		t.Errorf("unexpected lags: %+v", readiness.Lags)
+	}
+}
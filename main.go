@@ -16,6 +16,8 @@ func main() {
 	rootCmd.Flags().Bool("debug", false, "enable debug mode")
 
 	rootCmd.AddCommand(cmd.BlockscoutRefreshCmd())
+	rootCmd.AddCommand(cmd.MigrateCmd())
+	rootCmd.AddCommand(cmd.WebhookCmd())
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+const namespace = "submitter"
+
+// chain labels
+const (
+	ChainBTC = "btc"
+	ChainBNB = "bnb"
+)
+
+// component labels of errors, besides the chains
+const (
+	ComponentBlockscout = "blockscout"
+)
+
+// error classes
+const (
+	// ErrorClassChainRPC the BTC api or the BNB rpc failed
+	ErrorClassChainRPC = "chain_rpc"
+	// ErrorClassLorenzo a Lorenzo query or tx failed
+	ErrorClassLorenzo = "lorenzo"
+	// ErrorClassDatabase a repository call failed
+	ErrorClassDatabase = "database"
+	// ErrorClassIntegrity the BNB rpc returned inconsistent data
+	ErrorClassIntegrity = "integrity"
+	// ErrorClassBlockRange the BNB rpc rejected the block range of eth_getLogs
+	ErrorClassBlockRange = "block_range"
+	// ErrorClassInvalidDeposit a deposit is rejected, see the deposits counter for the status
+	ErrorClassInvalidDeposit = "invalid_deposit"
+	// ErrorClassBlockscoutApi the blockscout or the Lorenzo app api failed
+	ErrorClassBlockscoutApi = "blockscout_api"
+)
+
+// Registry holds the metrics of the submitter, served by NewServer
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	SyncPoint = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sync_point",
+		Help:      "The last block whose deposits are persisted.",
+	}, []string{"chain"})
+	ChainTip = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "chain_tip",
+		Help:      "The tip of the chain.",
+	}, []string{"chain"})
+	LorenzoLightClientTip = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "lorenzo_light_client_tip",
+		Help:      "The tip of the light client of the chain on Lorenzo.",
+	}, []string{"chain"})
+
+	DepositsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deposits_total",
+		Help:      "Handled deposits by the status they are marked with and the agent id.",
+	}, []string{"chain", "status", "agent"})
+	SubmissionDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "submission_duration_seconds",
+		Help:      "Duration of submitting a deposit to Lorenzo.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 10),
+	}, []string{"chain", "result"})
+	DepositLatency = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "deposit_latency_seconds",
+		Help:      "Duration from the block time of a deposit until it is marked successful.",
+		Buckets:   prometheus.ExponentialBuckets(60, 2, 12),
+	}, []string{"chain"})
+
+	ErrorsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Errors by component and classification.",
+	}, []string{"component", "class"})
+
+	HubAddressChangesTotal = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bnb_hub_address_changes_total",
+		Help:      "Changes of the stake plan hub address seen in the bnblightclient params.",
+	})
+
+	agentsRefreshTime atomic.Int64
+	_                 = factory.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "agents_refresh_age_seconds",
+		Help:      "Seconds since the agent list was refreshed from Lorenzo, -1 before the first refresh.",
+	}, func() float64 {
+		refreshed := agentsRefreshTime.Load()
+		if refreshed == 0 {
+			return -1
+		}
+		return time.Since(time.Unix(0, refreshed)).Seconds()
+	})
+
+	BlockscoutCursor = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: "blockscout_refresher",
+		Name:      "cursor",
+		Help:      "The next Lorenzo height to refresh.",
+	})
+	BlockscoutHeight = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: "blockscout_refresher",
+		Name:      "blockscout_height",
+		Help:      "The Lorenzo height indexed by blockscout.",
+	})
+	BlockscoutEventScanCursor = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: "blockscout_refresher",
+		Name:      "event_scan_cursor",
+		Help:      "The Lorenzo height scanned by the Lorenzo app api.",
+	})
+	BlockscoutRefreshedAccountsTotal = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: "blockscout_refresher",
+		Name:      "refreshed_accounts_total",
+		Help:      "Account balances refreshed on blockscout.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// SetAgentsRefreshed records the time the agent list is refreshed
+func SetAgentsRefreshed(t time.Time) {
+	agentsRefreshTime.Store(t.UnixNano())
+}
+
+// Error counts an error of the component
+func Error(component string, class string) {
+	ErrorsTotal.WithLabelValues(component, class).Inc()
+}
+
+// Deposit counts a deposit marked with the status, the agent is empty if it is unknown
+func Deposit(chain string, status int, agent string) {
+	DepositsTotal.WithLabelValues(chain, StatusLabel(status), agent).Inc()
+}
+
+// AgentLabel is the agent label of an agent id, 0 is unknown
+func AgentLabel(agentId uint64) string {
+	if agentId == 0 {
+		return ""
+	}
+	return strconv.FormatUint(agentId, 10)
+}
+
+// StatusLabel is the status label of a deposit status
+func StatusLabel(status int) string {
+	if name, ok := db.StatusNames[status]; ok {
+		return name
+	}
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+func TestServer(t *testing.T) {
+	SyncPoint.WithLabelValues(ChainBTC).Set(100)
+	Deposit(ChainBNB, db.StatusInvalidPlan, AgentLabel(2))
+	Error(ChainBTC, ErrorClassChainRPC)
+	SetAgentsRefreshed(time.Now().Add(-time.Minute))
+
+	recorder := httptest.NewRecorder()
+	NewServer(":0").Handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+	for _, expect := range []string{
+		`submitter_sync_point{chain="btc"} 100`,
+		`submitter_deposits_total{agent="2",chain="bnb",status="invalid_plan"} 1`,
+		`submitter_errors_total{class="chain_rpc",component="btc"} 1`,
+		`submitter_agents_refresh_age_seconds 6`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), expect) {
+			t.Errorf("metric %q not found", expect)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+// IdHeader is the Kafka header of the message id, consumers deduplicate the republished messages by it
+const IdHeader = "id"
+
+// KafkaProducer writes the messages to the Kafka topics. The messages of a chain share its key,
+// so they land in one partition in order.
+type KafkaProducer struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaProducer returns the producer of the brokers, batchSize is the maximum number of messages of a Produce,
+// they are written as one batch so a failure does not reorder them
+func NewKafkaProducer(cfg config.Queue, batchSize int) *KafkaProducer {
+	return &KafkaProducer{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(cfg.URLs...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+			BatchSize:              batchSize,
+			WriteTimeout:           cfg.Timeout,
+		},
+	}
+}
+
+func (p *KafkaProducer) Produce(ctx context.Context, topic string, messages []Message) error {
+	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	for _, message := range messages {
+		kafkaMessages = append(kafkaMessages, kafka.Message{
+			Topic:   topic,
+			Key:     []byte(message.Chain),
+			Value:   message.Value,
+			Headers: []kafka.Header{{Key: IdHeader, Value: []byte(message.Id)}},
+		})
+	}
+	return p.writer.WriteMessages(ctx, kafkaMessages...)
+}
+
+func (p *KafkaProducer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+type producedRecord struct {
+	partition int32
+	acks      int16
+	key       string
+	id        string
+}
+
+// fakeKafkaTransport answers the metadata and produce requests of the writer like a broker with one topic
+type fakeKafkaTransport struct {
+	partitions int
+	lock       sync.Mutex
+	records    []producedRecord
+}
+
+func (f *fakeKafkaTransport) RoundTrip(_ context.Context, _ net.Addr, req protocol.Message) (protocol.Message, error) {
+	switch req := req.(type) {
+	case *metadata.Request:
+		response := &metadata.Response{Brokers: []metadata.ResponseBroker{{NodeID: 0, Host: "127.0.0.1", Port: 9092}}}
+		for _, topic := range req.TopicNames {
+			responseTopic := metadata.ResponseTopic{Name: topic}
+			for i := 0; i < f.partitions; i++ {
+				responseTopic.Partitions = append(responseTopic.Partitions, metadata.ResponsePartition{PartitionIndex: int32(i)})
+			}
+			response.Topics = append(response.Topics, responseTopic)
+		}
+		return response, nil
+	case *produce.Request:
+		response := &produce.Response{}
+		for _, topic := range req.Topics {
+			responseTopic := produce.ResponseTopic{Topic: topic.Topic}
+			for _, partition := range topic.Partitions {
+				if err := f.record(req.Acks, partition); err != nil {
+					return nil, err
+				}
+				responseTopic.Partitions = append(responseTopic.Partitions, produce.ResponsePartition{Partition: partition.Partition})
+			}
+			response.Topics = append(response.Topics, responseTopic)
+		}
+		return response, nil
+	default:
+		return nil, fmt.Errorf("unexpected kafka request: %T", req)
+	}
+}
+
+func (f *fakeKafkaTransport) record(acks int16, partition produce.RequestPartition) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for {
+		record, err := partition.RecordSet.Records.ReadRecord()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		key, err := protocol.ReadAll(record.Key)
+		if err != nil {
+			return err
+		}
+		produced := producedRecord{partition: partition.Partition, acks: acks, key: string(key)}
+		for _, header := range record.Headers {
+			if header.Key == IdHeader {
+				produced.id = string(header.Value)
+			}
+		}
+		f.records = append(f.records, produced)
+	}
+}
+
+func TestKafkaProducerKeepsChainOrder(t *testing.T) {
+	transport := &fakeKafkaTransport{partitions: 8}
+	producer := NewKafkaProducer(newTestConfig(config.QueueBackendKafka, "127.0.0.1:9092"), 100)
+	producer.writer.Transport = transport
+	producer.writer.BatchTimeout = 10 * time.Millisecond
+	defer producer.Close()
+
+	ctx := context.Background()
+	for _, chain := range []string{"btc", "bnb", "btc"} {
+		var messages []Message
+		for i := 0; i < 5; i++ {
+			messages = append(messages, Message{Chain: chain, Id: fmt.Sprintf("%s:%d", chain, len(transport.records)+i), Value: []byte("{}")})
+		}
+		if err := producer.Produce(ctx, "lorenzo.deposits", messages); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(transport.records) != 15 {
+		t.Fatalf("unexpected records: %d", len(transport.records))
+	}
+	partitions := make(map[string]int32)
+	for i, record := range transport.records {
+		if record.acks != int16(kafka.RequireAll) {
+			t.Errorf("record %d: expect acks from all replicas, got: %d", i, record.acks)
+		}
+		// the messages of a chain land in one partition in the order they were produced
+		if partition, ok := partitions[record.key]; ok && partition != record.partition {
+			t.Errorf("record %d: chain %s moved from partition %d to %d", i, record.key, partition, record.partition)
+		}
+		partitions[record.key] = record.partition
+		if expect := fmt.Sprintf("%s:%d", record.key, i); record.id != expect {
+			t.Errorf("record %d: unexpected id: %s, expect: %s", i, record.id, expect)
+		}
+	}
+	if len(partitions) != 2 {
+		t.Errorf("unexpected chains: %v", partitions)
+	}
+}
@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+// NATSProducer publishes the messages to NATS JetStream, the stream deduplicates them by the Nats-Msg-Id header
+type NATSProducer struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+// NewNATSProducer connects to the NATS servers and creates the stream of the subjects if it is missing.
+// An existing stream not capturing the subjects is updated to capture them too, the messages published
+// to it would be lost otherwise.
+func NewNATSProducer(cfg config.Queue) (*NATSProducer, error) {
+	conn, err := nats.Connect(strings.Join(cfg.URLs, ","), nats.Name("lorenzo-btcstaking-submitter"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("connect to nats: %w", err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	subjects := cfg.SubjectPrefix + ".>"
+	info, err := js.StreamInfo(cfg.NATSStream)
+	switch {
+	case errors.Is(err, nats.ErrStreamNotFound):
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     cfg.NATSStream,
+			Subjects: []string{subjects},
+		})
+	case err == nil && !streamCaptures(info.Config.Subjects, subjects):
+		streamCfg := info.Config
+		streamCfg.Subjects = append(streamCfg.Subjects, subjects)
+		_, err = js.UpdateStream(&streamCfg)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("nats stream %s: %w", cfg.NATSStream, err)
+	}
+
+	return &NATSProducer{
+		conn: conn,
+		js:   js,
+	}, nil
+}
+
+// streamCaptures reports whether the subject filters of a stream capture every subject matching subject
+func streamCaptures(filters []string, subject string) bool {
+	for _, filter := range filters {
+		if subjectCovers(filter, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// subjectCovers reports whether the subjects matching subject all match filter, both may hold wildcards
+func subjectCovers(filter string, subject string) bool {
+	filterTokens := strings.Split(filter, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range filterTokens {
+		if token == ">" {
+			return i < len(subjectTokens)
+		}
+		if i >= len(subjectTokens) || subjectTokens[i] == ">" {
+			return false
+		}
+		if token != "*" && token != subjectTokens[i] {
+			return false
+		}
+	}
+	return len(filterTokens) == len(subjectTokens)
+}
+
+// Produce publishes the messages one by one, each waits for its acknowledgement so the order is kept on failures
+func (p *NATSProducer) Produce(ctx context.Context, subject string, messages []Message) error {
+	for _, message := range messages {
+		msg := nats.NewMsg(subject)
+		msg.Data = message.Value
+		if _, err := p.js.PublishMsg(msg, nats.MsgId(message.Id), nats.Context(ctx)); err != nil {
+			return fmt.Errorf("publish %s: %w", message.Id, err)
+		}
+	}
+	return nil
+}
+
+func (p *NATSProducer) Close() error {
+	return p.conn.Drain()
+}
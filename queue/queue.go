@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+)
+
+// SchemaVersion is the version of the message JSON, it is increased on incompatible changes
+const SchemaVersion = 1
+
+// Envelope is the JSON of a message on the queue
+type Envelope struct {
+	Version int          `json:"version"`
+	Event   events.Event `json:"event"`
+}
+
+// Message is a deposit event of a chain encoded for the queue
+type Message struct {
+	// Id identifies the event, "<chain>:<event id>", the brokers deduplicate the republished messages by it
+	Id    string
+	Chain string
+	Value []byte
+}
+
+// Producer sends the messages to a subject or topic in order, it returns once the broker acknowledged all of them.
+// The messages after a failed one are not sent.
+type Producer interface {
+	Produce(ctx context.Context, subject string, messages []Message) error
+	Close() error
+}
+
+// Publisher publishes the deposit events to the subject of their chain, e.g. lorenzo.deposits.btc.
+// With the outbox dispatchers, the events are delivered at least once and in order per chain.
+type Publisher struct {
+	backend  string
+	prefix   string
+	cfg      config.Queue
+	producer Producer
+}
+
+// NewPublisher connects the producer of the backend, batchSize is the maximum number of events of a Publish
+func NewPublisher(cfg config.Queue, batchSize int) (*Publisher, error) {
+	var producer Producer
+	switch cfg.Backend {
+	case config.QueueBackendNATS:
+		natsProducer, err := NewNATSProducer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		producer = natsProducer
+	case config.QueueBackendKafka:
+		producer = NewKafkaProducer(cfg, batchSize)
+	default:
+		return nil, fmt.Errorf("unsupported queue backend: %s", cfg.Backend)
+	}
+
+	return NewPublisherWithProducer(cfg, producer), nil
+}
+
+func NewPublisherWithProducer(cfg config.Queue, producer Producer) *Publisher {
+	return &Publisher{
+		backend:  cfg.Backend,
+		prefix:   cfg.SubjectPrefix,
+		cfg:      cfg,
+		producer: producer,
+	}
+}
+
+// Name is the backend, switching the backend publishes the outbox from the start
+func (p *Publisher) Name() string {
+	return p.backend
+}
+
+func (p *Publisher) Publish(ctx context.Context, published []events.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	// the events are grouped by chain in order, a dispatcher publishes the events of one chain
+	var chains []string
+	messages := make(map[string][]Message)
+	for _, event := range published {
+		value, err := json.Marshal(Envelope{Version: SchemaVersion, Event: event})
+		if err != nil {
+			return err
+		}
+		if _, ok := messages[event.Chain]; !ok {
+			chains = append(chains, event.Chain)
+		}
+		messages[event.Chain] = append(messages[event.Chain], Message{
+			Id:    fmt.Sprintf("%s:%d", event.Chain, event.Id),
+			Chain: event.Chain,
+			Value: value,
+		})
+	}
+
+	for _, chain := range chains {
+		if err := p.producer.Produce(ctx, p.Subject(chain), messages[chain]); err != nil {
+			return fmt.Errorf("produce %s events: %w", chain, err)
+		}
+	}
+	return nil
+}
+
+// Subject returns the NATS subject or the Kafka topic of the events of the chain
+func (p *Publisher) Subject(chain string) string {
+	return p.prefix + "." + chain
+}
+
+func (p *Publisher) Close() error {
+	return p.producer.Close()
+}
@@ -0,0 +1,210 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/queue/queuetest"
+)
+
+func newTestConfig(backend string, urls ...string) config.Queue {
+	return config.Queue{
+		Enabled:       true,
+		Backend:       backend,
+		URLs:          urls,
+		SubjectPrefix: config.DefaultQueueSubjectPrefix,
+		NATSStream:    config.DefaultQueueNATSStream,
+		Timeout:       5 * time.Second,
+	}
+}
+
+type recordingProducer struct {
+	subjects []string
+	messages []Message
+	err      error
+}
+
+func (p *recordingProducer) Produce(_ context.Context, subject string, messages []Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.subjects = append(p.subjects, subject)
+	p.messages = append(p.messages, messages...)
+	return nil
+}
+
+func (p *recordingProducer) Close() error {
+	return nil
+}
+
+func TestPublisherGroupsByChain(t *testing.T) {
+	producer := &recordingProducer{}
+	publisher := NewPublisherWithProducer(newTestConfig(config.QueueBackendKafka), producer)
+	published := []events.Event{
+		{Id: 1, Chain: "btc", Type: db.EventDetected, Data: json.RawMessage("{}")},
+		{Id: 7, Chain: "bnb", Type: db.EventDetected, Data: json.RawMessage("{}")},
+		{Id: 2, Chain: "btc", Type: db.EventMinted, Data: json.RawMessage("{}")},
+	}
+	if err := publisher.Publish(context.Background(), published); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(producer.subjects) != 2 || producer.subjects[0] != "lorenzo.deposits.btc" || producer.subjects[1] != "lorenzo.deposits.bnb" {
+		t.Fatalf("unexpected subjects: %v", producer.subjects)
+	}
+	var ids []string
+	for _, message := range producer.messages {
+		ids = append(ids, message.Id)
+	}
+	if len(ids) != 3 || ids[0] != "btc:1" || ids[1] != "btc:2" || ids[2] != "bnb:7" {
+		t.Fatalf("unexpected messages: %v", ids)
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(producer.messages[1].Value, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Version != SchemaVersion || envelope.Event.Type != db.EventMinted {
+		t.Fatalf("unexpected envelope: %+v", envelope)
+	}
+
+	producer.err = errors.New("broker down")
+	if err := publisher.Publish(context.Background(), published); err == nil {
+		t.Fatal("expect the producer error")
+	}
+}
+
+func TestNATSPublisherAtLeastOnceInOrder(t *testing.T) {
+	url := queuetest.RunNATSServer(t)
+	publisher, err := NewPublisher(newTestConfig(config.QueueBackendNATS, url), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+
+	repository := db.NewMemoryBTCRepository()
+	txs := []*db.BtcDepositTx{{Txid: "a", AgentId: 1}, {Txid: "b", AgentId: 1}}
+	if err := repository.InsertBtcDepositTxs(txs, 10, "0x10"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.UpdateTxStatus("a", db.StatusSuccess, db.StatusChange{Actor: db.ActorRelayer}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Events{PollInterval: time.Second, BatchSize: 100}
+	dispatcher := events.NewDispatcher(zap.NewNop().Sugar(), cfg, "btc", repository, publisher)
+	if n, err := dispatcher.DispatchOnce(context.Background()); err != nil || n != 3 {
+		t.Fatalf("unexpected dispatch: %d, error: %v", n, err)
+	}
+
+	// a batch published again, e.g. after a crash before the cursor was saved, is deduplicated by the stream
+	if err := repository.SetEventCursor(publisher.Name(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dispatcher.DispatchOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := js.StreamInfo(config.DefaultQueueNATSStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 3 {
+		t.Fatalf("unexpected stream messages: %d", info.State.Msgs)
+	}
+
+	sub, err := js.SubscribeSync("lorenzo.deposits.btc", nats.OrderedConsumer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i := 0; i < 3; i++ {
+		msg, err := sub.NextMsg(5 * time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var envelope Envelope
+		if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+			t.Fatal(err)
+		}
+		if envelope.Version != SchemaVersion {
+			t.Errorf("unexpected version: %d", envelope.Version)
+		}
+		got = append(got, envelope.Event.Txid+":"+envelope.Event.Type)
+	}
+	if got[0] != "a:detected" || got[1] != "b:detected" || got[2] != "a:minted" {
+		t.Fatalf("unexpected messages: %v", got)
+	}
+}
+
+func TestNATSProducerUpdatesExistingStream(t *testing.T) {
+	url := queuetest.RunNATSServer(t)
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the stream was created for other subjects
+	if _, err := js.AddStream(&nats.StreamConfig{Name: config.DefaultQueueNATSStream, Subjects: []string{"other.>"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	producer, err := NewNATSProducer(newTestConfig(config.QueueBackendNATS, url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer producer.Close()
+	info, err := js.StreamInfo(config.DefaultQueueNATSStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !streamCaptures(info.Config.Subjects, "lorenzo.deposits.>") || !streamCaptures(info.Config.Subjects, "other.>") {
+		t.Fatalf("unexpected stream subjects: %v", info.Config.Subjects)
+	}
+	messages := []Message{{Chain: "btc", Id: "btc:1", Value: []byte("{}")}}
+	if err := producer.Produce(context.Background(), "lorenzo.deposits.btc", messages); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubjectCovers(t *testing.T) {
+	testCases := []struct {
+		filter  string
+		subject string
+		expect  bool
+	}{
+		{"lorenzo.deposits.>", "lorenzo.deposits.>", true},
+		{"lorenzo.>", "lorenzo.deposits.>", true},
+		{">", "lorenzo.deposits.>", true},
+		{"*.deposits.>", "lorenzo.deposits.>", true},
+		{"lorenzo.deposits.*", "lorenzo.deposits.>", false},
+		{"lorenzo.deposits.btc", "lorenzo.deposits.>", false},
+		{"other.>", "lorenzo.deposits.>", false},
+		{"lorenzo.deposits.>", "lorenzo.>", false},
+	}
+	for _, tc := range testCases {
+		if got := subjectCovers(tc.filter, tc.subject); got != tc.expect {
+			t.Errorf("subjectCovers(%s, %s) = %v, expect %v", tc.filter, tc.subject, got, tc.expect)
+		}
+	}
+}
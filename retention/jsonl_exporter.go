@@ -0,0 +1,70 @@
+package retention
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+// JSONLExporter exports each batch of archived deposits into a gzip compressed JSONL file,
+// named by the table and the ids of the batch, e.g. btc_deposit_tx-1-500.jsonl.gz.
+// A file is complete once it is renamed from its .tmp name, a batch exported again overwrites its file.
+type JSONLExporter struct {
+	dir string
+}
+
+func NewJSONLExporter(dir string) (*JSONLExporter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &JSONLExporter{dir: dir}, nil
+}
+
+func (e *JSONLExporter) ExportBtcDepositTxs(txs []*db.ArchivedBtcDepositTx) error {
+	return writeJSONL(e.dir, db.BtcDepositTx{}.TableName(), txs[0].Id, txs[len(txs)-1].Id, txs)
+}
+
+func (e *JSONLExporter) ExportWrappedBTCDepositTxs(txs []*db.ArchivedWrappedBTCDepositTx) error {
+	return writeJSONL(e.dir, db.WrappedBTCDepositTx{}.TableName(), txs[0].Id, txs[len(txs)-1].Id, txs)
+}
+
+func writeJSONL[T any](dir string, table string, firstId int, lastId int, rows []T) (err error) {
+	name := fmt.Sprintf("%s-%d-%d.jsonl.gz", table, firstId, lastId)
+	path := filepath.Join(dir, name)
+	tmpPath := path + ".tmp"
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	writer := gzip.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,162 @@
+package retention
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+// Job strips and archives old handled deposits periodically, and exports the archived deposits in jsonl mode
+// after they are committed. Every batch is a short transaction, pending deposits and the sync checkpoints are never touched.
+type Job struct {
+	logger     *zap.SugaredLogger
+	cfg        config.Retention
+	repository db.IRetentionRepository
+	// exporter is nil in table mode
+	exporter db.DepositExporter
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+func NewJob(logger *zap.SugaredLogger, cfg config.Retention, repository db.IRetentionRepository) (*Job, error) {
+	var exporter db.DepositExporter
+	switch cfg.ArchiveMode {
+	case config.ArchiveModeTable:
+	case config.ArchiveModeJSONL:
+		jsonlExporter, err := NewJSONLExporter(cfg.ArchiveDir)
+		if err != nil {
+			return nil, err
+		}
+		exporter = jsonlExporter
+	default:
+		return nil, fmt.Errorf("unsupported retention archive mode: %s", cfg.ArchiveMode)
+	}
+
+	return &Job{
+		logger:     logger.Named("retention"),
+		cfg:        cfg,
+		repository: repository,
+		exporter:   exporter,
+		quit:       make(chan struct{}),
+	}, nil
+}
+
+func (j *Job) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		j.loop()
+	}()
+}
+
+func (j *Job) Stop() {
+	close(j.quit)
+}
+
+func (j *Job) WaitForShutdown() {
+	j.wg.Wait()
+}
+
+func (j *Job) loop() {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := j.RunOnce(time.Now()); err != nil {
+			j.logger.Errorf("Failed to run retention, error: %v", err)
+		}
+
+		select {
+		case <-j.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce strips, archives and exports the deposits older than the configured ages at now,
+// batch by batch until nothing is left or the job is stopped
+func (j *Job) RunOnce(now time.Time) error {
+	if j.cfg.StripProofsAfter > 0 {
+		stripped, err := j.runBatches(func() (int, error) {
+			return j.repository.StripWrappedBTCDepositProofs(now.Add(-j.cfg.StripProofsAfter), j.cfg.BatchSize)
+		})
+		if stripped > 0 {
+			j.logger.Infof("Stripped receipts and proofs of %d BNB deposits", stripped)
+		}
+		if err != nil {
+			return fmt.Errorf("strip proofs: %w", err)
+		}
+	}
+
+	if j.cfg.ArchiveAfter > 0 {
+		before := now.Add(-j.cfg.ArchiveAfter)
+		archived, err := j.runBatches(func() (int, error) {
+			return j.repository.ArchiveBtcDepositTxs(before, j.cfg.BatchSize)
+		})
+		if archived > 0 {
+			j.logger.Infof("Archived %d BTC deposits", archived)
+		}
+		if err != nil {
+			return fmt.Errorf("archive BTC deposits: %w", err)
+		}
+
+		archived, err = j.runBatches(func() (int, error) {
+			return j.repository.ArchiveWrappedBTCDepositTxs(before, j.cfg.BatchSize)
+		})
+		if archived > 0 {
+			j.logger.Infof("Archived %d BNB deposits", archived)
+		}
+		if err != nil {
+			return fmt.Errorf("archive BNB deposits: %w", err)
+		}
+	}
+
+	// deposits left by a failed export of a previous run are exported as well
+	if j.exporter != nil {
+		exported, err := j.runBatches(func() (int, error) {
+			return j.repository.ExportArchivedBtcDepositTxs(j.cfg.BatchSize, j.exporter)
+		})
+		if exported > 0 {
+			j.logger.Infof("Exported %d archived BTC deposits", exported)
+		}
+		if err != nil {
+			return fmt.Errorf("export BTC deposits: %w", err)
+		}
+
+		exported, err = j.runBatches(func() (int, error) {
+			return j.repository.ExportArchivedWrappedBTCDepositTxs(j.cfg.BatchSize, j.exporter)
+		})
+		if exported > 0 {
+			j.logger.Infof("Exported %d archived BNB deposits", exported)
+		}
+		if err != nil {
+			return fmt.Errorf("export BNB deposits: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// runBatches runs the batch until it handles less than a full batch, it returns the total handled rows
+func (j *Job) runBatches(batch func() (int, error)) (int, error) {
+	total := 0
+	for {
+		select {
+		case <-j.quit:
+			return total, nil
+		default:
+		}
+
+		n, err := batch()
+		total += n
+		if err != nil || n < j.cfg.BatchSize {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+func TestJobArchiveToJSONL(t *testing.T) {
+	dbCfg := config.Database{Driver: config.DatabaseDriverSQLite, DBName: filepath.Join(t.TempDir(), "submitter.db")}
+	handle, err := db.Open(dbCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrate(handle, dbCfg.Driver); err != nil {
+		t.Fatal(err)
+	}
+	btcRepository, _ := db.NewBTCRepository(handle)
+	repository, _ := db.NewRetentionRepository(handle)
+
+	var txs []*db.BtcDepositTx
+	for _, txid := range []string{"a", "b", "c"} {
+		txs = append(txs, &db.BtcDepositTx{Txid: txid, Amount: 1, Height: 10, BlockTime: time.Unix(1700000000, 0)})
+	}
+	if err := btcRepository.InsertBtcDepositTxs(txs, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, txid := range []string{"a", "b"} {
+		if err := btcRepository.UpdateTxStatus(txid, db.StatusSuccess, db.StatusChange{Actor: db.ActorRelayer}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archiveDir := filepath.Join(t.TempDir(), "archive")
+	cfg := config.Retention{
+		Enabled:      true,
+		Interval:     time.Hour,
+		ArchiveAfter: time.Hour,
+		ArchiveMode:  config.ArchiveModeJSONL,
+		ArchiveDir:   archiveDir,
+		BatchSize:    1,
+	}
+	job, err := NewJob(zap.NewNop().Sugar(), cfg, repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := job.RunOnce(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(archiveDir, "btc_deposit_tx-*.jsonl.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expect a file per batch, got %v", files)
+	}
+	var archived []string
+	for _, file := range files {
+		for _, tx := range readJSONL(t, file) {
+			archived = append(archived, tx.Txid)
+		}
+	}
+	if len(archived) != 2 || archived[0] != "a" || archived[1] != "b" {
+		t.Errorf("unexpected archived deposits: %v", archived)
+	}
+	if pending, _ := btcRepository.GetUnhandledBtcDepositTxs(100); len(pending) != 1 || pending[0].Txid != "c" {
+		t.Errorf("pending deposit should be kept: %+v", pending)
+	}
+}
+
+func readJSONL(t *testing.T, path string) []*db.BtcDepositTx {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var txs []*db.BtcDepositTx
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var tx db.BtcDepositTx
+		if err := json.Unmarshal(scanner.Bytes(), &tx); err != nil {
+			t.Fatal(err)
+		}
+		txs = append(txs, &tx)
+	}
+	return txs
+}
@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+const tracerName = "github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter"
+
+// attribute keys of the spans
+const (
+	ChainKey       = attribute.Key("chain")
+	DepositTxidKey = attribute.Key("deposit.txid")
+	LogIndexKey    = attribute.Key("deposit.log_index")
+	BlockHeightKey = attribute.Key("block.height")
+	BlockRangeKey  = attribute.Key("block.range")
+)
+
+// Init installs the global tracer provider of the exporter, the returned function flushes and stops it.
+// The none exporter keeps the no-op provider of otel.
+func Init(cfg config.Tracing) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case config.TracingExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case config.TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case config.TracingExporterOTLP:
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(context.Background(), options...)
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span of the global tracer provider
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Call runs the call in a span, e.g. a BTC api, BNB rpc, Lorenzo or DB call
+func Call[T any](ctx context.Context, name string, call func() (T, error), attrs ...attribute.KeyValue) (T, error) {
+	_, span := Start(ctx, name, attrs...)
+	result, err := call()
+	End(span, err)
+	return result, err
+}
+
+// Run runs the call without result in a span
+func Run(ctx context.Context, name string, call func() error, attrs ...attribute.KeyValue) error {
+	_, span := Start(ctx, name, attrs...)
+	err := call()
+	End(span, err)
+	return err
+}
+
+// DepositContext returns the context of the deposit trace. The trace id is derived from the chain and the txid,
+// so the spans of a deposit are in one trace across the scan and submit loops and restarts of the submitter.
+// The root span of the trace is never exported.
+func DepositContext(ctx context.Context, chain string, txid string) context.Context {
+	return trace.ContextWithRemoteSpanContext(ctx, DepositSpanContext(chain, txid))
+}
+
+// DepositSpanContext is the span context of the deposit trace, for links from the spans of a block
+func DepositSpanContext(chain string, txid string) trace.SpanContext {
+	hash := sha256.Sum256([]byte(chain + "/" + txid))
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	copy(traceID[:], hash[:16])
+	copy(spanID[:], hash[16:24])
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+}
+
+// DepositLinks links a span of a block to the traces of its deposits
+func DepositLinks(chain string, txids []string) trace.SpanStartOption {
+	links := make([]trace.Link, 0, len(txids))
+	for _, txid := range txids {
+		links = append(links, trace.Link{
+			SpanContext: DepositSpanContext(chain, txid),
+			Attributes:  []attribute.KeyValue{DepositTxidKey.String(txid)},
+		})
+	}
+	return trace.WithLinks(links...)
+}
+
+// StartWithLinks starts a span of the global tracer provider with the options, e.g. DepositLinks
+func StartWithLinks(ctx context.Context, name string, links trace.SpanStartOption, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, links, trace.WithAttributes(attrs...))
+}
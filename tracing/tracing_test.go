@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestDepositSpansShareTrace(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	// the scan and submit loops trace the deposit independently
+	scanCtx := DepositContext(context.Background(), "btc", "txid-1")
+	_, err := Call(scanCtx, "db.InsertBtcDepositTxs", func() (int, error) { return 1, nil })
+	require.NoError(t, err)
+	submitCtx := DepositContext(context.Background(), "btc", "txid-1")
+	callErr := errors.New("rpc down")
+	err = Run(submitCtx, "lorenzo.CreateBTCStakingWithBTCProof", func() error { return callErr })
+	require.ErrorIs(t, err, callErr)
+	otherCtx := DepositContext(context.Background(), "bnb", "txid-1")
+	require.NoError(t, Run(otherCtx, "db.MarkSuccess", func() error { return nil }))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	require.Equal(t, spans[0].SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+	require.NotEqual(t, spans[0].SpanContext().TraceID(), spans[2].SpanContext().TraceID())
+	require.Equal(t, DepositSpanContext("btc", "txid-1").SpanID(), spans[0].Parent().SpanID())
+
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, "rpc down", spans[1].Status().Description)
+}
+
+func TestDepositLinks(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	_, span := StartWithLinks(context.Background(), "db.InsertWrappedBTCDepositTxs", DepositLinks("bnb", []string{"a", "b"}))
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	links := spans[0].Links()
+	require.Len(t, links, 2)
+	require.Equal(t, DepositSpanContext("bnb", "a").TraceID(), links[0].SpanContext.TraceID())
+	require.Equal(t, DepositSpanContext("bnb", "b").TraceID(), links[1].SpanContext.TraceID())
+}
+
+func TestInit(t *testing.T) {
+	shutdown, err := Init(config.Tracing{Exporter: config.TracingExporterNone})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	shutdown, err = Init(config.Tracing{Exporter: config.TracingExporterStdout, SampleRatio: 1, ServiceName: "test"})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	_, err = Init(config.Tracing{Exporter: "jaeger"})
+	require.Error(t, err)
+}
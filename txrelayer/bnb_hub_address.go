@@ -0,0 +1,94 @@
+package txrelayer
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+// hubAddressRange is a block range scanned against one stake plan hub address
+type hubAddressRange struct {
+	address common.Address
+	start   uint64
+	end     uint64
+}
+
+type hubAddressRecord struct {
+	address       common.Address
+	effectiveFrom uint64
+}
+
+// hubAddressHistory records the stake plan hub addresses and the BNB heights they are effective from,
+// it mirrors the history persisted in the repository
+type hubAddressHistory struct {
+	lock    sync.RWMutex
+	records []hubAddressRecord
+}
+
+// newHubAddressHistory creates the history of the persisted addresses, which must not be empty
+func newHubAddressHistory(addresses []*db.HubAddress) *hubAddressHistory {
+	h := &hubAddressHistory{}
+	for _, address := range addresses {
+		h.records = append(h.records, hubAddressRecord{
+			address:       common.HexToAddress(address.Address),
+			effectiveFrom: address.EffectiveFrom,
+		})
+	}
+	return h
+}
+
+// current returns the latest stake plan hub address
+func (h *hubAddressHistory) current() common.Address {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.records[len(h.records)-1].address
+}
+
+// add records a new address effective from the given height, later records are replaced
+func (h *hubAddressHistory) add(address common.Address, effectiveFrom uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	i := len(h.records)
+	for i > 0 && h.records[i-1].effectiveFrom >= effectiveFrom {
+		i--
+	}
+	h.records = append(h.records[:i], hubAddressRecord{address: address, effectiveFrom: effectiveFrom})
+}
+
+// addressAt returns the address effective at the given height
+func (h *hubAddressHistory) addressAt(height uint64) common.Address {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	for i := len(h.records) - 1; i > 0; i-- {
+		if height >= h.records[i].effectiveFrom {
+			return h.records[i].address
+		}
+	}
+	return h.records[0].address
+}
+
+// split splits [start, end] into ranges with the address effective for each of them
+func (h *hubAddressHistory) split(start, end uint64) []hubAddressRange {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	var ranges []hubAddressRange
+	for i, record := range h.records {
+		rangeStart := max(start, record.effectiveFrom)
+		rangeEnd := end
+		if i+1 < len(h.records) && h.records[i+1].effectiveFrom <= end {
+			rangeEnd = h.records[i+1].effectiveFrom - 1
+		}
+		if rangeStart > rangeEnd {
+			continue
+		}
+		ranges = append(ranges, hubAddressRange{address: record.address, start: rangeStart, end: rangeEnd})
+	}
+
+	return ranges
+}
@@ -0,0 +1,87 @@
+package txrelayer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+func TestHubAddressHistory(t *testing.T) {
+	addr1 := common.HexToAddress("0x01")
+	addr2 := common.HexToAddress("0x02")
+	addr3 := common.HexToAddress("0x03")
+
+	h := newHubAddressHistory([]*db.HubAddress{{Address: addr1.Hex()}})
+	if got := h.split(10, 20); !reflect.DeepEqual(got, []hubAddressRange{{addr1, 10, 20}}) {
+		t.Errorf("unexpected ranges: %v", got)
+	}
+
+	h.add(addr2, 15)
+	h.add(addr3, 30)
+	if h.current() != addr3 {
+		t.Errorf("unexpected current address: %s", h.current().Hex())
+	}
+	if h.addressAt(14) != addr1 || h.addressAt(15) != addr2 || h.addressAt(29) != addr2 || h.addressAt(30) != addr3 {
+		t.Error("unexpected address at height")
+	}
+
+	expect := []hubAddressRange{{addr1, 10, 14}, {addr2, 15, 29}, {addr3, 30, 40}}
+	if got := h.split(10, 40); !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected ranges: %v, expect: %v", got, expect)
+	}
+	if got := h.split(16, 20); !reflect.DeepEqual(got, []hubAddressRange{{addr2, 16, 20}}) {
+		t.Errorf("unexpected ranges: %v", got)
+	}
+
+	// a record with an earlier height replaces the later ones
+	h.add(addr1, 20)
+	expect = []hubAddressRange{{addr1, 10, 14}, {addr2, 15, 19}, {addr1, 20, 40}}
+	if got := h.split(10, 40); !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected ranges: %v, expect: %v", got, expect)
+	}
+}
+
+func TestLoadHubAddresses(t *testing.T) {
+	addr1 := common.HexToAddress("0x01")
+	addr2 := common.HexToAddress("0x02")
+	repository := db.NewMemoryBNBRepository(BNBChainName)
+	if err := repository.UpdateSyncPoint(100, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &BNBTxRelayer{repository: repository, logger: zap.NewNop().Sugar()}
+	if err := r.loadHubAddresses(addr1); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.hubAddresses.split(1, 200); !reflect.DeepEqual(got, []hubAddressRange{{addr1, 1, 200}}) {
+		t.Errorf("unexpected ranges: %v", got)
+	}
+
+	// the address changed while the relayer was stopped, the history before it is kept across the restart
+	if err := repository.UpdateSyncPoint(120, ""); err != nil {
+		t.Fatal(err)
+	}
+	r = &BNBTxRelayer{repository: repository, logger: zap.NewNop().Sugar()}
+	if err := r.loadHubAddresses(addr2); err != nil {
+		t.Fatal(err)
+	}
+	expect := []hubAddressRange{{addr1, 1, 120}, {addr2, 121, 200}}
+	if got := r.hubAddresses.split(1, 200); !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected ranges: %v, expect: %v", got, expect)
+	}
+	if height, _ := repository.GetSyncPoint(); height != 120 {
+		t.Errorf("sync point should be kept, got: %d", height)
+	}
+
+	r = &BNBTxRelayer{repository: repository, logger: zap.NewNop().Sugar()}
+	if err := r.loadHubAddresses(addr2); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.hubAddresses.split(1, 200); !reflect.DeepEqual(got, expect) {
+		t.Errorf("unexpected ranges after restart: %v, expect: %v", got, expect)
+	}
+}
@@ -0,0 +1,159 @@
+package txrelayer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	plantypes "github.com/Lorenzo-Protocol/lorenzo/v3/x/plan/types"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/bnbclient"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+)
+
+// reasons of rejected stake plans stored on the deposit rows
+const (
+	PlanNotFoundReason               = "plan not found"
+	BtcContractAddressMismatchReason = "btc contract address mismatch"
+	SiblingEventRejectedReason       = "another event of the tx is rejected"
+	ValueOverflowReason              = "value exceeds uint64"
+)
+
+// errPlanPaused is returned for events of a paused plan, they stay pending until the plan is unpaused
+var errPlanPaused = errors.New("stake plan is paused")
+
+// stakePlanQuerier queries plans from the Lorenzo plan module
+type stakePlanQuerier interface {
+	Plan(planId uint64) (plantypes.Plan, error)
+}
+
+// stakePlanValidator checks the stake plans of BNB deposit events before they are submitted
+type stakePlanValidator struct {
+	querier stakePlanQuerier
+	// the expected wrapped BTC token, the check is skipped if it is the zero address
+	btcContractAddress common.Address
+
+	// plans queried in the current submit round, nil if the plan is not found
+	plans map[uint64]*plantypes.Plan
+}
+
+func newStakePlanValidator(querier stakePlanQuerier, btcContractAddress common.Address) *stakePlanValidator {
+	return &stakePlanValidator{
+		querier:            querier,
+		btcContractAddress: btcContractAddress,
+		plans:              make(map[uint64]*plantypes.Plan),
+	}
+}
+
+// reset drops the queried plans, plans are queried again in the next submit round
+func (v *stakePlanValidator) reset() {
+	v.plans = make(map[uint64]*plantypes.Plan)
+}
+
+// validate returns the reason why the stake plan of the event is rejected, or "" if it is valid.
+// An error is returned if the plan cannot be queried or is paused, the event should be retried later.
+func (v *stakePlanValidator) validate(tx *db.WrappedBTCDepositTx) (string, error) {
+	if tx.EventName != bnbclient.StakeBTC2JoinStakePlanEventName {
+		return "", nil
+	}
+	if reason := overflowReason(tx); reason != "" {
+		return reason, nil
+	}
+
+	plan, err := v.plan(tx.PlanId)
+	if err != nil {
+		return "", err
+	}
+	if plan == nil {
+		return fmt.Sprintf("%s, planId: %d", PlanNotFoundReason, tx.PlanId), nil
+	}
+	if plan.Enabled != plantypes.PlanStatus_Unpause {
+		return "", fmt.Errorf("%w, planId: %d", errPlanPaused, tx.PlanId)
+	}
+	if v.btcContractAddress != (common.Address{}) &&
+		common.HexToAddress(tx.BtcContractAddress) != v.btcContractAddress {
+		return fmt.Sprintf("%s, expect: %s, got: %s", BtcContractAddressMismatchReason, v.btcContractAddress.Hex(), tx.BtcContractAddress), nil
+	}
+
+	return "", nil
+}
+
+// overflowReason checks the uint256 stake index and plan id in the event data, the columns of the row are uint64
+// and hold 0 if the value of the event exceeds it
+func overflowReason(tx *db.WrappedBTCDepositTx) string {
+	var event struct {
+		StakeIndex *big.Int
+		PlanId     *big.Int
+	}
+	if err := json.Unmarshal([]byte(tx.EventData), &event); err != nil {
+		return ""
+	}
+	if event.StakeIndex != nil && !event.StakeIndex.IsUint64() {
+		return fmt.Sprintf("%s, stakeIndex: %s", ValueOverflowReason, event.StakeIndex)
+	}
+	if event.PlanId != nil && !event.PlanId.IsUint64() {
+		return fmt.Sprintf("%s, planId: %s", ValueOverflowReason, event.PlanId)
+	}
+	return ""
+}
+
+// validateTx validates all events of a tx, they share one receipt so the tx is rejected
+// if any of them is rejected. It returns the reasons by log index, or nil if the tx is valid.
+func (v *stakePlanValidator) validateTx(txs []*db.WrappedBTCDepositTx) (map[uint]string, error) {
+	reasons := make(map[uint]string)
+	var rejectedLogIndex []string
+	for _, tx := range txs {
+		reason, err := v.validate(tx)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			reasons[tx.LogIndex] = reason
+			rejectedLogIndex = append(rejectedLogIndex, fmt.Sprint(tx.LogIndex))
+		}
+	}
+	if len(reasons) == 0 {
+		return nil, nil
+	}
+
+	for _, tx := range txs {
+		if _, ok := reasons[tx.LogIndex]; !ok {
+			reasons[tx.LogIndex] = fmt.Sprintf("%s, logIndex: %s", SiblingEventRejectedReason, strings.Join(rejectedLogIndex, ","))
+		}
+	}
+	return reasons, nil
+}
+
+// agentLabel is the metrics agent label of the event from the plans queried in the current submit round
+func (v *stakePlanValidator) agentLabel(tx *db.WrappedBTCDepositTx) string {
+	if tx.EventName != bnbclient.StakeBTC2JoinStakePlanEventName {
+		return ""
+	}
+	plan := v.plans[tx.PlanId]
+	if plan == nil {
+		return ""
+	}
+	return metrics.AgentLabel(plan.AgentId)
+}
+
+func (v *stakePlanValidator) plan(planId uint64) (*plantypes.Plan, error) {
+	if plan, ok := v.plans[planId]; ok {
+		return plan, nil
+	}
+
+	plan, err := v.querier.Plan(planId)
+	if err != nil {
+		if !strings.Contains(err.Error(), LorenzoPlanNotFoundErrorMessage) {
+			return nil, err
+		}
+		v.plans[planId] = nil
+		return nil, nil
+	}
+
+	v.plans[planId] = &plan
+	return &plan, nil
+}
@@ -0,0 +1,113 @@
+package txrelayer
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	plantypes "github.com/Lorenzo-Protocol/lorenzo/v3/x/plan/types"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/bnbclient"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+type testPlanQuerier struct {
+	plans   map[uint64]plantypes.Plan
+	err     error
+	queries int
+}
+
+func (q *testPlanQuerier) Plan(planId uint64) (plantypes.Plan, error) {
+	q.queries++
+	if q.err != nil {
+		return plantypes.Plan{}, q.err
+	}
+	plan, ok := q.plans[planId]
+	if !ok {
+		return plantypes.Plan{}, errors.New("rpc error: code = Unknown desc = plan not found")
+	}
+	return plan, nil
+}
+
+func TestStakePlanValidator(t *testing.T) {
+	btcb := common.HexToAddress("0x7130d2A12B9BCbFAe4f2634d864A1Ee1Ce3Ead9c")
+	querier := &testPlanQuerier{plans: map[uint64]plantypes.Plan{
+		1: {Id: 1, Enabled: plantypes.PlanStatus_Unpause},
+		2: {Id: 2, Enabled: plantypes.PlanStatus_Pause},
+	}}
+	v := newStakePlanValidator(querier, btcb)
+
+	newTx := func(logIndex uint, planId uint64, btcContractAddress common.Address) *db.WrappedBTCDepositTx {
+		return &db.WrappedBTCDepositTx{
+			Txid:               "0xa1",
+			LogIndex:           logIndex,
+			EventName:          bnbclient.StakeBTC2JoinStakePlanEventName,
+			PlanId:             planId,
+			BtcContractAddress: btcContractAddress.Hex(),
+		}
+	}
+
+	testCases := []struct {
+		tx     *db.WrappedBTCDepositTx
+		reason string
+	}{
+		{newTx(0, 1, btcb), ""},
+		{newTx(0, 3, btcb), PlanNotFoundReason},
+		{newTx(0, 1, common.HexToAddress("0x01")), BtcContractAddressMismatchReason},
+	}
+	for i, tc := range testCases {
+		reason, err := v.validate(tc.tx)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if !strings.HasPrefix(reason, tc.reason) || (tc.reason == "") != (reason == "") {
+			t.Errorf("case %d: unexpected reason: %q, expect: %q", i, reason, tc.reason)
+		}
+	}
+	if querier.queries != 2 {
+		t.Errorf("plans should be queried once per round, got %d queries", querier.queries)
+	}
+
+	// a plan id exceeding uint64 is rejected without querying the truncated plan
+	overflow := newTx(0, 0, btcb)
+	planId, _ := new(big.Int).SetString("18446744073709551617", 10)
+	eventData, err := json.Marshal(&bnbclient.StakeBTC2JoinStakePlanEvent{StakeIndex: big.NewInt(1), PlanId: planId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overflow.EventData = string(eventData)
+	if reason, err := v.validate(overflow); err != nil || !strings.HasPrefix(reason, ValueOverflowReason) ||
+		!strings.Contains(reason, "18446744073709551617") || querier.queries != 2 {
+		t.Errorf("unexpected reason: %q, error: %v", reason, err)
+	}
+
+	// a paused plan is retried instead of rejecting the event, the whole tx waits for it
+	if reason, err := v.validate(newTx(0, 2, btcb)); !errors.Is(err, errPlanPaused) || reason != "" {
+		t.Errorf("expect paused plan retried, got reason: %q, error: %v", reason, err)
+	}
+	if reasons, err := v.validateTx([]*db.WrappedBTCDepositTx{newTx(0, 1, btcb), newTx(1, 2, btcb)}); !errors.Is(err, errPlanPaused) || reasons != nil {
+		t.Errorf("expect tx of paused plan retried, got reasons: %v, error: %v", reasons, err)
+	}
+
+	// all events of the tx are rejected with one invalid event
+	reasons, err := v.validateTx([]*db.WrappedBTCDepositTx{newTx(0, 1, btcb), newTx(1, 3, btcb)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(reasons[0], SiblingEventRejectedReason) || !strings.HasPrefix(reasons[1], PlanNotFoundReason) {
+		t.Errorf("unexpected reasons: %v", reasons)
+	}
+	if reasons, err := v.validateTx([]*db.WrappedBTCDepositTx{newTx(0, 1, btcb)}); err != nil || reasons != nil {
+		t.Errorf("expect valid tx, got reasons: %v, error: %v", reasons, err)
+	}
+
+	// query errors are retried instead of rejecting the event
+	v.reset()
+	querier.err = errors.New("context deadline exceeded")
+	if _, err := v.validate(newTx(0, 1, btcb)); err == nil {
+		t.Error("expect query error")
+	}
+}
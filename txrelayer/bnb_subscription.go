@@ -0,0 +1,140 @@
+package txrelayer
+
+import (
+	"sort"
+	"sync"
+
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// bnbSubscriptionState collects hub event logs and the chain tip received from
+// websocket subscriptions. Logs of blocks from coveredFrom on are complete while the subscription is active.
+type bnbSubscriptionState struct {
+	lock sync.Mutex
+
+	active      bool
+	coveredFrom uint64
+	tip         uint64
+	logs        map[uint64][]ethtypes.Log
+	// generation changes whenever the collected logs stop being complete, a snapshot of an older
+	// generation must not be persisted
+	generation uint64
+}
+
+func newBnbSubscriptionState() *bnbSubscriptionState {
+	return &bnbSubscriptionState{
+		logs: make(map[uint64][]ethtypes.Log),
+	}
+}
+
+// reset marks the subscription inactive and drops the collected logs, the gap is backfilled by polling
+func (s *bnbSubscriptionState) reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.active = false
+	s.coveredFrom = 0
+	s.tip = 0
+	s.logs = make(map[uint64][]ethtypes.Log)
+	s.generation++
+}
+
+// setTip records a new head, it returns true on the first head of a subscription
+func (s *bnbSubscriptionState) setTip(number uint64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	first := !s.active
+	if first {
+		s.active = true
+		s.coveredFrom = number
+	}
+	if number > s.tip {
+		s.tip = number
+	}
+	return first
+}
+
+// addLog adds a log, or removes it if the log is reverted by a chain reorganisation
+func (s *bnbSubscriptionState) addLog(log ethtypes.Log) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blockLogs := s.logs[log.BlockNumber]
+	for i, l := range blockLogs {
+		if l.BlockHash == log.BlockHash && l.TxHash == log.TxHash && l.Index == log.Index {
+			if log.Removed {
+				s.logs[log.BlockNumber] = append(blockLogs[:i], blockLogs[i+1:]...)
+			}
+			return
+		}
+	}
+	if log.Removed {
+		return
+	}
+
+	s.logs[log.BlockNumber] = append(blockLogs, log)
+}
+
+// chainTip returns the latest head number and whether the subscription is active
+func (s *bnbSubscriptionState) chainTip() (uint64, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.tip, s.active
+}
+
+// snapshot returns the logs of blocks in [start, end] ordered by block number and log index, and the generation
+// they belong to. ok is false unless all logs of blocks from start on have been received.
+func (s *bnbSubscriptionState) snapshot(start, end uint64) (logs []ethtypes.Log, generation uint64, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.active || start < s.coveredFrom {
+		return nil, s.generation, false
+	}
+	for number, blockLogs := range s.logs {
+		if number >= start && number <= end {
+			logs = append(logs, blockLogs...)
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+
+	return logs, s.generation, true
+}
+
+// unchanged returns true if the logs of the snapshot of the generation are still complete
+func (s *bnbSubscriptionState) unchanged(generation uint64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.active && s.generation == generation
+}
+
+// uncover marks the logs of blocks up to end as not received, e.g. after they are pruned before a rescan
+func (s *bnbSubscriptionState) uncover(end uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.active && s.coveredFrom <= end {
+		s.coveredFrom = end + 1
+		s.generation++
+	}
+}
+
+// pruneLogs drops the logs of blocks up to end which have been persisted
+func (s *bnbSubscriptionState) pruneLogs(end uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for number := range s.logs {
+		if number <= end {
+			delete(s.logs, number)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package txrelayer
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+func TestBnbSubscriptionState(t *testing.T) {
+	s := newBnbSubscriptionState()
+	if _, active := s.chainTip(); active {
+		t.Fatal("subscription should be inactive before the first head")
+	}
+
+	if first := s.setTip(100); !first {
+		t.Error("expect first head")
+	}
+	if first := s.setTip(101); first {
+		t.Error("expect not first head")
+	}
+	if tip, active := s.chainTip(); !active || tip != 101 {
+		t.Errorf("unexpected chain tip: %d, active: %v", tip, active)
+	}
+	if _, _, ok := s.snapshot(99, 101); ok {
+		t.Error("subscription should not cover blocks before 100")
+	}
+	if _, _, ok := s.snapshot(100, 101); !ok {
+		t.Error("subscription should cover blocks from 100")
+	}
+
+	log1 := ethtypes.Log{BlockNumber: 101, BlockHash: common.HexToHash("0x01"), TxHash: common.HexToHash("0xa1"), Index: 3}
+	log2 := ethtypes.Log{BlockNumber: 100, BlockHash: common.HexToHash("0x02"), TxHash: common.HexToHash("0xa2"), Index: 1}
+	log3 := ethtypes.Log{BlockNumber: 101, BlockHash: common.HexToHash("0x01"), TxHash: common.HexToHash("0xa3"), Index: 1}
+	s.addLog(log1)
+	s.addLog(log1)
+	s.addLog(log2)
+	s.addLog(log3)
+
+	logs, _, _ := s.snapshot(100, 101)
+	if len(logs) != 3 {
+		t.Fatalf("expect 3 logs, got %d", len(logs))
+	}
+	if logs[0].TxHash != log2.TxHash || logs[1].TxHash != log3.TxHash || logs[2].TxHash != log1.TxHash {
+		t.Error("logs are not ordered by block number and log index")
+	}
+
+	// reorg removes the log
+	removed := log1
+	removed.Removed = true
+	s.addLog(removed)
+	if logs, _, _ := s.snapshot(101, 101); len(logs) != 1 || logs[0].TxHash != log3.TxHash {
+		t.Errorf("removed log should be dropped, got %v", logs)
+	}
+
+	s.pruneLogs(100)
+	if logs, _, _ := s.snapshot(100, 200); len(logs) != 1 {
+		t.Errorf("expect 1 log after prune, got %d", len(logs))
+	}
+	// the pruned blocks are polled again on rescan
+	s.uncover(100)
+	if _, _, ok := s.snapshot(100, 200); ok {
+		t.Error("subscription should not cover blocks up to 100 after uncover")
+	}
+	logs, generation, ok := s.snapshot(101, 200)
+	if !ok || len(logs) != 1 || !s.unchanged(generation) {
+		t.Error("subscription should cover blocks from 101 after uncover")
+	}
+
+	s.reset()
+	if _, active := s.chainTip(); active || s.unchanged(generation) {
+		t.Error("subscription should be inactive after reset")
+	}
+	if _, _, ok := s.snapshot(101, 200); ok {
+		t.Error("subscription should not cover blocks after reset")
+	}
+	s.setTip(300)
+	if logs, _, _ := s.snapshot(300, 400); len(logs) != 0 {
+		t.Errorf("logs should be dropped after reset, got %d", len(logs))
+	}
+}
+
+func TestBnbSubscribedLogsResetBeforeCommit(t *testing.T) {
+	hub := common.HexToAddress("0x0100")
+	r := &BNBTxRelayer{subscription: newBnbSubscriptionState(), hubAddresses: newHubAddressHistory([]*db.HubAddress{{Address: hub.Hex()}})}
+	r.subscription.setTip(100)
+	r.subscription.addLog(ethtypes.Log{Address: hub, BlockNumber: 100, TxHash: common.HexToHash("0xa1")})
+	r.subscription.addLog(ethtypes.Log{Address: common.HexToAddress("0x0200"), BlockNumber: 100, TxHash: common.HexToHash("0xa2")})
+
+	logs, generation, subscribed := r.subscribedLogs(100, 101)
+	if !subscribed || len(logs) != 1 || logs[0].TxHash != common.HexToHash("0xa1") {
+		t.Fatalf("unexpected subscribed logs: %v, subscribed: %v", logs, subscribed)
+	}
+	// the subscription drops between taking the logs and committing the sync point
+	r.subscription.reset()
+	r.subscription.setTip(101)
+	if r.subscription.unchanged(generation) {
+		t.Fatal("the logs taken before the reset must not be committed")
+	}
+	if _, _, subscribed := r.subscribedLogs(100, 101); subscribed {
+		t.Fatal("the range before the new subscription must be polled")
+	}
+}
+
+// the coverage and the logs are taken together, a concurrent reset cannot leave a covered range without its logs
+func TestBnbSubscriptionSnapshotConcurrentReset(t *testing.T) {
+	s := newBnbSubscriptionState()
+	log := ethtypes.Log{BlockNumber: 100, TxHash: common.HexToHash("0xa1")}
+	s.addLog(log)
+	s.setTip(100)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			s.reset()
+			s.addLog(log)
+			s.setTip(100)
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if logs, _, ok := s.snapshot(100, 100); ok && len(logs) != 1 {
+			t.Fatalf("covered range without its logs: %v", logs)
+		}
+	}
+}
@@ -2,26 +2,34 @@ package txrelayer
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	errorsmod "cosmossdk.io/errors"
 	lrzclient "github.com/Lorenzo-Protocol/lorenzo-sdk/v3/client"
-	"github.com/Lorenzo-Protocol/lorenzo/v3/x/btcstaking/types"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/bnbclient"
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/health"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/tracing"
 )
 
 const (
-	BatchPlanStakeBlockSizeFetch = uint64(1000)
-	DefaultDelayBlocks           = uint64(15)
+	DefaultDelayBlocks = uint64(15)
+
+	BNBChainName = "bnb"
 )
 
 type BNBTxRelayer struct {
@@ -31,15 +39,36 @@ type BNBTxRelayer struct {
 	lorenzoClient *lrzclient.Client
 	delayBlocks   uint64
 
-	repository          db.IBNBRepository
-	planStakeHubAddress common.Address
+	repository db.IBNBRepository
+
+	// stake plans of the events are validated before submitting
+	planValidator *stakePlanValidator
+
+	// stake plan hub addresses by BNB height, refreshed from the bnblightclient params
+	hubAddresses          *hubAddressHistory
+	paramsRefreshInterval time.Duration
+	lastParamsRefreshTime time.Time
+	lastParamsRefreshTip  uint64
+
+	// the block range of eth_getLogs adapts to rpc provider limits
+	fetchBlockSize    uint64
+	minFetchBlockSize uint64
+	maxFetchBlockSize uint64
+
+	// logs and chain tip from the optional websocket subscriptions
+	subscription  *bnbSubscriptionState
+	newHeadNotify chan struct{}
+	resubscribe   chan struct{}
+
+	// the loops are paused and the chain is rescanned by operators through the admin API
+	*loopControl
 
 	quit      chan struct{}
 	wg        sync.WaitGroup
 	submitter string
 }
 
-func NewBnbTxRelayer(cfg config.BNBTxRelayerConfig, lorenzoClient *lrzclient.Client, logger *zap.SugaredLogger) (*BNBTxRelayer, error) {
+func NewBnbTxRelayer(cfg config.BNBTxRelayerConfig, lorenzoClient *lrzclient.Client, repository db.IBNBRepository, logger *zap.SugaredLogger) (*BNBTxRelayer, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid BNB Tx-relayer config, error: %v", err)
 	}
@@ -49,13 +78,8 @@ func NewBnbTxRelayer(cfg config.BNBTxRelayerConfig, lorenzoClient *lrzclient.Cli
 		return nil, err
 	}
 
-	chainName := "bnb"
-	bnbClient, err := bnbclient.New(cfg.RpcUrl)
-	if err != nil {
-		return nil, err
-	}
-
-	repository, err := db.NewBNBRepository(chainName)
+	chainName := BNBChainName
+	bnbClient, err := bnbclient.NewWithWebSocket(cfg.RpcUrl, cfg.WsUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +88,7 @@ func NewBnbTxRelayer(cfg config.BNBTxRelayerConfig, lorenzoClient *lrzclient.Cli
 	if height, err := repository.GetSyncPoint(); err != nil {
 		return nil, err
 	} else if height == 0 {
-		if err := repository.UpdateSyncPoint(cfg.StartBlockHeight); err != nil {
+		if err := repository.UpdateSyncPoint(cfg.StartBlockHeight, ""); err != nil {
 			return nil, err
 		}
 	}
@@ -75,16 +99,31 @@ func NewBnbTxRelayer(cfg config.BNBTxRelayerConfig, lorenzoClient *lrzclient.Cli
 		lorenzoClient: lorenzoClient,
 		delayBlocks:   DefaultDelayBlocks,
 
-		repository: repository,
+		repository:    repository,
+		planValidator: newStakePlanValidator(lorenzoClient, common.HexToAddress(cfg.BtcContractAddress)),
+
+		fetchBlockSize:    cfg.MaxBlockRange,
+		minFetchBlockSize: cfg.MinBlockRange,
+		maxFetchBlockSize: cfg.MaxBlockRange,
+
+		subscription:  newBnbSubscriptionState(),
+		newHeadNotify: make(chan struct{}, 1),
+		resubscribe:   make(chan struct{}, 1),
+
+		paramsRefreshInterval: cfg.ParamsRefreshInterval,
 
-		planStakeHubAddress: common.HexToAddress(bnblightParams.Params.StakePlanHubAddress),
-		quit:                make(chan struct{}),
-		submitter:           lorenzoClient.MustGetAddr(),
+		loopControl: newLoopControl(repository),
+
+		quit:      make(chan struct{}),
+		submitter: lorenzoClient.MustGetAddr(),
 	}
 	txRelayer.logger = logger.Named(txRelayer.chainName)
+	if err := txRelayer.loadHubAddresses(common.HexToAddress(bnblightParams.Params.StakePlanHubAddress)); err != nil {
+		return nil, err
+	}
 
-	txRelayer.logger.Infof("new Relayer on BNB Smart Chain, confirmations: %d, submitter: %s, planStakeHubAddress: %s",
-		txRelayer.delayBlocks+1, txRelayer.submitter, txRelayer.planStakeHubAddress.Hex())
+	txRelayer.logger.Infof("new Relayer on BNB Smart Chain, confirmations: %d, submitter: %s, planStakeHubAddress: %s, blockRange: %d-%d, websocket: %v",
+		txRelayer.delayBlocks+1, txRelayer.submitter, txRelayer.hubAddresses.current().Hex(), cfg.MinBlockRange, cfg.MaxBlockRange, bnbClient.WebSocketEnabled())
 	return txRelayer, nil
 }
 
@@ -93,6 +132,16 @@ func (r *BNBTxRelayer) ChainName() string {
 }
 
 func (r *BNBTxRelayer) Start() {
+	health.RegisterLoop("bnb/scan")
+	health.RegisterLoop("bnb/submit")
+	if r.bnbClient.WebSocketEnabled() {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.subscribeLoop()
+		}()
+	}
+
 	r.wg.Add(1)
 	go func() {
 		defer r.wg.Done()
@@ -118,33 +167,90 @@ func (r *BNBTxRelayer) scanLoop() {
 		default:
 		}
 
-		syncPoint, err := r.repository.GetSyncPoint()
+		if previous, applied, err := r.applyRescan(); err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
+			r.logger.Errorf("failed to apply rescan: %v", err)
+		} else if applied {
+			// the subscribed logs up to the previous sync point are pruned, the range is polled again
+			r.subscription.uncover(previous)
+			r.logger.Warnf("rescan requested, sync point moved back from %d", previous)
+		}
+		if r.isPaused(LoopScan) {
+			health.Beat("bnb/scan")
+			time.Sleep(pausedLoopWaitTime)
+			continue
+		}
+
+		ctx := context.Background()
+		syncPoint, err := tracing.Call(ctx, "db.GetSyncPoint", r.repository.GetSyncPoint)
 		if err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
 			r.logger.Warnf("failed to get sync point: %v", err)
 			time.Sleep(networkErrorWaitTime)
 			continue
 		}
-		bnbChainTipNumber, err := r.bnbClient.BlockNumber()
-		if err != nil {
-			r.logger.Warnf("failed to get BNB chain tip number: %v", err)
+		metrics.SyncPoint.WithLabelValues(metrics.ChainBNB).Set(float64(syncPoint))
+		// the chain tip comes from the head subscription while it is active
+		bnbChainTipNumber, subscribed := r.subscription.chainTip()
+		if !subscribed {
+			bnbChainTipNumber, err = tracing.Call(ctx, "bnb.BlockNumber", r.bnbClient.BlockNumber)
+			if err != nil {
+				metrics.Error(metrics.ChainBNB, metrics.ErrorClassChainRPC)
+				r.logger.Warnf("failed to get BNB chain tip number: %v", err)
+			}
+		}
+		if bnbChainTipNumber > 0 {
+			metrics.ChainTip.WithLabelValues(metrics.ChainBNB).Set(float64(bnbChainTipNumber))
+			health.SetProgress(metrics.ChainBNB, syncPoint, bnbChainTipNumber)
+		}
+		if bnbChainTipNumber > 0 && r.refreshStakePlanHubAddress(syncPoint, bnbChainTipNumber) {
+			continue
 		}
 		if syncPoint+r.delayBlocks >= bnbChainTipNumber {
 			r.logger.Infof("Sync point is %d, BNB chain tip is %d, wait for %d blocks",
 				syncPoint, bnbChainTipNumber, syncPoint+r.delayBlocks-bnbChainTipNumber+1)
-			time.Sleep(blockWaitTime)
+			if bnbChainTipNumber > 0 {
+				health.Beat("bnb/scan")
+			}
+			r.waitForNewHead(blockWaitTime)
 			continue
 		}
 
 		start := syncPoint + 1
 		end := bnbChainTipNumber - r.delayBlocks
-		if end-start+1 > BatchPlanStakeBlockSizeFetch {
-			end = start + BatchPlanStakeBlockSizeFetch - 1
+		var receiptWithProofList []*bnbclient.ReceiptWithProof
+		logs, generation, subscribed := r.subscribedLogs(start, end)
+		if subscribed {
+			// all logs of the range have been received by the subscription
+			r.logger.Debugf("start: %d, end: %d, subscribed logs: %d", start, end, len(logs))
+			receiptWithProofList, err = tracing.Call(ctx, "bnb.GetHubEventReceiptsWithProofByLogs", func() ([]*bnbclient.ReceiptWithProof, error) {
+				return r.bnbClient.GetHubEventReceiptsWithProofByLogs(logs)
+			}, tracing.BlockRangeKey.String(fmt.Sprintf("%d-%d", start, end)))
+		} else {
+			// polling, or backfilling the gap before the subscription
+			if end-start+1 > r.fetchBlockSize {
+				end = start + r.fetchBlockSize - 1
+			}
+			r.logger.Debugf("start: %d, end: %d", start, end)
+			receiptWithProofList, err = tracing.Call(ctx, "bnb.GetHubEventReceiptsWithProof", func() ([]*bnbclient.ReceiptWithProof, error) {
+				return r.getReceiptsWithProofByRange(start, end)
+			}, tracing.BlockRangeKey.String(fmt.Sprintf("%d-%d", start, end)))
 		}
-
-		r.logger.Debugf("start: %d, end: %d", start, end)
-		receiptWithProofList, err := r.bnbClient.GetStakeBTC2JoinStakePlanReceiptsWithProof(r.planStakeHubAddress, start, end)
 		if err != nil {
-			r.logger.Warnf("failed to get receipts with proof: %v", err)
+			if bnbclient.IsBlockRangeTooLargeError(err) && r.shrinkFetchBlockSize() {
+				metrics.Error(metrics.ChainBNB, metrics.ErrorClassBlockRange)
+				r.logger.Warnf("block range %d-%d rejected by rpc provider, shrink fetch block size to %d, error: %v",
+					start, end, r.fetchBlockSize, err)
+				continue
+			}
+			if errors.Is(err, bnbclient.ErrIntegrity) {
+				// never persist unverified receipts, retry the range until the rpc provider returns consistent data
+				metrics.Error(metrics.ChainBNB, metrics.ErrorClassIntegrity)
+				r.logger.Errorf("integrity error on block range %d-%d, receipts are not persisted: %v", start, end, err)
+			} else {
+				metrics.Error(metrics.ChainBNB, metrics.ErrorClassChainRPC)
+				r.logger.Warnf("failed to get receipts with proof: %v", err)
+			}
 			time.Sleep(networkErrorWaitTime)
 			continue
 		}
@@ -156,18 +262,272 @@ func (r *BNBTxRelayer) scanLoop() {
 			continue
 		}
 
-		if err := r.repository.InsertWrappedBTCDepositTxs(txs); err != nil {
-			r.logger.Errorf("failed to insert wrapped btc deposit txs: %v", err)
+		endHash, err := tracing.Call(ctx, "bnb.BlockHashByNumber", func() (common.Hash, error) {
+			return r.bnbClient.BlockHashByNumber(end)
+		}, tracing.BlockHeightKey.Int64(int64(end)))
+		if err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassChainRPC)
+			r.logger.Warnf("failed to get BNB block hash %d: %v", end, err)
 			time.Sleep(networkErrorWaitTime)
 			continue
 		}
 
-		if err := r.repository.UpdateSyncPoint(end); err != nil {
-			r.logger.Warnf("failed to set sync point: %v", err)
+		// the subscription dropped since the logs were taken, some may be missing: poll the range instead
+		if subscribed && !r.subscription.unchanged(generation) {
+			r.logger.Warnf("subscription reset while scanning block range %d-%d, scan it again", start, end)
 			continue
 		}
+
+		// the deposits and the sync point are committed in one transaction
+		if err := r.insertDepositTxs(ctx, txs, start, end, endHash.Hex()); err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
+			r.logger.Errorf("failed to insert wrapped btc deposit txs: %v", err)
+			time.Sleep(networkErrorWaitTime)
+			continue
+		}
+		metrics.SyncPoint.WithLabelValues(metrics.ChainBNB).Set(float64(end))
 		r.logger.Infof("sync point updated to %d", end)
+		r.subscription.pruneLogs(end)
+		r.growFetchBlockSize()
+		health.Beat("bnb/scan")
+	}
+}
+
+// subscribedLogs returns the hub event logs of the range received by the subscription and their generation,
+// subscribed is false unless the subscription has received all logs of the range
+func (r *BNBTxRelayer) subscribedLogs(start, end uint64) (logs []ethtypes.Log, generation uint64, subscribed bool) {
+	received, generation, subscribed := r.subscription.snapshot(start, end)
+	for _, log := range received {
+		if log.Address == r.hubAddresses.addressAt(log.BlockNumber) {
+			logs = append(logs, log)
+		}
 	}
+	return logs, generation, subscribed
+}
+
+// insertDepositTxs inserts the deposits of the range, each deposit starts its trace with a scanned span
+func (r *BNBTxRelayer) insertDepositTxs(ctx context.Context, txs []*db.WrappedBTCDepositTx, start, end uint64, endHash string) error {
+	var txids []string
+	for _, tx := range txs {
+		txids = append(txids, tx.Txid)
+	}
+	insertCtx, span := tracing.StartWithLinks(ctx, "db.InsertWrappedBTCDepositTxs", tracing.DepositLinks(metrics.ChainBNB, txids),
+		tracing.BlockRangeKey.String(fmt.Sprintf("%d-%d", start, end)))
+	err := r.repository.InsertWrappedBTCDepositTxs(txs, end, endHash)
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		_, scannedSpan := tracing.StartWithLinks(tracing.DepositContext(ctx, metrics.ChainBNB, tx.Txid), "bnb.depositScanned",
+			trace.WithLinks(trace.LinkFromContext(insertCtx)),
+			tracing.ChainKey.String(metrics.ChainBNB), tracing.DepositTxidKey.String(tx.Txid),
+			tracing.LogIndexKey.Int(int(tx.LogIndex)), tracing.BlockHeightKey.Int64(int64(tx.Height)))
+		scannedSpan.End()
+	}
+	return nil
+}
+
+// getReceiptsWithProofByRange scans each part of the range against the stake plan hub address effective for it
+func (r *BNBTxRelayer) getReceiptsWithProofByRange(start, end uint64) ([]*bnbclient.ReceiptWithProof, error) {
+	var receiptWithProofList []*bnbclient.ReceiptWithProof
+	for _, hubRange := range r.hubAddresses.split(start, end) {
+		list, err := r.bnbClient.GetHubEventReceiptsWithProof(hubRange.address, hubRange.start, hubRange.end)
+		if err != nil {
+			return nil, err
+		}
+		receiptWithProofList = append(receiptWithProofList, list...)
+	}
+
+	return receiptWithProofList, nil
+}
+
+// loadHubAddresses loads the persisted stake plan hub address history. The current address is recorded
+// if the history is empty, or effective after the sync point if it changed while the relayer was stopped.
+func (r *BNBTxRelayer) loadHubAddresses(current common.Address) error {
+	addresses, err := r.repository.GetHubAddresses()
+	if err != nil {
+		return err
+	}
+
+	if len(addresses) == 0 {
+		address := &db.HubAddress{Address: current.Hex()}
+		if _, err := r.repository.AddHubAddress(address); err != nil {
+			return err
+		}
+		addresses = append(addresses, address)
+	} else if last := common.HexToAddress(addresses[len(addresses)-1].Address); last != current {
+		syncPoint, err := r.repository.GetSyncPoint()
+		if err != nil {
+			return err
+		}
+		// the change was not seen before the sync point was reached, it happened after it
+		address := &db.HubAddress{Address: current.Hex(), EffectiveFrom: syncPoint + 1, Approximate: true}
+		if _, err := r.repository.AddHubAddress(address); err != nil {
+			return err
+		}
+		metrics.HubAddressChangesTotal.Inc()
+		r.logger.Warnf("StakePlanHubAddress changed from %s to %s while the relayer was stopped, "+
+			"effective from BNB height %d, which is only a lower bound of the change", last.Hex(), current.Hex(), address.EffectiveFrom)
+		addresses = append(addresses, address)
+	}
+
+	r.hubAddresses = newHubAddressHistory(addresses)
+	return nil
+}
+
+// refreshStakePlanHubAddress reloads the bnblightclient params periodically.
+// When the stake plan hub address changes, the new address is effective from the BNB chain tip seen at
+// the previous refresh, a lower bound of the height of the change. The address is persisted, and the sync point
+// is rolled back in the same transaction if that height has been scanned already.
+// It returns true if the sync point is rolled back.
+func (r *BNBTxRelayer) refreshStakePlanHubAddress(syncPoint, bnbChainTip uint64) bool {
+	if time.Since(r.lastParamsRefreshTime) < r.paramsRefreshInterval {
+		return false
+	}
+
+	bnblightParams, err := r.lorenzoClient.BNBLightClientParams()
+	if err != nil {
+		metrics.Error(metrics.ChainBNB, metrics.ErrorClassLorenzo)
+		r.logger.Warnf("failed to refresh BNB light client params: %v", err)
+		return false
+	}
+
+	oldAddress := r.hubAddresses.current()
+	newAddress := common.HexToAddress(bnblightParams.Params.StakePlanHubAddress)
+	rolledBack := false
+	if newAddress != oldAddress {
+		effectiveFrom := r.lastParamsRefreshTip
+		if effectiveFrom == 0 {
+			effectiveFrom = syncPoint + 1
+		}
+		address := &db.HubAddress{Address: newAddress.Hex(), EffectiveFrom: effectiveFrom, Approximate: true}
+		rolledBack, err = r.repository.AddHubAddress(address)
+		if err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
+			r.logger.Warnf("failed to record new StakePlanHubAddress: %v", err)
+			return false
+		}
+		metrics.HubAddressChangesTotal.Inc()
+		if rolledBack {
+			metrics.SyncPoint.WithLabelValues(metrics.ChainBNB).Set(float64(effectiveFrom - 1))
+		}
+
+		r.hubAddresses.add(newAddress, effectiveFrom)
+		r.logger.Warnf("StakePlanHubAddress changed from %s to %s between BNB heights %d and %d, "+
+			"effective from %d, which is only a lower bound of the change", oldAddress.Hex(), newAddress.Hex(), effectiveFrom, bnbChainTip, effectiveFrom)
+		if rolledBack {
+			r.logger.Warnf("sync point rolled back from %d to %d to rescan with the new StakePlanHubAddress",
+				syncPoint, effectiveFrom-1)
+		}
+
+		select {
+		case r.resubscribe <- struct{}{}:
+		default:
+		}
+	}
+
+	r.lastParamsRefreshTime = time.Now()
+	r.lastParamsRefreshTip = bnbChainTip
+	return rolledBack
+}
+
+// waitForNewHead waits for a new head from the subscription, at most timeout
+func (r *BNBTxRelayer) waitForNewHead(timeout time.Duration) {
+	select {
+	case <-r.quit:
+	case <-r.newHeadNotify:
+	case <-time.After(timeout):
+	}
+}
+
+// subscribeLoop keeps the websocket subscriptions alive, the scan loop falls back to polling while they are down
+func (r *BNBTxRelayer) subscribeLoop() {
+	resubscribeWaitTime := time.Second * 5
+
+	for {
+		if err := r.subscribe(); err != nil {
+			r.logger.Warnf("websocket subscription dropped, fall back to polling: %v", err)
+		}
+		r.subscription.reset()
+		r.bnbClient.CloseWebSocket()
+
+		select {
+		case <-r.quit:
+			return
+		case <-time.After(resubscribeWaitTime):
+		}
+	}
+}
+
+// subscribe subscribes hub event logs and new heads, it blocks until a subscription drops or the relayer quits
+func (r *BNBTxRelayer) subscribe() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logCh := make(chan ethtypes.Log, 128)
+	logSub, err := r.bnbClient.SubscribeHubEventLogs(ctx, r.hubAddresses.current(), logCh)
+	if err != nil {
+		return err
+	}
+	defer logSub.Unsubscribe()
+
+	headCh := make(chan *ethtypes.Header, 16)
+	headSub, err := r.bnbClient.SubscribeNewHead(ctx, headCh)
+	if err != nil {
+		return err
+	}
+	defer headSub.Unsubscribe()
+
+	for {
+		select {
+		case <-r.quit:
+			return nil
+		case <-r.resubscribe:
+			return errors.New("stake plan hub address changed, resubscribe")
+		case err := <-logSub.Err():
+			return fmt.Errorf("log subscription: %v", err)
+		case err := <-headSub.Err():
+			return fmt.Errorf("head subscription: %v", err)
+		case log := <-logCh:
+			r.subscription.addLog(log)
+		case head := <-headCh:
+			if r.subscription.setTip(head.Number.Uint64()) {
+				r.logger.Infof("websocket subscriptions established, covering blocks from %d", head.Number.Uint64())
+			}
+			select {
+			case r.newHeadNotify <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// shrinkFetchBlockSize halves the fetch block size, it returns false if the size is already the minimum
+func (r *BNBTxRelayer) shrinkFetchBlockSize() bool {
+	if r.fetchBlockSize <= r.minFetchBlockSize {
+		return false
+	}
+
+	r.fetchBlockSize /= 2
+	if r.fetchBlockSize < r.minFetchBlockSize {
+		r.fetchBlockSize = r.minFetchBlockSize
+	}
+	return true
+}
+
+// growFetchBlockSize doubles the fetch block size up to the maximum
+func (r *BNBTxRelayer) growFetchBlockSize() {
+	if r.fetchBlockSize >= r.maxFetchBlockSize {
+		return
+	}
+
+	r.fetchBlockSize *= 2
+	if r.fetchBlockSize > r.maxFetchBlockSize {
+		r.fetchBlockSize = r.maxFetchBlockSize
+	}
+	r.logger.Debugf("fetch block size grows to %d", r.fetchBlockSize)
 }
 
 func (r *BNBTxRelayer) submitLoop() {
@@ -181,78 +541,221 @@ func (r *BNBTxRelayer) submitLoop() {
 		default:
 		}
 
-		lorenzoBNBTip, err := r.lorenzoClient.BNBLatestHeader()
+		if r.isPaused(LoopSubmit) {
+			health.Beat("bnb/submit")
+			time.Sleep(pausedLoopWaitTime)
+			continue
+		}
+
+		ctx := context.Background()
+		lorenzoBNBTip, err := tracing.Call(ctx, "lorenzo.BNBLatestHeader", r.lorenzoClient.BNBLatestHeader)
 		if err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassLorenzo)
 			r.logger.Warnf("failed to get latest BNB header: %v", err)
 			time.Sleep(networkErrorWaitTime)
 			continue
 		}
+		metrics.LorenzoLightClientTip.WithLabelValues(metrics.ChainBNB).Set(float64(lorenzoBNBTip.Number))
 
-		txs, err := r.repository.GetUnhandledWrappedBTCDepositTxs(lorenzoBNBTip.Number)
+		txs, err := tracing.Call(ctx, "db.GetUnhandledWrappedBTCDepositTxs", func() ([]*db.WrappedBTCDepositTx, error) {
+			return r.repository.GetUnhandledWrappedBTCDepositTxs(lorenzoBNBTip.Number)
+		})
 		if err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
 			r.logger.Warnf("failed to get unhandled wrapped btc deposit txs: %v", err)
 			time.Sleep(networkErrorWaitTime)
 			continue
 		}
 
 		if len(txs) == 0 {
+			health.Beat("bnb/submit")
 			r.logger.Debugf("no unhandled wrapped btc deposit txs")
 			time.Sleep(blockWaitTime)
 			continue
 		}
 
-		r.submit(txs)
+		// the loop is only healthy if it handles deposits, not if every submission fails
+		handled, failed := r.submit(ctx, txs)
+		if handled > 0 || failed == 0 {
+			health.Beat("bnb/submit")
+		}
+		if handled == 0 {
+			// nothing changed, e.g. all deposits wait for paused stake plans
+			time.Sleep(blockWaitTime)
+		}
 	}
 }
 
-func (r *BNBTxRelayer) submit(txs []*db.WrappedBTCDepositTx) {
+// submit submits the pending events, it returns the numbers of handled and failed submissions,
+// events of paused stake plans are held back without failing
+func (r *BNBTxRelayer) submit(ctx context.Context, txs []*db.WrappedBTCDepositTx) (handled int, failed int) {
 	if len(txs) == 0 {
-		return
+		return 0, 0
 	}
 
+	// events of the same tx share one receipt, submit it once per event type
+	submitted := make(map[string]bool)
+	// a receipt is not submitted until all its events are recorded as confirmed, it is retried in the next round
+	for _, tx := range txs {
+		if err := r.recordDepositEvent(ctx, tx, db.EventConfirmed); err != nil {
+			submitted[tx.Txid+"/"+tx.EventName] = true
+			failed++
+		}
+	}
+	// stake plans are validated once per tx in a submit round
+	r.planValidator.reset()
+	planValid := make(map[string]bool)
 	for _, tx := range txs {
 		select {
 		case <-r.quit:
-			return
+			return handled, failed
 		default:
 		}
 
-		receiptRaw, err := hexutil.Decode(tx.Receipt)
-		if err != nil {
-			err = fmt.Errorf("invalid receipt: %v", err)
-			r.markDepositTxInvalid(tx.Txid, err)
+		submittedKey := tx.Txid + "/" + tx.EventName
+		if submitted[submittedKey] {
 			continue
 		}
-		proofRaw, err := hexutil.Decode(tx.Proof)
-		if err != nil {
-			err = fmt.Errorf("invalid proof: %v", err)
-			r.markDepositTxInvalid(tx.Txid, err)
+		submitted[submittedKey] = true
+
+		txCtx := tracing.DepositContext(ctx, metrics.ChainBNB, tx.Txid)
+		valid, ok := planValid[tx.Txid]
+		if !ok {
+			var err error
+			valid, err = r.validateStakePlans(txCtx, txs, tx.Txid)
+			if err != nil {
+				failed++
+			}
+			planValid[tx.Txid] = valid
+		}
+		if !valid {
 			continue
 		}
-		msg := &types.MsgCreateBTCBStaking{
-			Signer:  r.submitter,
-			Number:  tx.Height,
-			Receipt: receiptRaw,
-			Proof:   proofRaw,
+
+		var receiptEvents []*db.WrappedBTCDepositTx
+		for _, event := range txs {
+			if event.Txid == tx.Txid && event.EventName == tx.EventName {
+				receiptEvents = append(receiptEvents, event)
+			}
 		}
-		r.logger.Debugf("BlockNumber: %d\n", msg.Number)
-		r.logger.Debugf("Receipt: %x\n", msg.Receipt)
-		r.logger.Debugf("Proof: %x\n", msg.Proof)
-		r.logger.Debug("=====================================")
+		if err := r.submitDepositTx(txCtx, tx, receiptEvents); err != nil {
+			failed++
+			continue
+		}
+		handled++
+	}
 
-		_, err = r.lorenzoClient.CreateBTCBStaking(context.Background(), msg)
+	return handled, failed
+}
+
+// submitDepositTx submits the event to Lorenzo in the trace of the deposit, receiptEvents are the events
+// of the tx submitted with the same receipt. An error is returned if the deposit should be retried.
+func (r *BNBTxRelayer) submitDepositTx(ctx context.Context, tx *db.WrappedBTCDepositTx, receiptEvents []*db.WrappedBTCDepositTx) error {
+	ctx, span := tracing.Start(ctx, "bnb.submitDeposit", tracing.ChainKey.String(metrics.ChainBNB),
+		tracing.DepositTxidKey.String(tx.Txid), tracing.LogIndexKey.Int(int(tx.LogIndex)))
+	defer span.End()
+
+	eventDefinition := r.bnbClient.EventRegistry().ByName(tx.EventName)
+	if eventDefinition == nil {
+		r.markDepositTxInvalid(ctx, receiptEvents, fmt.Errorf("unknown event: %s", tx.EventName))
+		return nil
+	}
+
+	receiptRaw, err := hexutil.Decode(tx.Receipt)
+	if err != nil {
+		err = fmt.Errorf("invalid receipt: %v", err)
+		r.markDepositTxInvalid(ctx, receiptEvents, err)
+		return nil
+	}
+	proofRaw, err := hexutil.Decode(tx.Proof)
+	if err != nil {
+		err = fmt.Errorf("invalid proof: %v", err)
+		r.markDepositTxInvalid(ctx, receiptEvents, err)
+		return nil
+	}
+	msg := eventDefinition.NewLorenzoMsg(r.submitter, tx.Height, receiptRaw, proofRaw)
+	r.logger.Debugf("Event: %s\n", tx.EventName)
+	r.logger.Debugf("BlockNumber: %d\n", tx.Height)
+	r.logger.Debugf("Receipt: %x\n", receiptRaw)
+	r.logger.Debugf("Proof: %x\n", proofRaw)
+	r.logger.Debug("=====================================")
+
+	for _, event := range receiptEvents {
+		if err := r.recordDepositEvent(ctx, event, db.EventSubmitted); err != nil {
+			return err
+		}
+	}
+	submitStart := time.Now()
+	err = tracing.Run(ctx, "lorenzo.ReliablySendMsg", func() error {
+		_, err := r.lorenzoClient.ReliablySendMsg(ctx, msg, []*errorsmod.Error{}, []*errorsmod.Error{})
+		return err
+	})
+	result := "success"
+	if err != nil {
+		switch {
+		case isBNBStakingDuplicate(err):
+			result = "duplicate"
+			r.markDepositTxSuccess(ctx, receiptEvents)
+		case isBNBStakingRetryError(err):
+			//need to retry
+			result = "retry"
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassLorenzo)
+			r.logger.Warnf("failed to submit tx: %v, will retry", err)
+		default:
+			result = "error"
+			r.markDepositTxInvalid(ctx, receiptEvents, err)
+		}
+	}
+	metrics.SubmissionDuration.WithLabelValues(metrics.ChainBNB, result).Observe(time.Since(submitStart).Seconds())
+	if result == "retry" {
+		return err
+	}
+	return nil
+}
+
+// validateStakePlans rejects the tx if the stake plan of any of its events is invalid,
+// it returns false if the tx should not be submitted, and an error if the validation or the rejection failed
+func (r *BNBTxRelayer) validateStakePlans(ctx context.Context, txs []*db.WrappedBTCDepositTx, txid string) (bool, error) {
+	var txEvents []*db.WrappedBTCDepositTx
+	for _, tx := range txs {
+		if tx.Txid == txid {
+			txEvents = append(txEvents, tx)
+		}
+	}
+
+	reasons, err := tracing.Call(ctx, "lorenzo.ValidateStakePlans", func() (map[uint]string, error) {
+		return r.planValidator.validateTx(txEvents)
+	}, tracing.DepositTxidKey.String(txid))
+	if errors.Is(err, errPlanPaused) {
+		r.logger.Debugf("deposit of a paused stake plan is kept pending, txid: %s, error: %v", txid, err)
+		return false, nil
+	}
+	if err != nil {
+		metrics.Error(metrics.ChainBNB, metrics.ErrorClassLorenzo)
+		r.logger.Warnf("failed to validate stake plans, txid: %s, error: %v, will retry", txid, err)
+		return false, err
+	}
+	var markErr error
+	for _, tx := range txEvents {
+		reason, ok := reasons[tx.LogIndex]
+		if !ok {
+			continue
+		}
+		r.logger.Warnf("invalid stake plan, txid: %s, logIndex: %d, reason: %s", txid, tx.LogIndex, reason)
+		change := db.StatusChange{Actor: db.ActorRelayer, Reason: reason}
+		err := tracing.Run(ctx, "db.MarkInvalidPlan", func() error {
+			return r.repository.MarkInvalidPlan(txid, tx.LogIndex, change)
+		}, tracing.DepositTxidKey.String(txid), tracing.LogIndexKey.Int(int(tx.LogIndex)))
 		if err != nil {
-			switch {
-			case isBNBStakingDuplicate(err):
-				r.markDepositTxSuccess(tx.Txid)
-			case isBNBStakingRetryError(err):
-				//need to retry
-				r.logger.Warnf("failed to submit tx: %v, will retry", err)
-			default:
-				r.markDepositTxInvalid(tx.Txid, err)
-			}
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
+			r.logger.Warnf("failed to mark invalid stake plan, txid: %s, logIndex: %d, error: %v", txid, tx.LogIndex, err)
+			markErr = err
+			continue
 		}
+		metrics.Deposit(metrics.ChainBNB, db.StatusInvalidPlan, r.planValidator.agentLabel(tx))
 	}
+
+	return len(reasons) == 0, markErr
 }
 
 func (r *BNBTxRelayer) Stop() {
@@ -263,16 +766,50 @@ func (r *BNBTxRelayer) WaitForShutdown() {
 	r.wg.Wait()
 }
 
-func (r *BNBTxRelayer) markDepositTxInvalid(txid string, err error) {
-	r.logger.Warnf("invalid deposit tx, txid:%s, error:%v", txid, err)
-	if err := r.repository.MarkInvalid(txid); err != nil {
-		r.logger.Warnf("failed to mark deposit tx invalid, txid:%s, error:%v", txid, err)
+// recordDepositEvent records a relayer event of the deposit in the outbox. The deposit is not submitted until it
+// is recorded, a failure leaves it pending to be retried.
+func (r *BNBTxRelayer) recordDepositEvent(ctx context.Context, tx *db.WrappedBTCDepositTx, eventType string) error {
+	err := tracing.Run(ctx, "db.RecordDepositEvent", func() error {
+		return r.repository.RecordDepositEvent(db.NewWrappedBTCDepositEvent(tx, eventType))
+	}, tracing.DepositTxidKey.String(tx.Txid), tracing.LogIndexKey.Int(int(tx.LogIndex)))
+	if err != nil {
+		metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
+		r.logger.Warnf("failed to record %s event, txid: %s, logIndex: %d, error: %v, will retry", eventType, tx.Txid, tx.LogIndex, err)
+	}
+	return err
+}
+
+// markDepositTxInvalid rejects the events submitted with one receipt, the other events of the tx are left as they are
+func (r *BNBTxRelayer) markDepositTxInvalid(ctx context.Context, events []*db.WrappedBTCDepositTx, err error) {
+	change := db.StatusChange{Actor: db.ActorRelayer, Error: err.Error()}
+	for _, tx := range events {
+		r.logger.Warnf("invalid deposit tx, txid:%s, logIndex:%d, error:%v", tx.Txid, tx.LogIndex, err)
+		metrics.Error(metrics.ChainBNB, metrics.ErrorClassInvalidDeposit)
+		err := tracing.Run(ctx, "db.MarkInvalid", func() error {
+			return r.repository.MarkInvalid(tx.Txid, tx.LogIndex, change)
+		}, tracing.DepositTxidKey.String(tx.Txid), tracing.LogIndexKey.Int(int(tx.LogIndex)))
+		if err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
+			r.logger.Warnf("failed to mark deposit tx invalid, txid:%s, logIndex:%d, error:%v", tx.Txid, tx.LogIndex, err)
+			continue
+		}
+		metrics.Deposit(metrics.ChainBNB, db.StatusInvalid, r.planValidator.agentLabel(tx))
 	}
 }
 
-func (r *BNBTxRelayer) markDepositTxSuccess(txid string) {
-	if err := r.repository.MarkSuccess(txid); err != nil {
-		r.logger.Warnf("failed to mark success, txid:%s, error:%v", txid, err)
+// markDepositTxSuccess marks the events submitted with one receipt minted, the other events of the tx are left as they are
+func (r *BNBTxRelayer) markDepositTxSuccess(ctx context.Context, events []*db.WrappedBTCDepositTx) {
+	for _, tx := range events {
+		err := tracing.Run(ctx, "db.MarkSuccess", func() error {
+			return r.repository.MarkSuccess(tx.Txid, tx.LogIndex, db.StatusChange{Actor: db.ActorRelayer})
+		}, tracing.DepositTxidKey.String(tx.Txid), tracing.LogIndexKey.Int(int(tx.LogIndex)))
+		if err != nil {
+			metrics.Error(metrics.ChainBNB, metrics.ErrorClassDatabase)
+			r.logger.Warnf("failed to mark success, txid:%s, logIndex:%d, error:%v", tx.Txid, tx.LogIndex, err)
+			continue
+		}
+		metrics.Deposit(metrics.ChainBNB, db.StatusSuccess, r.planValidator.agentLabel(tx))
+		metrics.DepositLatency.WithLabelValues(metrics.ChainBNB).Observe(time.Since(tx.BlockTime).Seconds())
 	}
 }
 
@@ -287,16 +824,40 @@ func (r *BNBTxRelayer) ReceiptWithProofList2WrappedBTCDepositTxList(receiptWithP
 		if err != nil {
 			return nil, err
 		}
-		wrappedBTCDepositTx := &db.WrappedBTCDepositTx{
-			Chain:     r.chainName,
-			Txid:      receiptWithProof.Receipt.TxHash.Hex(),
-			Height:    receiptWithProof.Receipt.BlockNumber.Uint64(),
-			BlockHash: receiptWithProof.Receipt.BlockHash.Hex(),
-			BlockTime: time.Unix(int64(receiptWithProof.BlockTime), 0),
-			Receipt:   hexutil.Encode(receiptRaw),
-			Proof:     hexutil.Encode(proofRaw),
+		// one row per event, all events of a tx share the receipt and proof
+		for _, event := range receiptWithProof.Events {
+			eventData, err := json.Marshal(event)
+			if err != nil {
+				return nil, err
+			}
+			wrappedBTCDepositTx := &db.WrappedBTCDepositTx{
+				Chain:     r.chainName,
+				Txid:      receiptWithProof.Receipt.TxHash.Hex(),
+				LogIndex:  event.EventLog().LogIndex,
+				EventName: event.EventName(),
+				EventData: string(eventData),
+				Height:    receiptWithProof.Receipt.BlockNumber.Uint64(),
+				BlockHash: receiptWithProof.Receipt.BlockHash.Hex(),
+				BlockTime: time.Unix(int64(receiptWithProof.BlockTime), 0),
+				Receipt:   hexutil.Encode(receiptRaw),
+				Proof:     hexutil.Encode(proofRaw),
+			}
+			if stakePlanEvent, ok := event.(*bnbclient.StakeBTC2JoinStakePlanEvent); ok {
+				// values exceeding uint64 are kept in the event data only, the event is rejected by the plan validator
+				if stakePlanEvent.StakeIndex.IsUint64() && stakePlanEvent.PlanId.IsUint64() {
+					wrappedBTCDepositTx.StakeIndex = stakePlanEvent.StakeIndex.Uint64()
+					wrappedBTCDepositTx.PlanId = stakePlanEvent.PlanId.Uint64()
+				} else {
+					r.logger.Warnf("stake index %s or plan id %s of tx %s exceeds uint64",
+						stakePlanEvent.StakeIndex, stakePlanEvent.PlanId, wrappedBTCDepositTx.Txid)
+				}
+				wrappedBTCDepositTx.UserAddress = stakePlanEvent.User.Hex()
+				wrappedBTCDepositTx.BtcContractAddress = stakePlanEvent.BtcContractAddress.Hex()
+				wrappedBTCDepositTx.StakeAmount = stakePlanEvent.StakeAmount.String()
+				wrappedBTCDepositTx.StBTCAmount = stakePlanEvent.StBTCAmount.String()
+			}
+			wrappedBTCDepositTxList = append(wrappedBTCDepositTxList, wrappedBTCDepositTx)
 		}
-		wrappedBTCDepositTxList = append(wrappedBTCDepositTxList, wrappedBTCDepositTx)
 	}
 	return wrappedBTCDepositTxList, nil
 }
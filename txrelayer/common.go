@@ -9,6 +9,8 @@ const (
 
 	SequenceMismatch          = "account sequence mismatch"
 	BNBBTCBStakingDuplication = "duplicate event"
+
+	LorenzoPlanNotFoundErrorMessage = "plan not found"
 )
 
 type ITxRelayer interface {
@@ -0,0 +1,120 @@
+package txrelayer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+// names of the relayer loops that can be paused
+const (
+	LoopScan   = "scan"
+	LoopSubmit = "submit"
+)
+
+// pausedLoopWaitTime is the interval a paused loop checks whether it is resumed
+const pausedLoopWaitTime = time.Second
+
+var (
+	ErrUnknownLoop         = errors.New("unknown loop")
+	ErrInvalidRescanHeight = errors.New("invalid rescan height")
+)
+
+// loopControl pauses the loops of a relayer and rescans its chain on behalf of operators.
+// The state is not persisted, the loops run again after a restart.
+type loopControl struct {
+	repository db.ISyncPointRepository
+
+	lock   sync.Mutex
+	paused map[string]bool
+	// rescanHeight is the height the scan loop rescans from, 0 if no rescan is requested
+	rescanHeight uint64
+}
+
+func newLoopControl(repository db.ISyncPointRepository) *loopControl {
+	return &loopControl{
+		repository: repository,
+		paused:     map[string]bool{LoopScan: false, LoopSubmit: false},
+	}
+}
+
+func (c *loopControl) Pause(loop string) error {
+	return c.setPaused(loop, true)
+}
+
+func (c *loopControl) Resume(loop string) error {
+	return c.setPaused(loop, false)
+}
+
+// LoopStates returns whether each loop is paused
+func (c *loopControl) LoopStates() map[string]bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	states := make(map[string]bool, len(c.paused))
+	for loop, paused := range c.paused {
+		states[loop] = paused
+	}
+	return states
+}
+
+// RequestRescan asks the scan loop to scan again from the height, which must not be beyond the next height to scan.
+// The sync point is moved by the scan loop so it does not race with a scan in progress.
+func (c *loopControl) RequestRescan(height uint64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if height == 0 {
+		return fmt.Errorf("%w: the height must be positive", ErrInvalidRescanHeight)
+	}
+	syncPoint, err := c.repository.GetSyncPoint()
+	if err != nil {
+		return err
+	}
+	if height > syncPoint+1 {
+		return fmt.Errorf("%w: %d is beyond the next height %d to scan", ErrInvalidRescanHeight, height, syncPoint+1)
+	}
+	c.rescanHeight = height
+	return nil
+}
+
+func (c *loopControl) isPaused(loop string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.paused[loop]
+}
+
+// applyRescan moves the sync point before the requested rescan height,
+// it returns the sync point before the move if a rescan is applied
+func (c *loopControl) applyRescan() (previous uint64, applied bool, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.rescanHeight == 0 {
+		return 0, false, nil
+	}
+	previous, err = c.repository.GetSyncPoint()
+	if err != nil {
+		return 0, false, err
+	}
+	if err := c.repository.UpdateSyncPoint(c.rescanHeight-1, ""); err != nil {
+		return 0, false, err
+	}
+	c.rescanHeight = 0
+	return previous, true, nil
+}
+
+func (c *loopControl) setPaused(loop string, paused bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.paused[loop]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownLoop, loop)
+	}
+	c.paused[loop] = paused
+	return nil
+}
@@ -0,0 +1,51 @@
+package txrelayer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+)
+
+func TestLoopControl(t *testing.T) {
+	repository := db.NewMemoryBTCRepository()
+	if err := repository.UpdateSyncPoint(100, "0x100"); err != nil {
+		t.Fatal(err)
+	}
+	c := newLoopControl(repository)
+
+	if err := c.Pause(LoopSubmit); err != nil {
+		t.Fatal(err)
+	}
+	if !c.isPaused(LoopSubmit) || c.isPaused(LoopScan) {
+		t.Fatalf("unexpected loop states: %v", c.LoopStates())
+	}
+	if err := c.Resume(LoopSubmit); err != nil || c.isPaused(LoopSubmit) {
+		t.Fatalf("submit loop is not resumed, error: %v", err)
+	}
+	if err := c.Pause("subscribe"); !errors.Is(err, ErrUnknownLoop) {
+		t.Errorf("expect unknown loop, got %v", err)
+	}
+
+	if _, applied, err := c.applyRescan(); err != nil || applied {
+		t.Fatalf("unexpected rescan without request, error: %v", err)
+	}
+	for _, height := range []uint64{0, 102} {
+		if err := c.RequestRescan(height); !errors.Is(err, ErrInvalidRescanHeight) {
+			t.Errorf("expect invalid rescan height %d, got %v", height, err)
+		}
+	}
+	if err := c.RequestRescan(90); err != nil {
+		t.Fatal(err)
+	}
+	previous, applied, err := c.applyRescan()
+	if err != nil || !applied || previous != 100 {
+		t.Fatalf("unexpected rescan: previous %d, applied %v, error: %v", previous, applied, err)
+	}
+	if height, err := repository.GetSyncPoint(); err != nil || height != 89 {
+		t.Fatalf("unexpected sync point after rescan: %d, error: %v", height, err)
+	}
+	if _, applied, _ := c.applyRescan(); applied {
+		t.Error("the rescan should be applied once")
+	}
+}
@@ -2,6 +2,7 @@ package txrelayer
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
@@ -16,11 +17,15 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/cosmos/cosmos-sdk/types/query"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/btc"
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
 	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/health"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/metrics"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/tracing"
 )
 
 type TxRelayer struct {
@@ -35,25 +40,25 @@ type TxRelayer struct {
 	lorenzoClient *lrzclient.Client
 	repository    db.IBTCRepository
 
-	agents []agenttypes.Agent
+	agentsLock sync.RWMutex
+	agents     []agenttypes.Agent
+
+	// the loops are paused and the chain is rescanned by operators through the admin API
+	*loopControl
 
 	wg   sync.WaitGroup
 	quit chan struct{}
 }
 
-func NewTxRelayer(logger *zap.SugaredLogger, conf *config.TxRelayerConfig, lorenzoClient *lrzclient.Client) (*TxRelayer, error) {
+func NewTxRelayer(logger *zap.SugaredLogger, conf *config.TxRelayerConfig, lorenzoClient *lrzclient.Client, repository db.IBTCRepository) (*TxRelayer, error) {
 	btcQuery := btc.NewBTCQuery(conf.BtcApiEndpoint)
 	logger = logger.Named("btc")
 
-	repository, err := db.NewBTCRepository()
-	if err != nil {
-		return nil, err
-	}
 	// check if sync point is set, if not set it to start block height
 	if height, err := repository.GetSyncPoint(); err != nil {
 		return nil, err
 	} else if height == 0 {
-		if err := repository.UpdateSyncPoint(conf.StartBlockHeight); err != nil {
+		if err := repository.UpdateSyncPoint(conf.StartBlockHeight, ""); err != nil {
 			return nil, err
 		}
 	}
@@ -68,6 +73,7 @@ func NewTxRelayer(logger *zap.SugaredLogger, conf *config.TxRelayerConfig, loren
 		repository:    repository,
 		btcParam:      btcParam,
 		submitter:     lorenzoClient.MustGetAddr(),
+		loopControl:   newLoopControl(repository),
 
 		wg:   sync.WaitGroup{},
 		quit: make(chan struct{}),
@@ -82,6 +88,8 @@ func NewTxRelayer(logger *zap.SugaredLogger, conf *config.TxRelayerConfig, loren
 }
 
 func (r *TxRelayer) Start() {
+	health.RegisterLoop("btc/scan")
+	health.RegisterLoop("btc/submit")
 	r.wg.Add(2)
 	go func() {
 		defer r.wg.Done()
@@ -119,6 +127,7 @@ func (r *TxRelayer) updateAgentsListLoop() {
 			return
 		default:
 			if err := r.updateAgentsList(); err != nil {
+				metrics.Error(metrics.ChainBTC, metrics.ErrorClassLorenzo)
 				r.logger.Errorf("Failed to update agents list, error: %v", err)
 			}
 			time.Sleep(updateGap)
@@ -136,48 +145,95 @@ func (r *TxRelayer) scanBlockLoop() {
 		default:
 		}
 
-		btcTip, err := r.btcQuery.GetBTCCurrentHeight()
+		if previous, applied, err := r.applyRescan(); err != nil {
+			metrics.Error(metrics.ChainBTC, metrics.ErrorClassDatabase)
+			r.logger.Errorf("Failed to apply rescan, error: %v", err)
+		} else if applied {
+			r.logger.Warnf("Rescan requested, sync point moved back from %d", previous)
+		}
+		if r.isPaused(LoopScan) {
+			health.Beat("btc/scan")
+			time.Sleep(pausedLoopWaitTime)
+			continue
+		}
+
+		ctx := context.Background()
+		btcTip, err := tracing.Call(ctx, "btc.GetBTCCurrentHeight", r.btcQuery.GetBTCCurrentHeight)
 		if err != nil {
+			metrics.Error(metrics.ChainBTC, metrics.ErrorClassChainRPC)
 			r.logger.Errorf("Failed to get btc tip, error: %v", err)
 			time.Sleep(connectErrWaitInterval)
 			continue
 		}
+		metrics.ChainTip.WithLabelValues(metrics.ChainBTC).Set(float64(btcTip))
 
-		syncPoint, err := r.GetSyncPoint()
+		syncPoint, err := tracing.Call(ctx, "db.GetSyncPoint", r.GetSyncPoint)
 		if err != nil {
+			metrics.Error(metrics.ChainBTC, metrics.ErrorClassDatabase)
 			r.logger.Errorf("Failed to get sync point, error: %v", err)
 			time.Sleep(connectErrWaitInterval)
 			continue
 		}
+		metrics.SyncPoint.WithLabelValues(metrics.ChainBTC).Set(float64(syncPoint))
+		health.SetProgress(metrics.ChainBTC, syncPoint, btcTip)
 
 		nextBlockHeightToFetch := syncPoint + 1
 		if btcTip < nextBlockHeightToFetch+r.delayBlocks {
+			health.Beat("btc/scan")
 			r.logger.Infof("No new block, current tip: %d, syncPoint:%d", btcTip, syncPoint)
 			time.Sleep(btcInterval)
 			continue
 		}
 
-		msgBlock, err := r.btcQuery.GetBlockByHeight(nextBlockHeightToFetch)
-		if err != nil {
-			r.logger.Errorf("Failed to get btc block: %d, err: %v", nextBlockHeightToFetch, err)
+		if err := r.scanBlock(ctx, nextBlockHeightToFetch); err != nil {
 			time.Sleep(connectErrWaitInterval)
 			continue
 		}
 
-		depositTxs := r.getValidDepositTxs(nextBlockHeightToFetch, msgBlock)
-		if err := r.repository.InsertBtcDepositTxs(depositTxs); err != nil {
-			r.logger.Errorf("Failed to insert btc deposit txs,blockHeight:%d, error: %v", nextBlockHeightToFetch, err)
-			continue
-		}
+		metrics.SyncPoint.WithLabelValues(metrics.ChainBTC).Set(float64(nextBlockHeightToFetch))
+		health.SetProgress(metrics.ChainBTC, nextBlockHeightToFetch, btcTip)
+		health.Beat("btc/scan")
+		r.logger.Infof("Handled block: %d", nextBlockHeightToFetch)
+	}
+}
 
-		if err := r.updateSyncPoint(nextBlockHeightToFetch); err != nil {
-			r.logger.Errorf("Failed to update sync point, point:%d, error: %v", nextBlockHeightToFetch, err)
-			time.Sleep(connectErrWaitInterval)
-			continue
-		}
+// scanBlock inserts the deposits of the block, each deposit starts its trace with a scanned span
+func (r *TxRelayer) scanBlock(ctx context.Context, height uint64) (err error) {
+	ctx, span := tracing.Start(ctx, "btc.scanBlock", tracing.ChainKey.String(metrics.ChainBTC), tracing.BlockHeightKey.Int64(int64(height)))
+	defer func() { tracing.End(span, err) }()
 
-		r.logger.Infof("Handled block: %d", nextBlockHeightToFetch)
+	msgBlock, err := tracing.Call(ctx, "btc.GetBlockByHeight", func() (*wire.MsgBlock, error) {
+		return r.btcQuery.GetBlockByHeight(height)
+	})
+	if err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassChainRPC)
+		r.logger.Errorf("Failed to get btc block: %d, err: %v", height, err)
+		return err
+	}
+
+	// the deposits and the sync point are committed in one transaction
+	depositTxs := r.getValidDepositTxs(ctx, height, msgBlock)
+	blockHash := msgBlock.BlockHash().String()
+	txids := make([]string, 0, len(depositTxs))
+	for _, tx := range depositTxs {
+		txids = append(txids, tx.Txid)
+	}
+	insertCtx, insertSpan := tracing.StartWithLinks(ctx, "db.InsertBtcDepositTxs", tracing.DepositLinks(metrics.ChainBTC, txids))
+	err = r.repository.InsertBtcDepositTxs(depositTxs, height, blockHash)
+	tracing.End(insertSpan, err)
+	if err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassDatabase)
+		r.logger.Errorf("Failed to insert btc deposit txs,blockHeight:%d, error: %v", height, err)
+		return err
+	}
+
+	for _, tx := range depositTxs {
+		_, scannedSpan := tracing.StartWithLinks(tracing.DepositContext(ctx, metrics.ChainBTC, tx.Txid), "btc.depositScanned",
+			trace.WithLinks(trace.LinkFromContext(insertCtx)),
+			tracing.ChainKey.String(metrics.ChainBTC), tracing.DepositTxidKey.String(tx.Txid), tracing.BlockHeightKey.Int64(int64(height)))
+		scannedSpan.End()
 	}
+	return nil
 }
 
 func (r *TxRelayer) submitLoop() {
@@ -190,114 +246,189 @@ func (r *TxRelayer) submitLoop() {
 		default:
 		}
 
-		lorenzoBTCTipResponse, err := r.lorenzoClient.BTCHeaderChainTip()
+		if r.isPaused(LoopSubmit) {
+			health.Beat("btc/submit")
+			time.Sleep(pausedLoopWaitTime)
+			continue
+		}
+
+		ctx := context.Background()
+		lorenzoBTCTipResponse, err := tracing.Call(ctx, "lorenzo.BTCHeaderChainTip", r.lorenzoClient.BTCHeaderChainTip)
 		if err != nil {
+			metrics.Error(metrics.ChainBTC, metrics.ErrorClassLorenzo)
 			r.logger.Errorf("Failed to get lorenzo btc tip, error: %v", err)
 			time.Sleep(connectErrWaitInterval)
 			continue
 		}
+		metrics.LorenzoLightClientTip.WithLabelValues(metrics.ChainBTC).Set(float64(lorenzoBTCTipResponse.Header.Height))
 
-		txs, err := r.repository.GetUnhandledBtcDepositTxs(lorenzoBTCTipResponse.Header.Height)
+		txs, err := tracing.Call(ctx, "db.GetUnhandledBtcDepositTxs", func() ([]*db.BtcDepositTx, error) {
+			return r.repository.GetUnhandledBtcDepositTxs(lorenzoBTCTipResponse.Header.Height)
+		})
 		if err != nil {
+			metrics.Error(metrics.ChainBTC, metrics.ErrorClassDatabase)
 			r.logger.Errorf("Failed to get unhandled btc deposit txs, error: %v", err)
 			time.Sleep(connectErrWaitInterval)
 			continue
 		}
 
 		if len(txs) == 0 {
+			health.Beat("btc/submit")
 			r.logger.Infof("No unhandled btc deposit txs, lorenzoBTCTip: %d", lorenzoBTCTipResponse.Header.Height)
 			time.Sleep(btcInterval)
 			continue
 		}
 
+		// the loop is only healthy if it handles deposits, not if every submission fails
+		submitted := false
 		for _, tx := range txs {
-			txStakingRecordResp, err := r.lorenzoClient.GetBTCStakingRecord(tx.Txid)
-			if err != nil {
-				r.logger.Errorf("Failed to get btc staking record, txid: %s, error: %v", tx.Txid, err)
+			if err := r.submitDepositTx(ctx, tx); err != nil {
 				time.Sleep(connectErrWaitInterval)
 				continue
 			}
-			if txStakingRecordResp.Record != nil {
-				r.markDepositTxSuccess(tx.Txid)
-				continue
-			}
+			submitted = true
+		}
+		if submitted {
+			health.Beat("btc/submit")
+		}
+	}
+}
 
-			proofRaw, err := r.btcQuery.GetTxBlockProof(tx.Txid)
-			if err != nil {
-				r.logger.Errorf("Failed to get btc tx proof, txid: %s, error: %v", tx.Txid, err)
-				time.Sleep(connectErrWaitInterval)
-				continue
-			}
-			txBytes, err := r.btcQuery.GetTxBytes(tx.Txid)
-			if err != nil {
-				r.logger.Errorf("Failed to get btc tx bytes, txid: %s, error:%v", tx.Txid, err)
-				time.Sleep(connectErrWaitInterval)
-				continue
-			}
+// submitDepositTx submits the deposit in its trace, an error is returned if the deposit should be retried
+func (r *TxRelayer) submitDepositTx(ctx context.Context, tx *db.BtcDepositTx) (err error) {
+	ctx, span := tracing.Start(tracing.DepositContext(ctx, metrics.ChainBTC, tx.Txid), "btc.submitDeposit",
+		tracing.ChainKey.String(metrics.ChainBTC), tracing.DepositTxidKey.String(tx.Txid))
+	defer func() { tracing.End(span, err) }()
 
-			if tx.AgentId == 0 {
-				agent := r.GetAgentByAddress(tx.ReceiverAddress)
-				if agent == nil {
-					r.markDepositTxNotBelongToAgent(tx.Txid)
-					continue
-				}
+	if err = r.recordDepositEvent(ctx, tx, db.EventConfirmed); err != nil {
+		return err
+	}
+	txStakingRecordResp, err := tracing.Call(ctx, "lorenzo.GetBTCStakingRecord", func() (*types.QueryStakingRecordResponse, error) {
+		return r.lorenzoClient.GetBTCStakingRecord(tx.Txid)
+	})
+	if err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassLorenzo)
+		r.logger.Errorf("Failed to get btc staking record, txid: %s, error: %v", tx.Txid, err)
+		return err
+	}
+	if txStakingRecordResp.Record != nil {
+		r.markDepositTxSuccess(ctx, tx)
+		return nil
+	}
 
-				tx.AgentId = agent.Id
-			}
+	proofRaw, err := tracing.Call(ctx, "btc.GetTxBlockProof", func() ([]byte, error) {
+		return r.btcQuery.GetTxBlockProof(tx.Txid)
+	})
+	if err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassChainRPC)
+		r.logger.Errorf("Failed to get btc tx proof, txid: %s, error: %v", tx.Txid, err)
+		return err
+	}
+	txBytes, err := tracing.Call(ctx, "btc.GetTxBytes", func() ([]byte, error) {
+		return r.btcQuery.GetTxBytes(tx.Txid)
+	})
+	if err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassChainRPC)
+		r.logger.Errorf("Failed to get btc tx bytes, txid: %s, error:%v", tx.Txid, err)
+		return err
+	}
 
-			msg, err := r.newMsgCreateBTCStaking(tx.AgentId, r.submitter, proofRaw, txBytes)
-			if err != nil {
-				r.markDepositTxInvalid(tx.Txid)
-				continue
-			}
+	if tx.AgentId == 0 {
+		agent := r.GetAgentByAddress(tx.ReceiverAddress)
+		if agent == nil {
+			r.markDepositTxNotBelongToAgent(ctx, tx)
+			return nil
+		}
 
-			_, err = r.lorenzoClient.CreateBTCStakingWithBTCProof(context.Background(), msg)
-			if err != nil {
-				r.logger.Errorf("Failed to create btc staking with btc proof, txid:%s, error: %v", tx.Txid, err)
-				if !isStakingMintTryAgainError(err) {
-					r.markDepositTxInvalid(tx.Txid)
-				}
-				time.Sleep(connectErrWaitInterval)
-				continue
-			}
+		tx.AgentId = agent.Id
+	}
 
-			r.markDepositTxSuccess(tx.Txid)
-			r.logger.Infof("Submitted btc staking tx, txid: %s", tx.Txid)
+	msg, err := r.newMsgCreateBTCStaking(tx.AgentId, r.submitter, proofRaw, txBytes)
+	if err != nil {
+		r.markDepositTxInvalid(ctx, tx, err)
+		return nil
+	}
+
+	if err = r.recordDepositEvent(ctx, tx, db.EventSubmitted); err != nil {
+		return err
+	}
+	submitStart := time.Now()
+	err = tracing.Run(ctx, "lorenzo.CreateBTCStakingWithBTCProof", func() error {
+		_, err := r.lorenzoClient.CreateBTCStakingWithBTCProof(ctx, msg)
+		return err
+	})
+	if err != nil {
+		metrics.SubmissionDuration.WithLabelValues(metrics.ChainBTC, "error").Observe(time.Since(submitStart).Seconds())
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassLorenzo)
+		r.logger.Errorf("Failed to create btc staking with btc proof, txid:%s, error: %v", tx.Txid, err)
+		if !isStakingMintTryAgainError(err) {
+			r.markDepositTxInvalid(ctx, tx, err)
 		}
+		return err
 	}
+	metrics.SubmissionDuration.WithLabelValues(metrics.ChainBTC, "success").Observe(time.Since(submitStart).Seconds())
+
+	r.markDepositTxSuccess(ctx, tx)
+	r.logger.Infof("Submitted btc staking tx, txid: %s", tx.Txid)
+	return nil
 }
 
-func (r *TxRelayer) markDepositTxSuccess(txid string) {
-	if err := r.repository.UpdateTxStatus(txid, db.StatusSuccess); err != nil {
-		r.logger.Errorf("Failed to update tx status to success, txid: %s, error: %v", txid, err)
-	}
+func (r *TxRelayer) updateTxStatus(ctx context.Context, tx *db.BtcDepositTx, status int, change db.StatusChange) error {
+	return tracing.Run(ctx, "db.UpdateTxStatus", func() error {
+		return r.repository.UpdateTxStatus(tx.Txid, status, change)
+	}, tracing.DepositTxidKey.String(tx.Txid))
 }
 
-func (r *TxRelayer) markDepositTxInvalid(txid string) {
-	if err := r.repository.UpdateTxStatus(txid, db.StatusInvalid); err != nil {
-		r.logger.Errorf("Failed to update tx status to invalid, txid: %s, error: %v", txid, err)
+// recordDepositEvent records a relayer event of the deposit in the outbox. The deposit is not submitted until it
+// is recorded, a failure leaves it pending to be retried.
+func (r *TxRelayer) recordDepositEvent(ctx context.Context, tx *db.BtcDepositTx, eventType string) error {
+	err := tracing.Run(ctx, "db.RecordDepositEvent", func() error {
+		return r.repository.RecordDepositEvent(db.NewBtcDepositEvent(tx, eventType))
+	}, tracing.DepositTxidKey.String(tx.Txid))
+	if err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassDatabase)
+		r.logger.Errorf("Failed to record %s event, txid: %s, error: %v", eventType, tx.Txid, err)
 	}
+	return err
 }
 
-func (r *TxRelayer) markDepositTxNotBelongToAgent(txid string) {
-	if err := r.repository.UpdateTxStatus(txid, db.StatusReceiverIsNotBelongToAgent); err != nil {
-		r.logger.Errorf("Failed to update tx status to invalid, txid: %s, error: %v", txid, err)
+func (r *TxRelayer) markDepositTxSuccess(ctx context.Context, tx *db.BtcDepositTx) {
+	change := db.StatusChange{Actor: db.ActorRelayer}
+	if err := r.updateTxStatus(ctx, tx, db.StatusSuccess, change); err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassDatabase)
+		r.logger.Errorf("Failed to update tx status to success, txid: %s, error: %v", tx.Txid, err)
+		return
 	}
+	metrics.Deposit(metrics.ChainBTC, db.StatusSuccess, metrics.AgentLabel(tx.AgentId))
+	metrics.DepositLatency.WithLabelValues(metrics.ChainBTC).Observe(time.Since(tx.BlockTime).Seconds())
 }
 
-func (r *TxRelayer) updateSyncPoint(newPoint uint64) error {
-	if err := r.repository.UpdateSyncPoint(newPoint); err != nil {
-		return err
+func (r *TxRelayer) markDepositTxInvalid(ctx context.Context, tx *db.BtcDepositTx, txErr error) {
+	metrics.Error(metrics.ChainBTC, metrics.ErrorClassInvalidDeposit)
+	change := db.StatusChange{Actor: db.ActorRelayer, Error: txErr.Error()}
+	if err := r.updateTxStatus(ctx, tx, db.StatusInvalid, change); err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassDatabase)
+		r.logger.Errorf("Failed to update tx status to invalid, txid: %s, error: %v", tx.Txid, err)
+		return
 	}
+	metrics.Deposit(metrics.ChainBTC, db.StatusInvalid, metrics.AgentLabel(tx.AgentId))
+}
 
-	return nil
+func (r *TxRelayer) markDepositTxNotBelongToAgent(ctx context.Context, tx *db.BtcDepositTx) {
+	change := db.StatusChange{Actor: db.ActorRelayer, Reason: fmt.Sprintf("receiver %s does not belong to any agent", tx.ReceiverAddress)}
+	if err := r.updateTxStatus(ctx, tx, db.StatusReceiverIsNotBelongToAgent, change); err != nil {
+		metrics.Error(metrics.ChainBTC, metrics.ErrorClassDatabase)
+		r.logger.Errorf("Failed to update tx status to invalid, txid: %s, error: %v", tx.Txid, err)
+		return
+	}
+	metrics.Deposit(metrics.ChainBTC, db.StatusReceiverIsNotBelongToAgent, "")
 }
 
 func (r *TxRelayer) GetSyncPoint() (uint64, error) {
 	return r.repository.GetSyncPoint()
 }
 
-func (r *TxRelayer) getValidDepositTxs(blockHeight uint64, msgBlock *wire.MsgBlock) []*db.BtcDepositTx {
+func (r *TxRelayer) getValidDepositTxs(ctx context.Context, blockHeight uint64, msgBlock *wire.MsgBlock) []*db.BtcDepositTx {
 	var depositTxs []*db.BtcDepositTx
 
 MainLoop:
@@ -335,7 +466,9 @@ MainLoop:
 			//check inputs address if no opReturn
 			if agent.EthAddr != "" {
 				for {
-					txDetail, err := r.btcQuery.GetTx(txid)
+					txDetail, err := tracing.Call(ctx, "btc.GetTx", func() (*btc.BtcTx, error) {
+						return r.btcQuery.GetTx(txid)
+					}, tracing.DepositTxidKey.String(txid))
 					if err != nil {
 						r.logger.Errorf("Failed to get tx detail, txid: %s, error: %v", txid, err)
 						time.Sleep(time.Second)
@@ -372,6 +505,9 @@ MainLoop:
 }
 
 func (r *TxRelayer) IsValidDepositReceiver(addr string) bool {
+	r.agentsLock.RLock()
+	defer r.agentsLock.RUnlock()
+
 	for _, agent := range r.agents {
 		if agent.BtcReceivingAddress == addr {
 			return true
@@ -431,12 +567,10 @@ func (r *TxRelayer) updateAgentsList() error {
 		nextKey = agentsResponse.Pagination.NextKey
 	}
 
-	updated := false
-	if r.agents == nil {
-		updated = true
-	} else if !reflect.DeepEqual(r.agents, agents) {
-		updated = true
-	}
+	metrics.SetAgentsRefreshed(time.Now())
+	r.agentsLock.RLock()
+	updated := r.agents == nil || !reflect.DeepEqual(r.agents, agents)
+	r.agentsLock.RUnlock()
 
 	if !updated {
 		return nil
@@ -449,11 +583,24 @@ func (r *TxRelayer) updateAgentsList() error {
 	}
 	r.logger.Infof("*************** btc deposit receiver list ***************")
 	r.logger.Info("*************** agents ***************")
+	r.agentsLock.Lock()
 	r.agents = agents
+	r.agentsLock.Unlock()
 	return nil
 }
 
+// Agents returns a copy of the agent list refreshed from Lorenzo
+func (r *TxRelayer) Agents() []agenttypes.Agent {
+	r.agentsLock.RLock()
+	defer r.agentsLock.RUnlock()
+
+	return append([]agenttypes.Agent(nil), r.agents...)
+}
+
 func (r *TxRelayer) GetAgentByAddress(addr string) *agenttypes.Agent {
+	r.agentsLock.RLock()
+	defer r.agentsLock.RUnlock()
+
 	for _, agent := range r.agents {
 		if agent.BtcReceivingAddress == addr {
 			return &agenttypes.Agent{
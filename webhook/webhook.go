@@ -0,0 +1,375 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+)
+
+const (
+	// PublisherName is the cursor name of the events enqueued for the webhooks
+	PublisherName = "webhook"
+
+	// SignatureHeader carries the signature of the payload, see Sign
+	SignatureHeader = "X-Lorenzo-Signature"
+	// DeliveryHeader carries the id of the delivery, replays have new ids
+	DeliveryHeader = "X-Lorenzo-Delivery"
+	EventHeader    = "X-Lorenzo-Event"
+
+	pollInterval = time.Second
+	// deliveryBatchSize is the number of due deliveries of a subscription attempted in one round
+	deliveryBatchSize = 100
+)
+
+var eventTypes = map[string]bool{
+	db.EventDetected:  true,
+	db.EventConfirmed: true,
+	db.EventSubmitted: true,
+	db.EventMinted:    true,
+	db.EventInvalid:   true,
+}
+
+// Sign returns the signature of the body sent at timestamp, "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+// Receivers compute it again with the secret and reject old timestamps, so a captured payload cannot be replayed.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
+
+type subscription struct {
+	config.WebhookSubscription
+	events map[string]bool
+}
+
+// Deliverer is the publisher of the webhooks. The published events are enqueued as deliveries to the subscriptions
+// of their agents, which are sent in the background and retried with exponential backoff, so a slow or failing
+// subscriber does not hold the other publishers back. The subscriptions are delivered to by a bounded pool of workers,
+// one subscription at a time per worker, so the backlog of one subscriber does not hold the other subscribers back.
+type Deliverer struct {
+	logger        *zap.SugaredLogger
+	cfg           config.Webhooks
+	subscriptions []subscription
+	repository    db.IWebhookRepository
+	client        *http.Client
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+func NewDeliverer(logger *zap.SugaredLogger, cfg config.Webhooks, repository db.IWebhookRepository) (*Deliverer, error) {
+	var subscriptions []subscription
+	for _, subscriptionCfg := range cfg.Subscriptions {
+		types := subscriptionCfg.Events
+		if len(types) == 0 {
+			types = []string{db.EventMinted}
+		}
+		sub := subscription{WebhookSubscription: subscriptionCfg, events: make(map[string]bool)}
+		for _, eventType := range types {
+			if !eventTypes[eventType] {
+				return nil, fmt.Errorf("unsupported event type of webhook subscription %s: %s", subscriptionCfg.Name, eventType)
+			}
+			sub.events[eventType] = true
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	return &Deliverer{
+		logger:        logger.Named("webhook"),
+		cfg:           cfg,
+		subscriptions: subscriptions,
+		repository:    repository,
+		client:        &http.Client{Timeout: cfg.Timeout},
+		quit:          make(chan struct{}),
+	}, nil
+}
+
+func (d *Deliverer) Name() string {
+	return PublisherName
+}
+
+// Publish enqueues a delivery of every event to the subscriptions of its agent
+func (d *Deliverer) Publish(_ context.Context, published []events.Event) error {
+	now := time.Now()
+	var deliveries []*db.WebhookDelivery
+	for _, event := range published {
+		var data struct {
+			AgentId uint64 `json:"agentId"`
+		}
+		if err := json.Unmarshal(event.Data, &data); err != nil || data.AgentId == 0 {
+			continue
+		}
+
+		var payload []byte
+		for _, sub := range d.subscriptions {
+			if sub.AgentId != data.AgentId || !sub.events[event.Type] {
+				continue
+			}
+			if payload == nil {
+				var err error
+				if payload, err = json.Marshal(event); err != nil {
+					return err
+				}
+			}
+			deliveries = append(deliveries, &db.WebhookDelivery{
+				Subscription:    sub.Name,
+				URL:             sub.URL,
+				EventId:         event.Id,
+				Chain:           event.Chain,
+				Txid:            event.Txid,
+				LogIndex:        event.LogIndex,
+				EventType:       event.Type,
+				Payload:         string(payload),
+				Status:          db.WebhookDeliveryPending,
+				NextAttemptTime: now,
+			})
+		}
+	}
+
+	return d.repository.InsertWebhookDeliveries(deliveries)
+}
+
+func (d *Deliverer) Start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.loop()
+	}()
+}
+
+func (d *Deliverer) Stop() {
+	close(d.quit)
+}
+
+func (d *Deliverer) WaitForShutdown() {
+	d.wg.Wait()
+}
+
+func (d *Deliverer) loop() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		_, full, err := d.deliverDue(context.Background(), time.Now())
+		if err != nil {
+			d.logger.Warnf("Failed to get due webhook deliveries, error: %v", err)
+		}
+
+		// a full batch is followed by the next one right away
+		if err == nil && full {
+			select {
+			case <-d.quit:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-d.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverOnce attempts the deliveries due at now, it returns the number of attempted deliveries
+func (d *Deliverer) DeliverOnce(ctx context.Context, now time.Time) (int, error) {
+	n, _, err := d.deliverDue(ctx, now)
+	return n, err
+}
+
+// deliverDue attempts a batch of the due deliveries of every subscription, the subscriptions in parallel on up to
+// cfg.Workers workers and the deliveries of a subscription in order. It returns the number of attempted deliveries
+// and whether the batch of a subscription was full.
+func (d *Deliverer) deliverDue(ctx context.Context, now time.Time) (int, bool, error) {
+	subscriptions, err := d.repository.GetDueWebhookSubscriptions(now)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		lock      sync.Mutex
+		attempted int
+		full      bool
+		firstErr  error
+	)
+	workers := make(chan struct{}, max(d.cfg.Workers, 1))
+	for _, name := range subscriptions {
+		name := name
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			n, err := d.deliverSubscription(ctx, name, now)
+			lock.Lock()
+			defer lock.Unlock()
+			attempted += n
+			full = full || n == deliveryBatchSize
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	return attempted, full, firstErr
+}
+
+// deliverSubscription attempts the due deliveries of the subscription one after the other
+func (d *Deliverer) deliverSubscription(ctx context.Context, name string, now time.Time) (int, error) {
+	deliveries, err := d.repository.GetDueWebhookDeliveries(name, now, deliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, delivery := range deliveries {
+		select {
+		case <-d.quit:
+			return i, nil
+		default:
+		}
+
+		if err := d.attempt(ctx, delivery, now); err != nil {
+			d.logger.Warnf("Failed to deliver webhook %d of %s to %s, attempt: %d, error: %v",
+				delivery.Id, delivery.Txid, delivery.Subscription, delivery.Attempts, err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// attempt sends the delivery once and saves the result in the delivery log, it returns the error of a failed attempt.
+// A failed delivery is retried after the backoff until it runs out of attempts.
+func (d *Deliverer) attempt(ctx context.Context, delivery *db.WebhookDelivery, now time.Time) error {
+	sub := d.subscription(delivery.Subscription)
+	var err error
+	if sub == nil {
+		err = fmt.Errorf("subscription %s is not configured", delivery.Subscription)
+	} else {
+		delivery.URL = sub.URL
+		delivery.ResponseCode, err = d.send(ctx, sub, delivery)
+	}
+
+	delivery.Attempts++
+	switch {
+	case err == nil:
+		delivery.Status = db.WebhookDeliveryDelivered
+		delivery.Error = ""
+	case sub == nil || delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = db.WebhookDeliveryFailed
+		delivery.Error = err.Error()
+	default:
+		delivery.Status = db.WebhookDeliveryPending
+		delivery.Error = err.Error()
+		delivery.NextAttemptTime = now.Add(d.backoff(delivery.Attempts))
+	}
+	if saveErr := d.repository.UpdateWebhookDelivery(delivery); saveErr != nil {
+		return fmt.Errorf("save delivery %d: %w", delivery.Id, saveErr)
+	}
+
+	return err
+}
+
+// Replay enqueues the deliveries of the txid again, only the ones of the named subscription if it is not empty.
+// Every event is replayed once per subscription, however often it was delivered before.
+// The replays are sent by the running deliverer like the other pending deliveries.
+func (d *Deliverer) Replay(txid string, subscriptionName string, now time.Time) ([]*db.WebhookDelivery, error) {
+	deliveries, err := d.repository.GetWebhookDeliveries(txid)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := make(map[string]bool)
+	var replays []*db.WebhookDelivery
+	for _, delivery := range deliveries {
+		if subscriptionName != "" && delivery.Subscription != subscriptionName {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%d", delivery.Subscription, delivery.Chain, delivery.EventId)
+		if replayed[key] {
+			continue
+		}
+		replayed[key] = true
+
+		replay := *delivery
+		replay.Id = 0
+		replay.Status = db.WebhookDeliveryPending
+		replay.Attempts = 0
+		replay.ResponseCode = 0
+		replay.Error = ""
+		replay.NextAttemptTime = now
+		replays = append(replays, &replay)
+	}
+	if err := d.repository.InsertWebhookDeliveries(replays); err != nil {
+		return nil, err
+	}
+
+	return replays, nil
+}
+
+// send posts the delivery, it's signed with the time it is sent at: the deliveries of a batch are sent one after
+// the other and a slow subscriber would make the signatures of the later ones look old to their receivers
+func (d *Deliverer) send(ctx context.Context, sub *subscription, delivery *db.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, time.Now(), body))
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.Id))
+	req.Header.Set(EventHeader, delivery.EventType)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("webhook responded %d: %s", resp.StatusCode, message)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the wait after the failed attempts, doubled after every attempt up to the max backoff
+func (d *Deliverer) backoff(attempts int) time.Duration {
+	backoff := d.cfg.InitialBackoff
+	for i := 1; i < attempts && backoff < d.cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > d.cfg.MaxBackoff {
+		backoff = d.cfg.MaxBackoff
+	}
+	return backoff
+}
+
+func (d *Deliverer) subscription(name string) *subscription {
+	for i := range d.subscriptions {
+		if d.subscriptions[i].Name == name {
+			return &d.subscriptions[i]
+		}
+	}
+	return nil
+}
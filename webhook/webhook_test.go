@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/config"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/db"
+	"github.com/Lorenzo-Protocol/lorenzo-btcstaking-submitter/v2/events"
+)
+
+const testSecret = "0123456789abcdef"
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+type partnerServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	status   int
+	received []receivedWebhook
+}
+
+func newPartnerServer(t *testing.T) *partnerServer {
+	t.Helper()
+	partner := &partnerServer{status: http.StatusOK}
+	partner.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		partner.lock.Lock()
+		defer partner.lock.Unlock()
+		partner.received = append(partner.received, receivedWebhook{header: r.Header.Clone(), body: body})
+		w.WriteHeader(partner.status)
+	}))
+	t.Cleanup(partner.Close)
+	return partner
+}
+
+func newTestDeliverer(t *testing.T, url string, repository db.IWebhookRepository) *Deliverer {
+	t.Helper()
+	cfg := config.Webhooks{
+		Subscriptions: []config.WebhookSubscription{
+			{Name: "partner", AgentId: 7, URL: url, Secret: testSecret},
+			{Name: "audit", AgentId: 7, URL: url, Secret: testSecret, Events: []string{db.EventDetected, db.EventMinted}},
+		},
+		Timeout:        time.Second,
+		MaxAttempts:    3,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     90 * time.Second,
+	}
+	deliverer, err := NewDeliverer(zap.NewNop().Sugar(), cfg, repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deliverer
+}
+
+func testEvent(id int, agentId uint64, eventType string) events.Event {
+	data, _ := json.Marshal(map[string]any{"agentId": agentId, "amount": 1000})
+	return events.Event{Id: id, Chain: "btc", Txid: "a", Type: eventType, Status: "success", Data: data}
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign("secret", time.Unix(1700000000, 0), []byte(`{"id":1}`))
+	// echo -n '1700000000.{"id":1}' | openssl dgst -sha256 -hmac secret
+	if expected := "t=1700000000,v1=3dd1b9aef568d75f6790a84bd2e5dfa1f44409eef3cbdbd3f10b837376100c11"; signature != expected {
+		t.Fatalf("unexpected signature: %s", signature)
+	}
+}
+
+func TestDelivererPublishAndDeliver(t *testing.T) {
+	partner := newPartnerServer(t)
+	repository := db.NewMemoryWebhookRepository()
+	deliverer := newTestDeliverer(t, partner.URL, repository)
+	ctx := context.Background()
+
+	// the detected event only goes to the audit subscription, other agents are skipped
+	published := []events.Event{testEvent(1, 7, db.EventDetected), testEvent(2, 8, db.EventMinted), testEvent(3, 7, db.EventMinted)}
+	if err := deliverer.Publish(ctx, published); err != nil {
+		t.Fatal(err)
+	}
+	// the time of the batch is not the time the webhooks are sent at
+	now := time.Now().Add(time.Minute)
+	sentAfter := time.Now().Truncate(time.Second)
+	if n, err := deliverer.DeliverOnce(ctx, now); err != nil || n != 3 {
+		t.Fatalf("unexpected deliveries: %d, error: %v", n, err)
+	}
+	sentBefore := time.Now()
+	if len(partner.received) != 3 {
+		t.Fatalf("unexpected webhooks: %d", len(partner.received))
+	}
+
+	// the subscriptions are delivered to in parallel, the webhooks of different subscriptions arrive in any order
+	var received receivedWebhook
+	for _, webhook := range partner.received {
+		if webhook.header.Get(DeliveryHeader) == "2" {
+			received = webhook
+		}
+	}
+	if received.header.Get(EventHeader) != db.EventMinted {
+		t.Errorf("unexpected headers: %v", received.header)
+	}
+	// the webhook is signed when it is sent
+	signature := received.header.Get(SignatureHeader)
+	timestamp, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+	if err != nil || time.Unix(timestamp, 0).Before(sentAfter) || time.Unix(timestamp, 0).After(sentBefore) || signature != Sign(testSecret, time.Unix(timestamp, 0), received.body) {
+		t.Errorf("unexpected signature: %s", signature)
+	}
+	var event events.Event
+	if err := json.Unmarshal(received.body, &event); err != nil || event.Id != 3 || event.Txid != "a" {
+		t.Errorf("unexpected payload: %s, error: %v", received.body, err)
+	}
+
+	deliveries, err := repository.GetWebhookDeliveries("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, delivery := range deliveries {
+		if delivery.Status != db.WebhookDeliveryDelivered || delivery.Attempts != 1 || delivery.ResponseCode != http.StatusOK {
+			t.Errorf("unexpected delivery: %+v", delivery)
+		}
+	}
+}
+
+func TestDelivererRetriesWithBackoff(t *testing.T) {
+	partner := newPartnerServer(t)
+	partner.status = http.StatusServiceUnavailable
+	repository := db.NewMemoryWebhookRepository()
+	deliverer := newTestDeliverer(t, partner.URL, repository)
+	ctx := context.Background()
+
+	// invalid events are not subscribed
+	if err := deliverer.Publish(ctx, []events.Event{testEvent(1, 7, db.EventInvalid)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := deliverer.Publish(ctx, []events.Event{testEvent(2, 7, db.EventMinted)}); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, _ := repository.GetWebhookDeliveries("a")
+	if len(deliveries) != 2 {
+		t.Fatalf("unexpected deliveries: %d", len(deliveries))
+	}
+
+	now := time.Now()
+	// attempts at 0, 1m and 1m30s (the max backoff), then the delivery fails
+	for i, at := range []time.Duration{0, time.Minute, 150 * time.Second} {
+		if n, err := deliverer.DeliverOnce(ctx, now.Add(at-time.Second)); err != nil || (i > 0 && n != 0) {
+			t.Fatalf("attempt %d delivered early: %d, error: %v", i, n, err)
+		}
+		if n, err := deliverer.DeliverOnce(ctx, now.Add(at)); err != nil || n != 2 {
+			t.Fatalf("attempt %d: unexpected deliveries: %d, error: %v", i, n, err)
+		}
+	}
+
+	deliveries, _ = repository.GetWebhookDeliveries("a")
+	for _, delivery := range deliveries {
+		if delivery.Status != db.WebhookDeliveryFailed || delivery.Attempts != 3 || delivery.ResponseCode != http.StatusServiceUnavailable ||
+			!strings.Contains(delivery.Error, "503") {
+			t.Errorf("unexpected delivery: %+v", delivery)
+		}
+	}
+
+	// a replay delivers the events again once per subscription
+	partner.status = http.StatusOK
+	replays, err := deliverer.Replay("a", "partner", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replays) != 1 || replays[0].EventId != 2 {
+		t.Fatalf("unexpected replays: %+v", replays)
+	}
+	// the replay is sent by the deliverer
+	if n, err := deliverer.DeliverOnce(ctx, now); err != nil || n != 1 {
+		t.Fatalf("unexpected deliveries: %d, error: %v", n, err)
+	}
+	deliveries, _ = repository.GetWebhookDeliveries("a")
+	if len(deliveries) != 3 || deliveries[2].Status != db.WebhookDeliveryDelivered {
+		t.Fatalf("unexpected deliveries: %+v", deliveries)
+	}
+}
+
+// the backlog of a slow subscriber does not hold the deliveries to the other subscribers back
+func TestDelivererDeliversSubscriptionsInParallel(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+	fast := newPartnerServer(t)
+
+	repository := db.NewMemoryWebhookRepository()
+	cfg := config.Webhooks{
+		Subscriptions: []config.WebhookSubscription{
+			{Name: "slow", AgentId: 7, URL: slow.URL, Secret: testSecret},
+			{Name: "fast", AgentId: 7, URL: fast.URL, Secret: testSecret},
+		},
+		Timeout:        10 * time.Second,
+		MaxAttempts:    3,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     time.Hour,
+		Workers:        2,
+	}
+	deliverer, err := NewDeliverer(zap.NewNop().Sugar(), cfg, repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := deliverer.Publish(ctx, []events.Event{testEvent(1, 7, db.EventMinted), testEvent(2, 7, db.EventMinted)}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan int)
+	go func() {
+		n, _ := deliverer.DeliverOnce(ctx, time.Now())
+		done <- n
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		fast.lock.Lock()
+		received := len(fast.received)
+		fast.lock.Unlock()
+		if received == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the fast subscriber received %d webhooks while the slow one is blocked", received)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+	if n := <-done; n != 4 {
+		t.Fatalf("unexpected deliveries: %d", n)
+	}
+	deliveries, _ := repository.GetWebhookDeliveries("a")
+	for _, delivery := range deliveries {
+		if delivery.Status != db.WebhookDeliveryDelivered {
+			t.Errorf("unexpected delivery: %+v", delivery)
+		}
+	}
+}
+
+func TestNewDelivererRejectsUnknownEvent(t *testing.T) {
+	cfg := config.Webhooks{Subscriptions: []config.WebhookSubscription{{Name: "partner", AgentId: 1, Events: []string{"burned"}}}}
+	if _, err := NewDeliverer(zap.NewNop().Sugar(), cfg, db.NewMemoryWebhookRepository()); err == nil {
+		t.Fatal("expect an error for an unknown event type")
+	}
+}